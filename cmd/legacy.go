@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"miners_game/config"
+	"miners_game/internal/game/legacy"
+	"miners_game/pkg/database"
+	"miners_game/pkg/errs"
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+const legacyUsage = "usage: miners_game legacy-saves status | convert | drop"
+
+// runLegacySaves - подкоманда `legacy-saves status|convert|drop` для переноса
+// старой таблицы game_saves в games, возвращает код выхода
+func runLegacySaves(args []string, dbConfig *config.DatabaseConfig, gameConfig *config.GameConfig, logger *zerolog.Logger) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, legacyUsage)
+		return 2
+	}
+	dbPool := database.CreateDbPool(dbConfig, logger)
+	defer dbPool.Close()
+
+	// отметки переноса добавляет миграция, без неё convert не запустится
+	if err := migrateUp(dbPool, logger); err != nil {
+		logger.Error().Err(err).Msg("не удалось применить миграции")
+		return 1
+	}
+	legacyService := legacy.NewService(legacy.ServiceDeps{
+		Repo: legacy.NewRepository(legacy.RepositoryDeps{
+			DbPool: dbPool,
+			Logger: logger.With().Str("repository", "legacy").Logger(),
+		}),
+		Config: gameConfig,
+		Logger: logger.With().Str("service", "legacy").Logger(),
+	})
+	ctx := context.Background()
+
+	switch args[0] {
+	case "status":
+		status, err := legacyService.Status(ctx)
+		if err != nil {
+			logger.Error().Err(err).Msg("не удалось получить статус старых сохранений")
+			return 1
+		}
+		if !status.Exists {
+			fmt.Println("game_saves dropped")
+			return 0
+		}
+		fmt.Printf("total %d, converted %d, pending %d\n", status.Total, status.Converted, status.Pending())
+		printConflicts(status.Conflicts)
+	case "convert":
+		report, err := legacyService.Convert(ctx)
+		if err != nil {
+			logger.Error().Err(err).Msg("не удалось перенести старые сохранения")
+			return 1
+		}
+		fmt.Printf("converted %d save(s), %d conflict(s)\n", report.Converted, len(report.Conflicts))
+		printConflicts(report.Conflicts)
+		if len(report.Conflicts) > 0 {
+			return 1
+		}
+	case "drop":
+		if err := legacyService.Drop(ctx); err != nil {
+			if errors.Is(err, errs.ErrLegacySavesPending) {
+				fmt.Fprintln(os.Stderr, "game_saves has pending rows, run convert and resolve conflicts first")
+				return 1
+			}
+			logger.Error().Err(err).Msg("не удалось удалить game_saves")
+			return 1
+		}
+		fmt.Println("game_saves dropped")
+	default:
+		fmt.Fprintln(os.Stderr, legacyUsage)
+		return 2
+	}
+	return 0
+}
+
+func printConflicts(conflicts []legacy.Conflict) {
+	for _, c := range conflicts {
+		fmt.Printf("conflict  %-36s %-36s %s\n", c.UserID, c.SaveID, c.Reason)
+	}
+}
@@ -1,21 +1,32 @@
 package main
 
 import (
+	"context"
 	"encoding/gob"
 	"miners_game/config"
+	"miners_game/internal/api"
+	"miners_game/internal/archive"
 	"miners_game/internal/auth"
 	"miners_game/internal/auth/email"
+	"miners_game/internal/chat"
 	"miners_game/internal/game"
+	"miners_game/internal/game/journal"
 	"miners_game/internal/game/loop"
 	"miners_game/internal/game/sessions"
+	"miners_game/internal/lease"
+	"miners_game/internal/ledger"
 	"miners_game/internal/pages"
+	"miners_game/internal/profile"
+	"miners_game/internal/referral"
 	"miners_game/internal/robots"
-	"miners_game/internal/user"
-	"miners_game/pkg/database"
+	"miners_game/internal/snapshot"
+	"miners_game/pkg/clock"
+	"miners_game/pkg/lifecycle"
 	"miners_game/pkg/logger"
 	"miners_game/pkg/middleware"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -25,11 +36,9 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/gofiber/fiber/v2/middleware/session"
-	"github.com/gofiber/storage/postgres/v3"
 	"github.com/gookit/validate/locales/ruru"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/rs/zerolog"
 )
 
 func main() {
@@ -39,6 +48,17 @@ func main() {
 	dbConfig := config.NewDatabaseConfig()
 	gmailConfig := config.NewGmailConfig()
 	robotsConfig := config.NewRobotsConfig()
+	chatConfig := config.NewChatConfig()
+	adminConfig := config.NewAdminConfig()
+	referralConfig := config.NewReferralConfig()
+	gameConfig := config.NewGameConfig()
+	snapshotConfig := config.NewSnapshotConfig()
+	storageConfig := config.NewStorageConfig()
+	serverConfig := config.NewServerConfig()
+	leaseConfig := config.NewLeaseConfig()
+	journalConfig := config.NewJournalConfig()
+	archiveConfig := config.NewArchiveConfig()
+	loopConfig := config.NewLoopConfig()
 
 	ruru.RegisterGlobal()
 
@@ -46,12 +66,27 @@ func main() {
 
 	customLogger := logger.NewLogger(loggerConfig)
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:], dbConfig, customLogger))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "legacy-saves" {
+		os.Exit(runLegacySaves(os.Args[2:], dbConfig, gameConfig, customLogger))
+	}
+
+	clk := clock.New()
+
 	reg := prometheus.NewRegistry()
 
 	//Metrics:
 	httpMetrics := middleware.NewMetrics(reg)
 	authMetrics := auth.NewMetrics(reg)
 	gameMetrics := game.NewMetrics(reg)
+	chatMetrics := chat.NewMetrics(reg)
+	ledgerMetrics := ledger.NewMetrics(reg)
+	leaseMetrics := lease.NewMetrics(reg)
+	journalMetrics := journal.NewMetrics(reg)
+	archiveMetrics := archive.NewMetrics(reg)
+	loopMetrics := loop.NewMetrics(reg)
 
 	app := fiber.New()
 
@@ -61,57 +96,172 @@ func main() {
 		Logger: customLogger,
 	}))
 	app.Use(recover.New())
+	app.Use(middleware.RequestDeadlineMiddleware(serverConfig.RequestTimeout))
 	app.Use(middleware.MetricsMiddleware(httpMetrics))
 	app.Static("/public", "./public")
-	dbPool := database.CreateDbPool(dbConfig, customLogger)
-	defer dbPool.Close()
-	storage := postgres.New(postgres.Config{
-		DB:         dbPool,
-		Table:      "session",
-		Reset:      false,
-		GCInterval: 10 * time.Second,
-	})
+	storage := newStorage(storageConfig, dbConfig, customLogger)
 	store := session.New(session.Config{
-		Storage: storage,
+		Storage:    storage.Sessions,
+		Expiration: serverConfig.SessionTTL,
 	})
 	gob.Register(auth.RegisterSession{})
 
 	app.Use(middleware.AuthMiddleware(store))
 
 	//Repositories:
-	gameRepository := game.NewRepository(game.RepositoryDeps{
-		DbPool: dbPool,
-		Logger: customLogger.With().Str("repository", "game").Logger(),
-	})
-	userRepository := user.NewRepository(user.RepositoryDeps{
-		DbPool: dbPool,
-		Logger: customLogger.With().Str("repository", "user").Logger(),
-	})
+	gameRepository := storage.Games
+	userRepository := storage.Users
+
+	// журнал накатывается до первого запроса: игры загрузятся уже с покупками,
+	// которые не успели сохраниться до падения
+	var gameJournal *journal.Journal
+	var gameJournalDep game.IJournal
+	if journalConfig.Enabled && storageConfig.Backend != config.StorageBackendMemory {
+		var err error
+		gameJournal, err = journal.NewJournal(journal.JournalDeps{
+			Dir:          journalConfig.Dir,
+			SyncInterval: journalConfig.SyncInterval,
+			Metrics:      journalMetrics,
+			Logger:       customLogger.With().Str("component", "journal").Logger(),
+		})
+		if err != nil {
+			customLogger.Fatal().Err(err).Str("dir", journalConfig.Dir).Msg("не удалось открыть журнал игр")
+		}
+		replayCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if _, err := gameJournal.Replay(replayCtx, gameRepository); err != nil {
+			customLogger.Error().Err(err).Msg("не удалось накатить журнал игр, сегменты оставлены до следующего запуска")
+		}
+		cancel()
+		gameJournalDep = gameJournal
+	}
+
 	//Services:
 	emailService := email.NewService(email.ServiceDeps{
 		Logger: customLogger.With().Str("service", "email").Logger(),
 	})
+	var rewards game.IRewardService
+	var authReferrals auth.IReferralService
+	var registrations auth.IRegistrationRepository
+	var leaseService *lease.Service
+	var gameLeases game.ILeaseService
+	var archiveService *archive.Service
+	var gameArchive game.IArchiveService
+	if storage.DbPool != nil {
+		referralService := referral.NewService(referral.ServiceDeps{
+			Repo: referral.NewRepository(referral.RepositoryDeps{
+				DbPool:  storage.DbPool,
+				Timeout: dbConfig.QueryTimeout,
+				Logger:  customLogger.With().Str("repository", "referral").Logger(),
+			}),
+			UserRepository: userRepository,
+			Config:         referralConfig,
+			Clock:          clk,
+			Logger:         customLogger.With().Str("service", "referral").Logger(),
+		})
+		rewards, authReferrals = referralService, referralService
+		leaseService = lease.NewService(lease.ServiceDeps{
+			Repo: lease.NewRepository(lease.RepositoryDeps{
+				DbPool: storage.DbPool,
+				Logger: customLogger.With().Str("repository", "lease").Logger(),
+			}),
+			Config:  leaseConfig,
+			Clock:   clk,
+			Metrics: leaseMetrics,
+			Logger:  customLogger.With().Str("service", "lease").Logger(),
+		})
+		gameLeases = leaseService
+		archiveService = archive.NewService(archive.ServiceDeps{
+			Repo: archive.NewRepository(archive.RepositoryDeps{
+				DbPool: storage.DbPool,
+				Logger: customLogger.With().Str("repository", "archive").Logger(),
+			}),
+			Games:   gameRepository,
+			Config:  archiveConfig,
+			Metrics: archiveMetrics,
+			Logger:  customLogger.With().Str("service", "archive").Logger(),
+		})
+		gameArchive = archiveService
+		registrations = auth.NewRegistrationRepository(auth.RegistrationRepositoryDeps{
+			DbPool:  storage.DbPool,
+			Timeout: dbConfig.QueryTimeout,
+			Logger:  customLogger.With().Str("repository", "registration").Logger(),
+		})
+	}
 	loopService := loop.NewService(loop.ServiceDeps{
-		Logger: customLogger.With().Str("service", "loop").Logger(),
+		Config:  loopConfig,
+		Clock:   clk,
+		Metrics: loopMetrics,
+		Logger:  customLogger.With().Str("service", "loop").Logger(),
 	})
 	sessionService := sessions.NewService(sessions.ServiceDeps{
 		Timeout: timeout,
+		Clock:   clk,
 		Logger:  customLogger.With().Str("service", "session").Logger(),
 	})
 	gameService := game.NewService(game.ServiceDeps{
 		Repo:     gameRepository,
 		Loop:     loopService,
 		Sessions: sessionService,
+		Rewards:  rewards,
+		Leases:   gameLeases,
+		Journal:  gameJournalDep,
+		Archive:  gameArchive,
+		Config:   gameConfig,
+		Clock:    clk,
 		Metrics:  gameMetrics,
 		Logger:   customLogger.With().Str("service", "game").Logger(),
 	})
 	authService := auth.NewService(auth.ServiceDeps{
 		UserRepository: userRepository,
 		EmailService:   emailService,
+		Referrals:      authReferrals,
+		Registrations:  registrations,
 		GmailConfig:    gmailConfig,
+		Clock:          clk,
 		Metrics:        authMetrics,
 		Logger:         customLogger.With().Str("service", "auth").Logger(),
 	})
+	profileService := profile.NewService(profile.ServiceDeps{
+		UserRepository: userRepository,
+		Games:          gameService,
+		Logger:         customLogger.With().Str("service", "profile").Logger(),
+	})
+	var snapshotService *snapshot.Service
+	var ledgerService *ledger.Service
+	var chatService *chat.Service
+	if storage.DbPool != nil {
+		snapshotService = snapshot.NewService(snapshot.ServiceDeps{
+			Repo: snapshot.NewRepository(snapshot.RepositoryDeps{
+				DbPool:  storage.DbPool,
+				Timeout: dbConfig.QueryTimeout,
+				Logger:  customLogger.With().Str("repository", "snapshot").Logger(),
+			}),
+			Games:  gameService,
+			Config: snapshotConfig,
+			Clock:  clk,
+			Logger: customLogger.With().Str("service", "snapshot").Logger(),
+		})
+		ledgerService = ledger.NewService(ledger.ServiceDeps{
+			Repo: ledger.NewRepository(ledger.RepositoryDeps{
+				DbPool: storage.DbPool,
+				Logger: customLogger.With().Str("repository", "ledger").Logger(),
+			}),
+			Metrics: ledgerMetrics,
+			Logger:  customLogger.With().Str("service", "ledger").Logger(),
+		})
+		chatService = chat.NewService(chat.ServiceDeps{
+			Repo: chat.NewRepository(chat.RepositoryDeps{
+				DbPool:  storage.DbPool,
+				Timeout: dbConfig.QueryTimeout,
+				Logger:  customLogger.With().Str("repository", "chat").Logger(),
+			}),
+			Config:  chatConfig,
+			Metrics: chatMetrics,
+			Logger:  customLogger.With().Str("service", "chat").Logger(),
+		})
+	} else {
+		customLogger.Warn().Msg("без Postgres отключены рефералы, чат, снимки, сверка журнала экономики, аренда и архив игр")
+	}
 
 	//Handlers:
 	pages.NewHandler(pages.HandlerDeps{
@@ -122,65 +272,124 @@ func main() {
 		Router:      app,
 		GameService: gameService,
 		Store:       store,
+		AdminConfig: adminConfig,
 	})
 	auth.NewHandler(auth.HandlerDeps{
 		Router:      app,
 		AuthService: authService,
 		Store:       store,
 	})
+	api.NewHandler(api.HandlerDeps{
+		Router:      app,
+		AuthService: authService,
+		GameService: gameService,
+		Store:       store,
+	})
+	profile.NewHandler(profile.HandlerDeps{
+		Router:         app,
+		ProfileService: profileService,
+	})
+	if chatService != nil {
+		chat.NewHandler(chat.HandlerDeps{
+			Router:      app,
+			ChatService: chatService,
+			AdminConfig: adminConfig,
+		})
+	}
+	if snapshotService != nil {
+		snapshot.NewHandler(snapshot.HandlerDeps{
+			Router:          app,
+			SnapshotService: snapshotService,
+			AdminConfig:     adminConfig,
+		})
+	}
 	robots.NewHandler(robots.RobotsHandlerDeps{
 		Router: app,
 		Data:   robotsConfig.Robots,
 	})
 	app.Get("/metrics", adaptor.HTTPHandler(promhttp.HandlerFor(reg, promhttp.HandlerOpts{})))
 
-	App(loopService, gameService, customLogger)
-
-	if err := app.Listen(":3000"); err != nil {
-		customLogger.Fatal().Err(err).Msg("не удалось запустить HTTP сервер")
-	}
-}
-
-func App(loopService *loop.Service, gameService *game.Service, logger *zerolog.Logger) {
-	go func() {
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
+	lc := lifecycle.NewManager(lifecycle.ManagerDeps{
+		StepTimeout: serverConfig.ShutdownTimeout,
+		Clock:       clk,
+		Logger:      customLogger.With().Str("component", "lifecycle").Logger(),
+	})
+	App(lc, clk, loopService, gameService, snapshotService, ledgerService, leaseService, leaseConfig, archiveService, archiveConfig)
 
-		for range ticker.C {
-			now := time.Now().Unix()
-			loopService.Tick(now)
+	// порядок остановки: новые запросы не принимаются и текущие дорабатывают,
+	// затем встают фоновые задачи, игры сохраняются, хранилище закрывается последним
+	lc.OnShutdown("http", func(ctx context.Context) error {
+		done := make(chan error, 1)
+		go func() {
+			done <- app.ShutdownWithContext(ctx)
+		}()
+		// без этого drain ждал бы открытые HUD-потоки до дедлайна
+		loopService.CloseSubscriptions()
+		return <-done
+	})
+	lc.OnShutdown("jobs", lc.StopJobs)
+	lc.OnShutdown("games", func(ctx context.Context) error {
+		gameService.SaveAll(ctx)
+		gameService.ReleaseLeases(ctx)
+		return nil
+	})
+	lc.OnShutdown("journal", func(ctx context.Context) error {
+		if gameJournal == nil {
+			return nil
 		}
-	}()
+		return gameJournal.Close()
+	})
+	lc.OnShutdown("storage", func(ctx context.Context) error {
+		storage.Close()
+		return nil
+	})
 
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	listenErr := make(chan error, 1)
 	go func() {
-		ticker := time.NewTicker(5 * time.Second)
-		defer ticker.Stop()
-
-		for range ticker.C {
-			gameService.DeleteExpiredSessions()
-		}
+		listenErr <- app.Listen(":3000")
 	}()
+	select {
+	case <-sigCtx.Done():
+		customLogger.Info().Msg("получен сигнал остановки")
+	case err := <-listenErr:
+		// игры всё равно сохраняются, но процесс завершается с ошибкой
+		lc.Shutdown()
+		customLogger.Fatal().Err(err).Msg("не удалось запустить HTTP сервер")
+	}
+	lc.Shutdown()
+}
 
-	go func() {
-		ticker := time.NewTicker(1 * time.Minute)
-		defer ticker.Stop()
+// App - фоновые задачи. Они работают в контексте lc и останавливаются шагом jobs
+func App(lc *lifecycle.Manager, clk clock.Clock, loopService *loop.Service, gameService *game.Service, snapshotService *snapshot.Service, ledgerService *ledger.Service, leaseService *lease.Service, leaseConfig *config.LeaseConfig, archiveService *archive.Service, archiveConfig *config.ArchiveConfig) {
+	for i := 0; i < loopService.Shards(); i++ {
+		lc.Every("loop-"+strconv.Itoa(i), loopService.Interval(), func(ctx context.Context) {
+			loopService.TickShard(i, clk.Now().Unix())
+		})
+	}
+	lc.Every("expired_sessions", 5*time.Second, gameService.DeleteExpiredSessions)
+	lc.Every("save", 1*time.Minute, gameService.SaveAll)
 
-		for range ticker.C {
-			gameService.SaveAll()
-		}
-	}()
+	if snapshotService != nil {
+		lc.Every("snapshots", 1*time.Hour, func(ctx context.Context) {
+			snapshotService.Rotate(ctx, clk.Now().Unix())
+		})
+	}
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(
-		sigCh,
-		os.Interrupt,
-		syscall.SIGTERM,
-	)
-	go func() {
-		<-sigCh
-		gameService.SaveAll()
-		logger.Info().Msg("games saves complete")
-		os.Exit(0)
-	}()
+	if ledgerService != nil {
+		lc.Every("ledger", 15*time.Minute, func(ctx context.Context) {
+			ledgerService.Reconcile(ctx)
+		})
+	}
 
+	if leaseService != nil {
+		lc.Every("leases", leaseConfig.RenewInterval, gameService.RenewLeases)
+	}
+
+	if archiveService != nil {
+		lc.Every("archive", archiveConfig.Interval, func(ctx context.Context) {
+			archiveService.Run(ctx)
+		})
+	}
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"miners_game/config"
+	"miners_game/migrations"
+	"miners_game/pkg/database"
+	"miners_game/pkg/migrator"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+const migrateUsage = "usage: miners_game migrate up | down [N] | status"
+
+// runMigrate - подкоманда `migrate up|down [N]|status`, возвращает код выхода
+func runMigrate(args []string, dbConfig *config.DatabaseConfig, logger *zerolog.Logger) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	dbPool := database.CreateDbPool(dbConfig, logger)
+	defer dbPool.Close()
+
+	m, err := newMigrator(dbPool, logger)
+	if err != nil {
+		logger.Error().Err(err).Msg("не удалось прочитать миграции")
+		return 1
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		count, err := m.Up(ctx)
+		if err != nil {
+			logger.Error().Err(err).Msg("не удалось применить миграции")
+			return 1
+		}
+		fmt.Printf("applied %d migration(s)\n", count)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+		}
+		count, err := m.Down(ctx, steps)
+		if err != nil {
+			logger.Error().Err(err).Msg("не удалось откатить миграции")
+			return 1
+		}
+		fmt.Printf("reverted %d migration(s)\n", count)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			logger.Error().Err(err).Msg("не удалось получить статус миграций")
+			return 1
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt > 0 {
+				applied = time.Unix(s.AppliedAt, 0).Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-24s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
+
+func migrateUp(dbPool *pgxpool.Pool, logger *zerolog.Logger) error {
+	m, err := newMigrator(dbPool, logger)
+	if err != nil {
+		return err
+	}
+	count, err := m.Up(context.Background())
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		logger.Info().Int("count", count).Msg("миграции применены")
+	}
+	return nil
+}
+
+func newMigrator(dbPool *pgxpool.Pool, logger *zerolog.Logger) (*migrator.Migrator, error) {
+	return migrator.NewMigrator(migrator.MigratorDeps{
+		DbPool: dbPool,
+		FS:     migrations.FS,
+		Logger: logger.With().Str("component", "migrator").Logger(),
+	})
+}
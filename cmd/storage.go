@@ -0,0 +1,92 @@
+package main
+
+import (
+	"miners_game/config"
+	"miners_game/internal/game"
+	"miners_game/internal/user"
+	"miners_game/pkg/database"
+	"miners_game/pkg/sqlite"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/storage/postgres/v3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// Storage - хранилища игр, пользователей и сессий выбранного бэкенда.
+// DbPool есть только у postgres: рефералы, чат, снимки и журнал экономики
+// работают только с ним и без него отключаются
+type Storage struct {
+	Games    game.IGameRepository
+	Users    user.IUserRepository
+	Sessions fiber.Storage
+	DbPool   *pgxpool.Pool
+	Close    func()
+}
+
+func newStorage(storageConfig *config.StorageConfig, dbConfig *config.DatabaseConfig, logger *zerolog.Logger) *Storage {
+	switch storageConfig.Backend {
+	case config.StorageBackendMemory:
+		logger.Warn().Msg("данные хранятся в памяти и пропадут при перезапуске")
+		return &Storage{
+			Games: game.NewMemoryRepository(),
+			Users: user.NewMemoryRepository(),
+			// session.New без Storage хранит сессии в памяти
+			Close: func() {},
+		}
+	case config.StorageBackendSQLite:
+		db, err := sqlite.Open(storageConfig.SQLitePath)
+		if err != nil {
+			logger.Fatal().Err(err).Str("path", storageConfig.SQLitePath).Msg("не удалось открыть базу SQLite")
+		}
+		sessions := sqlite.NewStorage(db, 10*time.Second)
+		return &Storage{
+			Games: game.NewSQLiteRepository(game.SQLiteRepositoryDeps{
+				DB:      db,
+				Timeout: dbConfig.QueryTimeout,
+				Logger:  logger.With().Str("repository", "game").Logger(),
+			}),
+			Users: user.NewSQLiteRepository(user.SQLiteRepositoryDeps{
+				DB:      db,
+				Timeout: dbConfig.QueryTimeout,
+				Logger:  logger.With().Str("repository", "user").Logger(),
+			}),
+			Sessions: sessions,
+			Close: func() {
+				sessions.Close()
+				db.Close()
+			},
+		}
+	case config.StorageBackendPostgres:
+	default:
+		logger.Fatal().Str("backend", storageConfig.Backend).Msg("неизвестный STORAGE_BACKEND")
+	}
+
+	dbPool := database.CreateDbPool(dbConfig, logger)
+	if dbConfig.MigrateOnStart {
+		if err := migrateUp(dbPool, logger); err != nil {
+			logger.Fatal().Err(err).Msg("не удалось применить миграции")
+		}
+	}
+	return &Storage{
+		Games: game.NewRepository(game.RepositoryDeps{
+			DbPool:  dbPool,
+			Timeout: dbConfig.QueryTimeout,
+			Logger:  logger.With().Str("repository", "game").Logger(),
+		}),
+		Users: user.NewRepository(user.RepositoryDeps{
+			DbPool:  dbPool,
+			Timeout: dbConfig.QueryTimeout,
+			Logger:  logger.With().Str("repository", "user").Logger(),
+		}),
+		Sessions: postgres.New(postgres.Config{
+			DB:         dbPool,
+			Table:      "session",
+			Reset:      false,
+			GCInterval: 10 * time.Second,
+		}),
+		DbPool: dbPool,
+		Close:  dbPool.Close,
+	}
+}
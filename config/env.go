@@ -1,9 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -17,12 +20,17 @@ func Init() {
 }
 
 type DatabaseConfig struct {
-	Url string
+	Url            string
+	MigrateOnStart bool
+	// QueryTimeout - дедлайн одной операции репозитория
+	QueryTimeout time.Duration
 }
 
 func NewDatabaseConfig() *DatabaseConfig {
 	return &DatabaseConfig{
-		Url: getString("DATABASE_URL", ""),
+		Url:            getString("DATABASE_URL", ""),
+		MigrateOnStart: getBool("MIGRATE_ON_START", true),
+		QueryTimeout:   time.Duration(getInt("DB_QUERY_TIMEOUT_SEC", 5)) * time.Second,
 	}
 }
 
@@ -52,6 +60,36 @@ func getString(key, defaultValue string) string {
 	return str
 }
 
+func getList(key string) []string {
+	str := os.Getenv(key)
+	if str == "" {
+		return nil
+	}
+	list := []string{}
+	for _, v := range strings.Split(str, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// ServerConfig - RequestTimeout ограничивает работу обработчика вместе с запросами к базе,
+// SessionTTL - срок жизни cookie-сессии с последнего сохранения
+type ServerConfig struct {
+	RequestTimeout  time.Duration
+	ShutdownTimeout time.Duration
+	SessionTTL      time.Duration
+}
+
+func NewServerConfig() *ServerConfig {
+	return &ServerConfig{
+		RequestTimeout:  time.Duration(getInt("HTTP_REQUEST_TIMEOUT_SEC", 15)) * time.Second,
+		ShutdownTimeout: time.Duration(getInt("SHUTDOWN_TIMEOUT_SEC", 10)) * time.Second,
+		SessionTTL:      time.Duration(getInt("SESSION_TTL_HOURS", 24)) * time.Hour,
+	}
+}
+
 type LogConfig struct {
 	Level  int
 	Format string
@@ -91,3 +129,168 @@ func NewRobotsConfig() *RobotsConfig {
 		Robots: rob,
 	}
 }
+
+type ChatConfig struct {
+	MaxMessageLength int
+	RateLimitCount   int
+	RateLimitWindow  time.Duration
+	HistoryLimit     int
+}
+
+func NewChatConfig() *ChatConfig {
+	return &ChatConfig{
+		MaxMessageLength: getInt("CHAT_MAX_MESSAGE_LENGTH", 300),
+		RateLimitCount:   getInt("CHAT_RATE_LIMIT_COUNT", 5),
+		RateLimitWindow:  time.Duration(getInt("CHAT_RATE_LIMIT_WINDOW_SEC", 10)) * time.Second,
+		HistoryLimit:     getInt("CHAT_HISTORY_LIMIT", 50),
+	}
+}
+
+type AdminConfig struct {
+	UserIDs []string
+}
+
+func NewAdminConfig() *AdminConfig {
+	return &AdminConfig{
+		UserIDs: getList("ADMIN_USER_IDS"),
+	}
+}
+
+type ReferralConfig struct {
+	RewardReferrer int64
+	RewardReferee  int64
+	IPWindow       time.Duration
+	MaxPerIP       int
+}
+
+func NewReferralConfig() *ReferralConfig {
+	return &ReferralConfig{
+		RewardReferrer: int64(getInt("REFERRAL_REWARD_REFERRER", 1000)),
+		RewardReferee:  int64(getInt("REFERRAL_REWARD_REFEREE", 500)),
+		IPWindow:       time.Duration(getInt("REFERRAL_IP_WINDOW_HOURS", 24)) * time.Hour,
+		MaxPerIP:       getInt("REFERRAL_MAX_PER_IP", 2),
+	}
+}
+
+type GameConfig struct {
+	MaxSaveSlots   int
+	SaveSigningKey []byte
+}
+
+func NewGameConfig() *GameConfig {
+	return &GameConfig{
+		MaxSaveSlots:   getInt("MAX_SAVE_SLOTS", 3),
+		SaveSigningKey: []byte(getString("SAVE_SIGNING_KEY", "")),
+	}
+}
+
+type SnapshotConfig struct {
+	HourlyRetention time.Duration
+	DailyRetention  time.Duration
+}
+
+func NewSnapshotConfig() *SnapshotConfig {
+	return &SnapshotConfig{
+		HourlyRetention: time.Duration(getInt("SNAPSHOT_HOURLY_RETENTION_HOURS", 24)) * time.Hour,
+		DailyRetention:  time.Duration(getInt("SNAPSHOT_DAILY_RETENTION_DAYS", 30)) * 24 * time.Hour,
+	}
+}
+
+const (
+	StorageBackendPostgres = "postgres"
+	StorageBackendMemory   = "memory"
+	StorageBackendSQLite   = "sqlite"
+)
+
+// StorageConfig - хранилище игр, пользователей и сессий. memory и sqlite
+// нужны для локального запуска без Postgres
+type StorageConfig struct {
+	Backend    string
+	SQLitePath string
+}
+
+func NewStorageConfig() *StorageConfig {
+	return &StorageConfig{
+		Backend:    getString("STORAGE_BACKEND", StorageBackendPostgres),
+		SQLitePath: getString("SQLITE_PATH", "miners.db"),
+	}
+}
+
+// LeaseConfig - аренда игр между инстансами. RenewInterval должен быть заметно
+// меньше TTL, а HandoffWait - больше RenewInterval: владелец узнаёт о передаче
+// только при продлении
+type LeaseConfig struct {
+	InstanceID    string
+	TTL           time.Duration
+	RenewInterval time.Duration
+	HandoffWait   time.Duration
+}
+
+func NewLeaseConfig() *LeaseConfig {
+	return &LeaseConfig{
+		InstanceID:    getString("INSTANCE_ID", defaultInstanceID()),
+		TTL:           time.Duration(getInt("LEASE_TTL_SEC", 15)) * time.Second,
+		RenewInterval: time.Duration(getInt("LEASE_RENEW_SEC", 3)) * time.Second,
+		HandoffWait:   time.Duration(getInt("LEASE_HANDOFF_WAIT_SEC", 8)) * time.Second,
+	}
+}
+
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "instance"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// JournalConfig - локальный журнал покупок между сохранениями. Для хранилища
+// в памяти он не нужен: после перезапуска накатывать не на что
+type JournalConfig struct {
+	Enabled      bool
+	Dir          string
+	SyncInterval time.Duration
+}
+
+func NewJournalConfig() *JournalConfig {
+	return &JournalConfig{
+		Enabled:      getBool("JOURNAL_ENABLED", true),
+		Dir:          getString("JOURNAL_DIR", "data/journal"),
+		SyncInterval: time.Duration(getInt("JOURNAL_SYNC_MS", 20)) * time.Millisecond,
+	}
+}
+
+// ArchiveConfig - перенос в архив аккаунтов, где ни одна игра не менялась
+// InactiveAfter, и чистка сессий. BatchSize - аккаунтов за один проход,
+// RegistrationTTL - сколько живёт сессия незавершённой регистрации
+type ArchiveConfig struct {
+	InactiveAfter   time.Duration
+	Interval        time.Duration
+	BatchSize       int
+	RegistrationTTL time.Duration
+}
+
+func NewArchiveConfig() *ArchiveConfig {
+	return &ArchiveConfig{
+		InactiveAfter:   time.Duration(getInt("ARCHIVE_AFTER_DAYS", 30)) * 24 * time.Hour,
+		Interval:        time.Duration(getInt("ARCHIVE_INTERVAL_MIN", 60)) * time.Minute,
+		BatchSize:       getInt("ARCHIVE_BATCH_SIZE", 200),
+		RegistrationTTL: time.Duration(getInt("ARCHIVE_REGISTRATION_TTL_MIN", 60)) * time.Minute,
+	}
+}
+
+// LoopConfig - игровой цикл: игры делятся на Shards шардов, каждый тикает
+// свой воркер раз в TickInterval. Lazy - игры считаются при чтении и изменении,
+// воркеры обрабатывают только окончания майнеров
+type LoopConfig struct {
+	Shards       int
+	TickInterval time.Duration
+	Lazy         bool
+}
+
+func NewLoopConfig() *LoopConfig {
+	return &LoopConfig{
+		Shards:       getInt("LOOP_SHARDS", 4),
+		TickInterval: time.Duration(getInt("LOOP_TICK_MS", 1000)) * time.Millisecond,
+		Lazy:         getBool("LOOP_LAZY", false),
+	}
+}
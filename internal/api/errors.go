@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"miners_game/pkg/errs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type apiError struct {
+	status int
+	code   string
+}
+
+// typedErrors - коды ошибок API, клиенты ориентируются на code, а не на текст
+var typedErrors = map[error]apiError{
+	errs.ErrBadRequest:         {fiber.StatusBadRequest, "bad_request"},
+	errs.ErrUnauthorized:       {fiber.StatusUnauthorized, "unauthorized"},
+	errs.ErrIncorrectLogin:     {fiber.StatusUnauthorized, "incorrect_login"},
+	errs.ErrUserNotFound:       {fiber.StatusUnauthorized, "incorrect_login"},
+	errs.ErrEmailAlreadyExist:  {fiber.StatusConflict, "email_taken"},
+	errs.ErrUsernameTaken:      {fiber.StatusConflict, "username_taken"},
+	errs.ErrReferralNotFound:   {fiber.StatusBadRequest, "referral_not_found"},
+	errs.ErrReferralAbuse:      {fiber.StatusBadRequest, "referral_unavailable"},
+	errs.ErrExpireSession:      {fiber.StatusGone, "registration_expired"},
+	errs.ErrEmptyRegisterCode:  {fiber.StatusBadRequest, "empty_code"},
+	errs.ErrRegisterCode:       {fiber.StatusBadRequest, "invalid_code"},
+	errs.ErrGameNotFound:       {fiber.StatusNotFound, "game_not_found"},
+	errs.ErrSessionIsNotActive: {fiber.StatusConflict, "session_inactive"},
+	errs.ErrNotEnoughBalance:   {fiber.StatusUnprocessableEntity, "not_enough_balance"},
+	errs.ErrAlreadyOwn:         {fiber.StatusConflict, "already_own"},
+	errs.ErrUnknownItem:        {fiber.StatusNotFound, "unknown_item"},
+	errs.ErrGameLeased:         {fiber.StatusServiceUnavailable, "game_leased"},
+	errs.ErrServer:             {fiber.StatusInternalServerError, "server_error"},
+}
+
+// sendError - ошибка в формате {"error": {"code", "message"}}.
+// Неизвестные ошибки получают fallback: у auth это ошибки валидации формы
+func sendError(c *fiber.Ctx, err error, fallback int) error {
+	for target, typed := range typedErrors {
+		if errors.Is(err, target) {
+			return c.Status(typed.status).JSON(ErrorResponse{
+				Error: ErrorBody{Code: typed.code, Message: err.Error()},
+			})
+		}
+	}
+	code := "validation_failed"
+	message := err.Error()
+	if fallback == fiber.StatusInternalServerError {
+		code = "server_error"
+		message = errs.ErrServer.Error()
+	}
+	return c.Status(fallback).JSON(ErrorResponse{
+		Error: ErrorBody{Code: code, Message: message},
+	})
+}
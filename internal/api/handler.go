@@ -0,0 +1,285 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"miners_game/internal/auth"
+	"miners_game/internal/game"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/game/equipments"
+	"miners_game/internal/game/shop"
+	"miners_game/internal/game/upgrades"
+	"miners_game/internal/miners"
+	"miners_game/pkg/errs"
+	"miners_game/pkg/middleware"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+	"github.com/rs/zerolog"
+)
+
+//go:embed openapi.json
+var openapiSpec []byte
+
+type Handler struct {
+	router      fiber.Router
+	authService *auth.Service
+	gameService *game.Service
+	store       *session.Store
+}
+
+type HandlerDeps struct {
+	Router      fiber.Router
+	AuthService *auth.Service
+	GameService *game.Service
+	Store       *session.Store
+}
+
+func NewHandler(deps HandlerDeps) {
+	h := &Handler{
+		router:      deps.Router,
+		authService: deps.AuthService,
+		gameService: deps.GameService,
+		store:       deps.Store,
+	}
+	v1 := h.router.Group("/api/v1")
+	v1.Get("/openapi.json", h.openapi)
+	v1.Get("/shop", h.shop)
+
+	a := v1.Group("/auth")
+	a.Post("/login", h.login)
+	a.Post("/logout", h.logout)
+	a.Post("/register", h.register)
+	a.Post("/register/confirm", h.confirm)
+	a.Get("/me", h.requireUser, h.me)
+
+	g := v1.Group("/game", h.requireUser, middleware.GameMiddleware(h.store, h.gameService))
+	g.Get("/", h.state)
+	g.Get("/income", h.income)
+	g.Post("/buy", h.buy)
+}
+
+func (h *Handler) requireUser(c *fiber.Ctx) error {
+	if userID, _ := c.Locals("user_id").(string); userID == "" {
+		return sendError(c, errs.ErrUnauthorized, fiber.StatusUnauthorized)
+	}
+	return c.Next()
+}
+
+func (h *Handler) openapi(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(openapiSpec)
+}
+
+func (h *Handler) login(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+
+	var form auth.LoginForm
+	if err := c.BodyParser(&form); err != nil {
+		return sendError(c, errs.ErrBadRequest, fiber.StatusBadRequest)
+	}
+	userID, userName, err := h.authService.Login(c.UserContext(), form)
+	if err != nil {
+		return sendError(c, err, fiber.StatusBadRequest)
+	}
+	sess := c.Locals("sess").(*session.Session)
+	sess.Set("user_id", userID)
+	sess.Set("username", userName)
+	if err := sess.Save(); err != nil {
+		logger.Error().Err(err).Msg("failed to save session")
+		return sendError(c, errs.ErrServer, fiber.StatusInternalServerError)
+	}
+	return c.JSON(UserResponse{UserID: userID, Username: userName})
+}
+
+func (h *Handler) logout(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+
+	sess := c.Locals("sess").(*session.Session)
+	if err := sess.Destroy(); err != nil {
+		logger.Error().Err(err).Msg("failed to destroy session")
+		return sendError(c, errs.ErrServer, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *Handler) register(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+
+	var form auth.RegisterForm
+	if err := c.BodyParser(&form); err != nil {
+		return sendError(c, errs.ErrBadRequest, fiber.StatusBadRequest)
+	}
+	regSess, err := h.authService.StartRegistration(c.UserContext(), form, c.IP())
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed api register")
+		return sendError(c, err, fiber.StatusBadRequest)
+	}
+	sess := c.Locals("sess").(*session.Session)
+	h.authService.TrackRegistration(c.UserContext(), sess.ID())
+	sess.Set("register", regSess)
+	if err := sess.Save(); err != nil {
+		logger.Error().Err(err).Msg("failed save session")
+		return sendError(c, errs.ErrServer, fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+func (h *Handler) confirm(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	sess := c.Locals("sess").(*session.Session)
+
+	var req ConfirmRequest
+	if err := c.BodyParser(&req); err != nil {
+		return sendError(c, errs.ErrBadRequest, fiber.StatusBadRequest)
+	}
+	regSess, ok := sess.Get("register").(auth.RegisterSession)
+	if !ok {
+		return sendError(c, errs.ErrExpireSession, fiber.StatusGone)
+	}
+	userID, err := h.authService.CompleteRegistration(c.UserContext(), regSess, req.Code)
+	if err != nil {
+		if errors.Is(err, errs.ErrExpireSession) || errors.Is(err, errs.ErrUsernameTaken) {
+			h.authService.ForgetRegistration(c.UserContext(), sess.ID())
+			sess.Delete("register")
+			sess.Save()
+		}
+		return sendError(c, err, fiber.StatusBadRequest)
+	}
+	h.authService.ForgetRegistration(c.UserContext(), sess.ID())
+	sess.Delete("register")
+	sess.Set("user_id", userID)
+	sess.Set("username", regSess.Username)
+	if err := sess.Save(); err != nil {
+		logger.Error().Err(err).Msg("failed to save session")
+		return sendError(c, errs.ErrServer, fiber.StatusInternalServerError)
+	}
+	return c.Status(fiber.StatusCreated).JSON(UserResponse{UserID: userID, Username: regSess.Username})
+}
+
+func (h *Handler) me(c *fiber.Ctx) error {
+	return c.JSON(UserResponse{
+		UserID:   c.Locals("user_id").(string),
+		Username: c.Locals("username").(string),
+	})
+}
+
+func (h *Handler) state(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	userID := c.Locals("user_id").(string)
+	gameID := c.Locals("game_id").(string)
+
+	state, err := h.gameService.Snapshot(c.UserContext(), userID, gameID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed snapshot service")
+		return sendError(c, err, fiber.StatusInternalServerError)
+	}
+	return c.JSON(toGameResponse(state))
+}
+
+func (h *Handler) income(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	userID := c.Locals("user_id").(string)
+	gameID := c.Locals("game_id").(string)
+
+	b, err := h.gameService.IncomeBreakdown(c.UserContext(), userID, gameID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed incomeBreakdown service")
+		return sendError(c, err, fiber.StatusInternalServerError)
+	}
+	return c.JSON(IncomeResponse{
+		Passive:        b.Passive,
+		Miners:         b.Miners,
+		Base:           b.Base,
+		EquipmentBonus: b.EquipmentBonus,
+		UpgradeBonus:   b.UpgradeBonus,
+		Total:          b.Total,
+	})
+}
+
+func (h *Handler) buy(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	userID := c.Locals("user_id").(string)
+	gameID := c.Locals("game_id").(string)
+
+	var req BuyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return sendError(c, errs.ErrBadRequest, fiber.StatusBadRequest)
+	}
+	cases := map[string]func(context.Context, string, string, string, string) (shop.ShopCard, error){
+		"miner":     h.gameService.BuyMiner,
+		"equipment": h.gameService.BuyEquipment,
+		"upgrade":   h.gameService.BuyUpgrade,
+	}
+	buy, ok := cases[req.Kind]
+	if !ok || game.GetShopCardByName(req.Name, req.Kind).Name == "" {
+		return sendError(c, errs.ErrUnknownItem, fiber.StatusNotFound)
+	}
+	// покупки работают только с активной игрой в памяти
+	if _, err := h.gameService.EnterGame(c.UserContext(), userID, gameID); err != nil {
+		logger.Error().Err(err).Msg("failed enterGame service")
+		return sendError(c, err, fiber.StatusInternalServerError)
+	}
+	if _, err := buy(c.UserContext(), userID, gameID, req.Name, req.Kind); err != nil {
+		logger.Warn().Err(err).Msg("failed api buy")
+		return sendError(c, err, fiber.StatusInternalServerError)
+	}
+	state, err := h.gameService.Snapshot(c.UserContext(), userID, gameID)
+	if err != nil {
+		return sendError(c, err, fiber.StatusInternalServerError)
+	}
+	return c.JSON(toGameResponse(state))
+}
+
+func (h *Handler) shop(c *fiber.Ctx) error {
+	resp := ShopResponse{}
+	for _, card := range miners.MinerShopCards() {
+		cfg := miners.GetMinerConfig(card.Name)
+		resp.Miners = append(resp.Miners, ShopItem{
+			Kind: card.Kind, Name: card.Name, Title: cfg.Title, Price: cfg.Price,
+			Power: cfg.Power, Duration: cfg.Energy, Icon: card.Icon,
+		})
+	}
+	for _, card := range equipments.EquipmentShopCards() {
+		cfg := equipments.GetEquipmentConfig(card.Name)
+		resp.Equipments = append(resp.Equipments, ShopItem{
+			Kind: card.Kind, Name: card.Name, Title: cfg.Title, Price: cfg.Price,
+			Bonus: cfg.Value, Icon: card.Icon,
+		})
+	}
+	for _, card := range upgrades.UpgradeShopCards() {
+		cfg := upgrades.GetUpgradesConfig(card.Name)
+		resp.Upgrades = append(resp.Upgrades, ShopItem{
+			Kind: card.Kind, Name: card.Name, Title: cfg.Title, Price: cfg.Price,
+			Bonus: cfg.Value, Icon: card.Icon,
+		})
+	}
+	return c.JSON(resp)
+}
+
+func toGameResponse(state *domain.GameState) GameResponse {
+	resp := GameResponse{
+		GameID:       state.GameID,
+		Balance:      state.Balance,
+		IncomePerSec: state.IncomePerSec,
+		LastUpdateAt: state.LastUpdateAt,
+		Miners:       make([]MinerState, 0, len(state.Miners)),
+		Equipments:   make([]OwnedItem, 0, len(state.Equipments)),
+		Upgrades:     make([]OwnedItem, 0, len(state.Upgrades)),
+	}
+	for _, m := range state.Miners {
+		resp.Miners = append(resp.Miners, MinerState{ID: m.ID, Class: m.Class, StartAt: m.StartAt, EndAt: m.EndAt})
+	}
+	sort.Slice(resp.Miners, func(i, j int) bool {
+		return resp.Miners[i].StartAt < resp.Miners[j].StartAt
+	})
+	for _, e := range state.Equipments {
+		resp.Equipments = append(resp.Equipments, OwnedItem{Name: e.Name, Own: e.Own})
+	}
+	for _, u := range state.Upgrades {
+		resp.Upgrades = append(resp.Upgrades, OwnedItem{Name: u.Name, Own: u.Own})
+	}
+	return resp
+}
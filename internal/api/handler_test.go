@@ -0,0 +1,75 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"miners_game/internal/api"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+func newTestApp() *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("logger", zerolog.Nop())
+		c.Locals("user_id", "")
+		c.Locals("username", "")
+		return c.Next()
+	})
+	api.NewHandler(api.HandlerDeps{Router: app})
+	return app
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	resp, err := newTestApp().Test(httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &spec); err != nil {
+		t.Fatalf("expected valid json, got %v:", err)
+	}
+	for _, path := range []string{"/auth/login", "/game", "/game/buy", "/game/income", "/shop"} {
+		if _, ok := spec.Paths[path]; !ok {
+			t.Fatalf("expected path %s in spec", path)
+		}
+	}
+}
+
+func TestGameUnauthorized(t *testing.T) {
+	resp, err := newTestApp().Test(httptest.NewRequest("GET", "/api/v1/game", nil))
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+	var body api.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("expected json error, got %v:", err)
+	}
+	if body.Error.Code != "unauthorized" {
+		t.Fatalf("expected unauthorized code, got %q", body.Error.Code)
+	}
+}
+
+func TestShopCatalog(t *testing.T) {
+	resp, err := newTestApp().Test(httptest.NewRequest("GET", "/api/v1/shop", nil))
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	var shop api.ShopResponse
+	if err := json.NewDecoder(resp.Body).Decode(&shop); err != nil {
+		t.Fatalf("expected json, got %v:", err)
+	}
+	if len(shop.Miners) == 0 || shop.Miners[0].Price <= 0 {
+		t.Fatalf("expected priced miners, got %+v", shop.Miners)
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type UserResponse struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+type ConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+type BuyRequest struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+type MinerState struct {
+	ID      string `json:"id"`
+	Class   string `json:"class"`
+	StartAt int64  `json:"start_at"`
+	EndAt   int64  `json:"end_at"`
+}
+
+type OwnedItem struct {
+	Name string `json:"name"`
+	Own  bool   `json:"own"`
+}
+
+type GameResponse struct {
+	GameID       string       `json:"game_id"`
+	Balance      int64        `json:"balance"`
+	IncomePerSec int64        `json:"income_per_sec"`
+	LastUpdateAt int64        `json:"last_update_at"`
+	Miners       []MinerState `json:"miners"`
+	Equipments   []OwnedItem  `json:"equipments"`
+	Upgrades     []OwnedItem  `json:"upgrades"`
+}
+
+type ShopItem struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Title    string `json:"title"`
+	Price    int64  `json:"price"`
+	Power    int64  `json:"power,omitempty"`
+	Duration int64  `json:"duration,omitempty"`
+	Bonus    int64  `json:"bonus,omitempty"`
+	Icon     string `json:"icon"`
+}
+
+type ShopResponse struct {
+	Miners     []ShopItem `json:"miners"`
+	Equipments []ShopItem `json:"equipments"`
+	Upgrades   []ShopItem `json:"upgrades"`
+}
+
+type IncomeResponse struct {
+	Passive        int64            `json:"passive"`
+	Miners         map[string]int64 `json:"miners"`
+	Base           int64            `json:"base"`
+	EquipmentBonus int64            `json:"equipment_bonus"`
+	UpgradeBonus   int64            `json:"upgrade_bonus"`
+	Total          int64            `json:"total"`
+}
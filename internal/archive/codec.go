@@ -0,0 +1,79 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/game/equipments"
+	"miners_game/internal/game/upgrades"
+	"miners_game/internal/miners"
+)
+
+// gameData - состояние игры в архиве. Журнал экономики в базе переживает
+// удаление игры, поэтому в архив он не попадает
+type gameData struct {
+	Name         string                   `json:"name"`
+	CreatedAt    int64                    `json:"created_at"`
+	Balance      int64                    `json:"balance"`
+	IncomePerSec int64                    `json:"income_per_sec"`
+	LastUpdateAt int64                    `json:"last_update_at"`
+	Miners       map[string]*miners.Miner `json:"miners"`
+	Equipments   []equipments.Equipment   `json:"equipments"`
+	Upgrades     []upgrades.Upgrade       `json:"upgrades"`
+}
+
+func encode(game *domain.GameState) ([]byte, error) {
+	snapshot := game.Clone()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(gameData{
+		Name:         snapshot.Name,
+		CreatedAt:    snapshot.CreatedAt,
+		Balance:      snapshot.Balance,
+		IncomePerSec: snapshot.IncomePerSec,
+		LastUpdateAt: snapshot.LastUpdateAt,
+		Miners:       snapshot.Miners,
+		Equipments:   snapshot.Equipments,
+		Upgrades:     snapshot.Upgrades,
+	}); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decode - новое состояние с Version 0: при сохранении игра вставляется заново
+func decode(userID, gameID string, data []byte) (*domain.GameState, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	var g gameData
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, err
+	}
+	if g.Miners == nil {
+		g.Miners = make(map[string]*miners.Miner)
+	}
+	return &domain.GameState{
+		UserID:       userID,
+		GameID:       gameID,
+		Name:         g.Name,
+		CreatedAt:    g.CreatedAt,
+		Balance:      g.Balance,
+		IncomePerSec: g.IncomePerSec,
+		LastUpdateAt: g.LastUpdateAt,
+		Miners:       g.Miners,
+		Equipments:   g.Equipments,
+		Upgrades:     g.Upgrades,
+	}, nil
+}
@@ -0,0 +1,22 @@
+package archive
+
+import (
+	"context"
+	"miners_game/internal/game"
+	"miners_game/internal/game/domain"
+)
+
+type IArchiveRepository interface {
+	InactiveUsers(ctx context.Context, cutoff, now int64, limit int) ([]string, error)
+	Archive(ctx context.Context, row Archived, version, now int64) (bool, error)
+	ListByUser(ctx context.Context, userID string) ([]Archived, error)
+	Delete(ctx context.Context, userID, gameID string) error
+	PurgeExpiredSessions(ctx context.Context, now int64) (int64, error)
+	PurgeRegistrations(ctx context.Context, before int64) (int64, error)
+}
+
+type IGameStore interface {
+	ListByUser(ctx context.Context, userID string) ([]game.SaveSlot, error)
+	Load(ctx context.Context, userID, gameID string) (*domain.GameState, error)
+	Save(ctx context.Context, gameState *domain.GameState) error
+}
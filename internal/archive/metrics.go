@@ -0,0 +1,50 @@
+package archive
+
+import "github.com/prometheus/client_golang/prometheus"
+
+type Metrics struct {
+	RunsTotal      prometheus.Counter
+	RunsFailed     prometheus.Counter
+	RunDuration    prometheus.Histogram
+	GamesArchived  prometheus.Counter
+	GamesSkipped   prometheus.Counter
+	GamesRestored  prometheus.Counter
+	SessionsPurged *prometheus.CounterVec
+}
+
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		RunsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "archive_runs_total",
+			Help: "Total archive and retention runs",
+		}),
+		RunsFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "archive_runs_failed_total",
+			Help: "Total archive runs stopped by a storage error",
+		}),
+		RunDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "archive_run_duration_seconds",
+			Help:    "Duration of one archive and retention run",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+		}),
+		GamesArchived: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "archive_games_archived_total",
+			Help: "Total games moved to the archive",
+		}),
+		GamesSkipped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "archive_games_skipped_total",
+			Help: "Total games left in place because they changed or were leased during archiving",
+		}),
+		GamesRestored: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "archive_games_restored_total",
+			Help: "Total games restored from the archive",
+		}),
+		SessionsPurged: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "archive_sessions_purged_total",
+			Help: "Total sessions deleted by retention",
+		}, []string{"kind"}),
+	}
+	reg.MustRegister(m.RunsTotal, m.RunsFailed, m.RunDuration, m.GamesArchived, m.GamesSkipped, m.GamesRestored, m.SessionsPurged)
+
+	return m
+}
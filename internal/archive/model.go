@@ -0,0 +1,19 @@
+package archive
+
+// Archived - строка games_archive: Data - сжатый gzip JSON состояния игры
+type Archived struct {
+	UserID       string
+	GameID       string
+	Name         string
+	LastUpdateAt int64
+	ArchivedAt   int64
+	Data         []byte
+}
+
+// RunResult - итог одного прохода архивации
+type RunResult struct {
+	Archived      int
+	Skipped       int
+	Sessions      int64
+	Registrations int64
+}
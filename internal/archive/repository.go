@@ -0,0 +1,197 @@
+package archive
+
+import (
+	"context"
+	"miners_game/pkg/errs"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+type Repository struct {
+	dbPool *pgxpool.Pool
+	logger zerolog.Logger
+}
+
+type RepositoryDeps struct {
+	DbPool *pgxpool.Pool
+	Logger zerolog.Logger
+}
+
+func NewRepository(deps RepositoryDeps) *Repository {
+	return &Repository{
+		dbPool: deps.DbPool,
+		logger: deps.Logger,
+	}
+}
+
+// InactiveUsers - аккаунты, где ни одна игра не менялась с cutoff
+// и ни одну не арендует живой инстанс
+func (r *Repository) InactiveUsers(ctx context.Context, cutoff, now int64, limit int) ([]string, error) {
+	query := `
+		SELECT g.user_id
+		FROM games g
+		WHERE NOT EXISTS (
+			SELECT 1 FROM game_leases l
+			WHERE l.user_id = g.user_id AND l.expires_at >= @now
+		)
+		GROUP BY g.user_id
+		HAVING MAX(g.last_update_at) < @cutoff
+		ORDER BY MAX(g.last_update_at)
+		LIMIT @limit
+	`
+	rows, err := r.dbPool.Query(ctx, query, pgx.NamedArgs{
+		"cutoff": cutoff,
+		"now":    now,
+		"limit":  limit,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to select inactive users")
+		return nil, errs.ErrServer
+	}
+	defer rows.Close()
+
+	users := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			r.logger.Error().Err(err).Msg("failed to scan inactive user")
+			return nil, errs.ErrServer
+		}
+		users = append(users, userID)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("failed to select inactive users")
+		return nil, errs.ErrServer
+	}
+	return users, nil
+}
+
+// Archive - запись в архив и удаление игры в одной транзакции. false - игру
+// успели изменить или арендовать после чтения, она остаётся на месте
+func (r *Repository) Archive(ctx context.Context, row Archived, version, now int64) (bool, error) {
+	tx, err := r.dbPool.Begin(ctx)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", row.UserID).Str("game_id", row.GameID).Msg("failed to begin archive transaction")
+		return false, errs.ErrServer
+	}
+	defer tx.Rollback(ctx)
+
+	args := pgx.NamedArgs{
+		"user_id":        row.UserID,
+		"game_id":        row.GameID,
+		"name":           row.Name,
+		"last_update_at": row.LastUpdateAt,
+		"archived_at":    row.ArchivedAt,
+		"data":           row.Data,
+		"version":        version,
+		"now":            now,
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO games_archive (user_id, game_id, name, last_update_at, archived_at, data)
+		VALUES (@user_id, @game_id, @name, @last_update_at, @archived_at, @data)
+		ON CONFLICT (user_id, game_id) DO UPDATE
+		SET name = EXCLUDED.name, last_update_at = EXCLUDED.last_update_at,
+			archived_at = EXCLUDED.archived_at, data = EXCLUDED.data
+	`, args); err != nil {
+		r.logger.Error().Err(err).Str("user_id", row.UserID).Str("game_id", row.GameID).Msg("failed to archive game")
+		return false, errs.ErrServer
+	}
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM games g
+		WHERE g.user_id = @user_id AND g.game_id = @game_id AND g.version = @version
+		AND NOT EXISTS (
+			SELECT 1 FROM game_leases l
+			WHERE l.user_id = g.user_id AND l.game_id = g.game_id AND l.expires_at >= @now
+		)
+	`, args)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", row.UserID).Str("game_id", row.GameID).Msg("failed to delete archived game")
+		return false, errs.ErrServer
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error().Err(err).Str("user_id", row.UserID).Str("game_id", row.GameID).Msg("failed to commit archive transaction")
+		return false, errs.ErrServer
+	}
+	return true, nil
+}
+
+func (r *Repository) ListByUser(ctx context.Context, userID string) ([]Archived, error) {
+	query := `
+		SELECT game_id, name, last_update_at, archived_at, data
+		FROM games_archive
+		WHERE user_id = @user_id
+		ORDER BY last_update_at
+	`
+	rows, err := r.dbPool.Query(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to list archived games")
+		return nil, errs.ErrServer
+	}
+	defer rows.Close()
+
+	archived := []Archived{}
+	for rows.Next() {
+		a := Archived{UserID: userID}
+		if err := rows.Scan(&a.GameID, &a.Name, &a.LastUpdateAt, &a.ArchivedAt, &a.Data); err != nil {
+			r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to scan archived game")
+			return nil, errs.ErrServer
+		}
+		archived = append(archived, a)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to list archived games")
+		return nil, errs.ErrServer
+	}
+	return archived, nil
+}
+
+func (r *Repository) Delete(ctx context.Context, userID, gameID string) error {
+	query := `DELETE FROM games_archive WHERE user_id = @user_id AND game_id = @game_id`
+	if _, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+		"game_id": gameID,
+	}); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to delete archived game")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+// PurgeExpiredSessions - e = 0 у сессий без срока, их не трогаем
+func (r *Repository) PurgeExpiredSessions(ctx context.Context, now int64) (int64, error) {
+	tag, err := r.dbPool.Exec(ctx, `DELETE FROM session WHERE e <> 0 AND e < @now`, pgx.NamedArgs{
+		"now": now,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to purge expired sessions")
+		return 0, errs.ErrServer
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PurgeRegistrations - сессии регистраций, начатых раньше before, код по
+// которым так и не ввели. Завершённые регистрации auth убирает из registrations
+func (r *Repository) PurgeRegistrations(ctx context.Context, before int64) (int64, error) {
+	query := `
+		WITH expired AS (
+			DELETE FROM registrations WHERE started_at < @before
+			RETURNING session_id
+		)
+		DELETE FROM session WHERE k IN (SELECT session_id FROM expired)
+	`
+	tag, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"before": before,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to purge registration sessions")
+		return 0, errs.ErrServer
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,169 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"miners_game/config"
+	"miners_game/pkg/errs"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type Service struct {
+	repo    IArchiveRepository
+	games   IGameStore
+	config  *config.ArchiveConfig
+	metrics *Metrics
+	logger  zerolog.Logger
+}
+
+type ServiceDeps struct {
+	Repo    IArchiveRepository
+	Games   IGameStore
+	Config  *config.ArchiveConfig
+	Metrics *Metrics
+	Logger  zerolog.Logger
+}
+
+func NewService(deps ServiceDeps) *Service {
+	return &Service{
+		repo:    deps.Repo,
+		games:   deps.Games,
+		config:  deps.Config,
+		metrics: deps.Metrics,
+		logger:  deps.Logger,
+	}
+}
+
+// Run - один проход: игры неактивных аккаунтов уходят в архив, затем
+// удаляются истёкшие сессии и брошенные регистрации. Аккаунт архивируется
+// целиком, чтобы список сохранений после возврата был полным
+func (s *Service) Run(ctx context.Context) (result RunResult, err error) {
+	start := time.Now()
+	defer func() {
+		if s.metrics == nil {
+			return
+		}
+		s.metrics.RunsTotal.Inc()
+		s.metrics.RunDuration.Observe(time.Since(start).Seconds())
+		s.metrics.GamesArchived.Add(float64(result.Archived))
+		s.metrics.GamesSkipped.Add(float64(result.Skipped))
+		s.metrics.SessionsPurged.WithLabelValues("expired").Add(float64(result.Sessions))
+		s.metrics.SessionsPurged.WithLabelValues("registration").Add(float64(result.Registrations))
+		if err != nil {
+			s.metrics.RunsFailed.Inc()
+		}
+	}()
+
+	now := start.Unix()
+	users, err := s.repo.InactiveUsers(ctx, now-int64(s.config.InactiveAfter.Seconds()), now, s.config.BatchSize)
+	if err != nil {
+		return result, err
+	}
+	for _, userID := range users {
+		if err = s.archiveUser(ctx, userID, now, &result); err != nil {
+			return result, err
+		}
+	}
+
+	if result.Sessions, err = s.repo.PurgeExpiredSessions(ctx, now); err != nil {
+		return result, err
+	}
+	before := now - int64(s.config.RegistrationTTL.Seconds())
+	if result.Registrations, err = s.repo.PurgeRegistrations(ctx, before); err != nil {
+		return result, err
+	}
+
+	s.logger.Info().
+		Int("users", len(users)).
+		Int("archived", result.Archived).
+		Int("skipped", result.Skipped).
+		Int64("sessions", result.Sessions).
+		Int64("registrations", result.Registrations).
+		Dur("duration", time.Since(start)).
+		Msg("archive run finished")
+	return result, nil
+}
+
+func (s *Service) archiveUser(ctx context.Context, userID string, now int64, result *RunResult) error {
+	slots, err := s.games.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, slot := range slots {
+		game, err := s.games.Load(ctx, userID, slot.GameID)
+		if errors.Is(err, errs.ErrGameNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		data, err := encode(game)
+		if err != nil {
+			s.logger.Error().Err(err).Str("user_id", userID).Str("game_id", slot.GameID).Msg("failed to encode game for archive")
+			result.Skipped++
+			continue
+		}
+		ok, err := s.repo.Archive(ctx, Archived{
+			UserID:       userID,
+			GameID:       game.GameID,
+			Name:         game.Name,
+			LastUpdateAt: game.LastUpdateAt,
+			ArchivedAt:   now,
+			Data:         data,
+		}, game.Version, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		result.Archived++
+	}
+	return nil
+}
+
+// Restore - возвращает все игры аккаунта из архива, 0 - архивных игр нет.
+// Строка архива удаляется только после сохранения игры: при сбое игра
+// останется в архиве и вернётся при следующем входе
+func (s *Service) Restore(ctx context.Context, userID string) (int, error) {
+	archived, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	restored := 0
+	var firstErr error
+	for _, a := range archived {
+		if err := s.restore(ctx, a); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		restored++
+	}
+	if s.metrics != nil {
+		s.metrics.GamesRestored.Add(float64(restored))
+	}
+	if restored > 0 {
+		s.logger.Info().Str("user_id", userID).Int("games", restored).Msg("games restored from archive")
+	}
+	return restored, firstErr
+}
+
+func (s *Service) restore(ctx context.Context, a Archived) error {
+	game, err := decode(a.UserID, a.GameID, a.Data)
+	if err != nil {
+		s.logger.Error().Err(err).Str("user_id", a.UserID).Str("game_id", a.GameID).Msg("failed to decode archived game")
+		return errs.ErrServer
+	}
+	// Save с Version 0 только вставляет: если игру уже вернул другой инстанс,
+	// будет ErrSaveConflict и архив не тронем
+	if err := s.games.Save(ctx, game); err != nil {
+		s.logger.Warn().Err(err).Str("user_id", a.UserID).Str("game_id", a.GameID).Msg("failed to restore archived game")
+		return err
+	}
+	return s.repo.Delete(ctx, a.UserID, a.GameID)
+}
@@ -0,0 +1,175 @@
+package archive_test
+
+import (
+	"context"
+	"miners_game/config"
+	"miners_game/internal/archive"
+	"miners_game/internal/game"
+	"miners_game/internal/game/domain"
+	"miners_game/pkg/errs"
+	"testing"
+	"time"
+)
+
+type MockArchiveRepository struct {
+	Users    []string
+	Rows     map[string]archive.Archived
+	Leased   map[string]bool
+	Versions map[string]int64
+}
+
+func (m *MockArchiveRepository) InactiveUsers(ctx context.Context, cutoff, now int64, limit int) ([]string, error) {
+	return m.Users, nil
+}
+
+func (m *MockArchiveRepository) Archive(ctx context.Context, row archive.Archived, version, now int64) (bool, error) {
+	if m.Leased[row.GameID] {
+		return false, nil
+	}
+	m.Rows[row.GameID] = row
+	m.Versions[row.GameID] = version
+	return true, nil
+}
+
+func (m *MockArchiveRepository) ListByUser(ctx context.Context, userID string) ([]archive.Archived, error) {
+	rows := []archive.Archived{}
+	for _, row := range m.Rows {
+		if row.UserID == userID {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (m *MockArchiveRepository) Delete(ctx context.Context, userID, gameID string) error {
+	delete(m.Rows, gameID)
+	return nil
+}
+
+func (m *MockArchiveRepository) PurgeExpiredSessions(ctx context.Context, now int64) (int64, error) {
+	return 3, nil
+}
+
+func (m *MockArchiveRepository) PurgeRegistrations(ctx context.Context, before int64) (int64, error) {
+	return 1, nil
+}
+
+type MockGameStore struct {
+	Games   map[string]*domain.GameState
+	SaveErr error
+}
+
+func (m *MockGameStore) ListByUser(ctx context.Context, userID string) ([]game.SaveSlot, error) {
+	slots := []game.SaveSlot{}
+	for _, g := range m.Games {
+		if g.UserID == userID {
+			slots = append(slots, game.SaveSlot{GameID: g.GameID})
+		}
+	}
+	return slots, nil
+}
+
+func (m *MockGameStore) Load(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
+	g, ok := m.Games[gameID]
+	if !ok {
+		return nil, errs.ErrGameNotFound
+	}
+	return g.Clone(), nil
+}
+
+func (m *MockGameStore) Save(ctx context.Context, gameState *domain.GameState) error {
+	if m.SaveErr != nil {
+		return m.SaveErr
+	}
+	m.Games[gameState.GameID] = gameState
+	return nil
+}
+
+func newArchiveService(repo *MockArchiveRepository, games *MockGameStore) *archive.Service {
+	return archive.NewService(archive.ServiceDeps{
+		Repo:  repo,
+		Games: games,
+		Config: &config.ArchiveConfig{
+			InactiveAfter:   30 * 24 * time.Hour,
+			BatchSize:       10,
+			RegistrationTTL: time.Hour,
+		},
+	})
+}
+
+func TestRunArchivesAndRestoresAccount(t *testing.T) {
+	played := domain.NewGameState("testUserID", "testGameID", time.Now().Unix())
+	played.Name = "Сохранение"
+	played.Balance = 4200
+	played.AddMiner("base", time.Now().Unix())
+	played.Version = 7
+	leased := domain.NewGameState("testUserID", "leasedGameID", time.Now().Unix())
+
+	repo := &MockArchiveRepository{
+		Users:    []string{"testUserID"},
+		Rows:     map[string]archive.Archived{},
+		Leased:   map[string]bool{"leasedGameID": true},
+		Versions: map[string]int64{},
+	}
+	games := &MockGameStore{Games: map[string]*domain.GameState{
+		"testGameID":   played,
+		"leasedGameID": leased,
+	}}
+	archiveService := newArchiveService(repo, games)
+
+	result, err := archiveService.Run(context.Background())
+	if err != nil {
+		t.Fatalf("expected success, got err %v:", err)
+	}
+	if result.Archived != 1 || result.Skipped != 1 {
+		t.Fatalf("expected 1 archived and 1 skipped game, got %d and %d", result.Archived, result.Skipped)
+	}
+	if result.Sessions != 3 || result.Registrations != 1 {
+		t.Fatalf("expected purged sessions to be reported, got %+v", result)
+	}
+	if repo.Versions["testGameID"] != 7 {
+		t.Fatalf("expected archive to be guarded by version 7, got %d", repo.Versions["testGameID"])
+	}
+
+	delete(games.Games, "testGameID")
+	restored, err := archiveService.Restore(context.Background(), "testUserID")
+	if err != nil {
+		t.Fatalf("expected success, got err %v:", err)
+	}
+	if restored != 1 {
+		t.Fatalf("expected 1 restored game, got %d", restored)
+	}
+	g := games.Games["testGameID"]
+	if g == nil || g.Balance != 4200 || len(g.Miners) != 1 || g.Name != "Сохранение" {
+		t.Fatalf("expected archived state to be restored, got %+v", g)
+	}
+	if g.Version != 0 {
+		t.Fatalf("expected restored game to be inserted with version 0, got %d", g.Version)
+	}
+	if len(repo.Rows) != 0 {
+		t.Fatalf("expected archive row to be deleted after restore")
+	}
+}
+
+func TestRestoreKeepsArchiveOnSaveError(t *testing.T) {
+	played := domain.NewGameState("testUserID", "testGameID", time.Now().Unix())
+	repo := &MockArchiveRepository{
+		Users:    []string{"testUserID"},
+		Rows:     map[string]archive.Archived{},
+		Versions: map[string]int64{},
+	}
+	games := &MockGameStore{Games: map[string]*domain.GameState{"testGameID": played}}
+	archiveService := newArchiveService(repo, games)
+	if _, err := archiveService.Run(context.Background()); err != nil {
+		t.Fatalf("expected success, got err %v:", err)
+	}
+
+	games.SaveErr = errs.ErrServer
+	restored, err := archiveService.Restore(context.Background(), "testUserID")
+	if err == nil || restored != 0 {
+		t.Fatalf("expected restore to fail, got %d restored", restored)
+	}
+	if _, ok := repo.Rows["testGameID"]; !ok {
+		t.Fatalf("expected archive row to be kept until the game is saved")
+	}
+}
@@ -1,5 +1,7 @@
 package email
 
+import "context"
+
 type IEmailService interface {
-	Send(to, code string) error
+	Send(ctx context.Context, to, code string) error
 }
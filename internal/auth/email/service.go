@@ -1,9 +1,15 @@
 package email
 
 import (
+	"context"
+	"time"
+
 	"github.com/rs/zerolog"
 )
 
+// sendTimeout - дедлайн отправки одного письма
+const sendTimeout = 10 * time.Second
+
 type Service struct {
 	logger zerolog.Logger
 }
@@ -18,7 +24,12 @@ func NewService(deps ServiceDeps) *Service {
 	}
 }
 
-func (s *Service) Send(to, code string) error {
+func (s *Service) Send(ctx context.Context, to, code string) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	s.logger.Info().Str("to", to).Str("code", code).Msg("send verification email")
 	return nil
 }
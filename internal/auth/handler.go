@@ -63,7 +63,7 @@ func (h *Handler) login(c *fiber.Ctx) error {
 		Password: c.FormValue("password"),
 	}
 
-	userID, userName, err := h.authService.Login(form)
+	userID, userName, err := h.authService.Login(c.UserContext(), form)
 	if err != nil {
 		component := components.Notification(err.Error(), components.NotificationFail)
 		return tadapter.Render(c, component, fiber.StatusBadRequest)
@@ -99,15 +99,17 @@ func (h *Handler) register(c *fiber.Ctx) error {
 		}
 		regSess := data.(RegisterSession)
 		code := c.FormValue("code")
-		userID, err := h.authService.CompleteRegistration(regSess, code)
+		userID, err := h.authService.CompleteRegistration(c.UserContext(), regSess, code)
 		if err != nil {
-			if errors.Is(err, errs.ErrExpireSession) {
+			if errors.Is(err, errs.ErrExpireSession) || errors.Is(err, errs.ErrUsernameTaken) {
+				h.authService.ForgetRegistration(c.UserContext(), sess.ID())
 				sess.Delete("register")
 				sess.Save()
 			}
 			component := components.Notification(err.Error(), components.NotificationFail)
 			return tadapter.Render(c, component, fiber.StatusBadRequest)
 		}
+		h.authService.ForgetRegistration(c.UserContext(), sess.ID())
 		sess.Delete("register")
 		sess.Set("user_id", userID)
 		sess.Set("username", regSess.Username)
@@ -126,13 +128,15 @@ func (h *Handler) register(c *fiber.Ctx) error {
 			UserName:        c.FormValue("userName"),
 			Password:        c.FormValue("password"),
 			PasswordConfirm: c.FormValue("passwordConfirm"),
+			ReferralCode:    c.FormValue("referralCode"),
 		}
-		regSess, err := h.authService.StartRegistration(form)
+		regSess, err := h.authService.StartRegistration(c.UserContext(), form, c.IP())
 		if err != nil {
 			logger.Warn().Err(err).Msg("failed register step default")
 			component := components.Notification(err.Error(), components.NotificationFail)
 			return tadapter.Render(c, component, fiber.StatusBadRequest)
 		}
+		h.authService.TrackRegistration(c.UserContext(), sess.ID())
 		sess.Set("register", regSess)
 		if err := sess.Save(); err != nil {
 			logger.Error().Err(err).Msg("failed save session")
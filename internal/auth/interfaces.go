@@ -0,0 +1,13 @@
+package auth
+
+import "context"
+
+type IReferralService interface {
+	Validate(ctx context.Context, referralCode, email, ip string) (string, error)
+	Link(ctx context.Context, referrerID, refereeID, ip string) error
+}
+
+type IRegistrationRepository interface {
+	Track(ctx context.Context, sessionID string, startedAt int64) error
+	Forget(ctx context.Context, sessionID string) error
+}
@@ -6,4 +6,6 @@ type RegisterSession struct {
 	Username       string
 	HashedPassword string
 	ExpiresAt      int64
+	ReferrerID     string
+	IP             string
 }
@@ -10,4 +10,5 @@ type RegisterForm struct {
 	UserName        string `json:"userName" validate:"required|minLen:8"`
 	Password        string `json:"password" validate:"required|minLen:8"`
 	PasswordConfirm string `json:"passwordConfirm" validate:"required|eqField:password"`
+	ReferralCode    string `json:"referralCode"`
 }
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"miners_game/pkg/database"
+	"miners_game/pkg/errs"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// RegistrationRepository - сессии незавершённых регистраций. Архив удаляет
+// по этой таблице брошенные регистрации, не разбирая данные сессий
+type RegistrationRepository struct {
+	dbPool  *pgxpool.Pool
+	timeout time.Duration
+	logger  zerolog.Logger
+}
+
+type RegistrationRepositoryDeps struct {
+	DbPool  *pgxpool.Pool
+	Timeout time.Duration
+	Logger  zerolog.Logger
+}
+
+func NewRegistrationRepository(deps RegistrationRepositoryDeps) *RegistrationRepository {
+	return &RegistrationRepository{
+		dbPool:  deps.DbPool,
+		timeout: deps.Timeout,
+		logger:  deps.Logger,
+	}
+}
+
+// Track - повторная регистрация в той же сессии переносит начало
+func (r *RegistrationRepository) Track(ctx context.Context, sessionID string, startedAt int64) error {
+	query := `
+		INSERT INTO registrations (session_id, started_at)
+		VALUES (@session_id, @started_at)
+		ON CONFLICT (session_id) DO UPDATE SET started_at = EXCLUDED.started_at
+	`
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"session_id": sessionID,
+		"started_at": startedAt,
+	}); err != nil {
+		r.logger.Error().Err(err).Msg("failed to track registration")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *RegistrationRepository) Forget(ctx context.Context, sessionID string) error {
+	query := `DELETE FROM registrations WHERE session_id = @session_id`
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"session_id": sessionID,
+	}); err != nil {
+		r.logger.Error().Err(err).Msg("failed to forget registration")
+		return errs.ErrServer
+	}
+	return nil
+}
@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"miners_game/config"
 	"miners_game/internal/auth/email"
 	"miners_game/internal/user"
+	"miners_game/pkg/clock"
 	"miners_game/pkg/code"
 	"miners_game/pkg/errs"
 	"time"
@@ -13,33 +16,45 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// registerCodeTTL - сколько действует код из письма
+const registerCodeTTL = 10 * time.Minute
+
 type Service struct {
-	userRepo     user.IUserRepository
-	emailService email.IEmailService
-	gmailConfig  *config.GmailConfig
-	metrics      *Metrics
-	logger       zerolog.Logger
+	userRepo      user.IUserRepository
+	emailService  email.IEmailService
+	referrals     IReferralService
+	registrations IRegistrationRepository
+	gmailConfig   *config.GmailConfig
+	clock         clock.Clock
+	metrics       *Metrics
+	logger        zerolog.Logger
 }
 
 type ServiceDeps struct {
 	UserRepository user.IUserRepository
 	EmailService   email.IEmailService
+	Referrals      IReferralService
+	Registrations  IRegistrationRepository
 	GmailConfig    *config.GmailConfig
+	Clock          clock.Clock
 	Metrics        *Metrics
 	Logger         zerolog.Logger
 }
 
 func NewService(deps ServiceDeps) *Service {
 	return &Service{
-		userRepo:     deps.UserRepository,
-		emailService: deps.EmailService,
-		gmailConfig:  deps.GmailConfig,
-		metrics:      deps.Metrics,
-		logger:       deps.Logger,
+		userRepo:      deps.UserRepository,
+		emailService:  deps.EmailService,
+		referrals:     deps.Referrals,
+		registrations: deps.Registrations,
+		gmailConfig:   deps.GmailConfig,
+		clock:         clock.OrReal(deps.Clock),
+		metrics:       deps.Metrics,
+		logger:        deps.Logger,
 	}
 }
 
-func (s *Service) Login(form LoginForm) (userID, userName string, err error) {
+func (s *Service) Login(ctx context.Context, form LoginForm) (userID, userName string, err error) {
 	defer func() {
 		if s.metrics == nil {
 			return
@@ -53,7 +68,7 @@ func (s *Service) Login(form LoginForm) (userID, userName string, err error) {
 		s.logger.Warn().Err(v.Errors).Msg("failed to validate login form")
 		return "", "", v.Errors.OneError()
 	}
-	user, err := s.userRepo.FindByEmail(form.Email)
+	user, err := s.userRepo.FindByEmail(ctx, form.Email)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("failed to find user by email")
 		return "", "", err
@@ -69,7 +84,7 @@ func (s *Service) Login(form LoginForm) (userID, userName string, err error) {
 	return user.ID, user.UserName, nil
 }
 
-func (s *Service) StartRegistration(form RegisterForm) (formSess RegisterSession, err error) {
+func (s *Service) StartRegistration(ctx context.Context, form RegisterForm, ip string) (formSess RegisterSession, err error) {
 	defer func() {
 		if s.metrics == nil {
 			return
@@ -84,18 +99,39 @@ func (s *Service) StartRegistration(form RegisterForm) (formSess RegisterSession
 		s.logger.Warn().Err(v.Errors).Msg("failed to validate register form")
 		return RegisterSession{}, v.Errors.OneError()
 	}
-	user, _ := s.userRepo.FindByEmail(form.Email)
+	user, err := s.userRepo.FindByEmail(ctx, form.Email)
+	if err != nil && !errors.Is(err, errs.ErrUserNotFound) {
+		s.logger.Error().Err(err).Msg("failed to find user by email")
+		return RegisterSession{}, errs.ErrServer
+	}
 	if user != nil {
 		s.logger.Warn().Msg("failed user already exist")
 		return RegisterSession{}, errs.ErrEmailAlreadyExist
 	}
+	user, err = s.userRepo.FindByUsername(ctx, form.UserName)
+	if err != nil && !errors.Is(err, errs.ErrUserNotFound) {
+		s.logger.Error().Err(err).Msg("failed to find user by username")
+		return RegisterSession{}, errs.ErrServer
+	}
+	if user != nil {
+		s.logger.Warn().Msg("failed username already exist")
+		return RegisterSession{}, errs.ErrUsernameTaken
+	}
+	referrerID := ""
+	if s.referrals != nil {
+		referrerID, err = s.referrals.Validate(ctx, form.ReferralCode, form.Email, ip)
+		if err != nil {
+			s.logger.Warn().Err(err).Msg("failed to validate referral code")
+			return RegisterSession{}, err
+		}
+	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(form.Password), bcrypt.DefaultCost)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("failed to hash password")
 		return RegisterSession{}, errs.ErrServer
 	}
 	code := code.Generate()
-	if err := s.emailService.Send(form.Email, code); err != nil {
+	if err := s.emailService.Send(ctx, form.Email, code); err != nil {
 		s.logger.Error().Err(err).Msg("failed to send email")
 		return RegisterSession{}, errs.ErrServer
 	}
@@ -104,12 +140,14 @@ func (s *Service) StartRegistration(form RegisterForm) (formSess RegisterSession
 		Code:           code,
 		Username:       form.UserName,
 		HashedPassword: string(hashedPassword),
-		ExpiresAt:      time.Now().Add(10 * time.Minute).Unix(),
+		ExpiresAt:      s.clock.Now().Add(registerCodeTTL).Unix(),
+		ReferrerID:     referrerID,
+		IP:             ip,
 	}
 	return sess, nil
 }
 
-func (s *Service) CompleteRegistration(sess RegisterSession, enteredCode string) (userID string, err error) {
+func (s *Service) CompleteRegistration(ctx context.Context, sess RegisterSession, enteredCode string) (userID string, err error) {
 	defer func() {
 		if s.metrics == nil {
 			return
@@ -120,7 +158,7 @@ func (s *Service) CompleteRegistration(sess RegisterSession, enteredCode string)
 			s.metrics.RegisterSuccessTotal.Inc()
 		}
 	}()
-	if time.Now().Unix() > sess.ExpiresAt {
+	if s.clock.Now().Unix() > sess.ExpiresAt {
 		s.logger.Warn().Msg("failed expire session")
 		return "", errs.ErrExpireSession
 	}
@@ -131,12 +169,43 @@ func (s *Service) CompleteRegistration(sess RegisterSession, enteredCode string)
 		return "", errs.ErrRegisterCode
 	}
 
-	user := user.NewUser(sess.Email, sess.HashedPassword, sess.Username)
+	user := user.NewUser(sess.Email, sess.HashedPassword, sess.Username, sess.IP)
 
-	if err := s.userRepo.SaveUser(user); err != nil {
+	if err := s.userRepo.SaveUser(ctx, user); err != nil {
+		// ник могли занять между проверкой в StartRegistration и вводом кода
+		if errors.Is(err, errs.ErrUsernameTaken) {
+			s.logger.Warn().Str("email", sess.Email).Msg("failed username already exist")
+			return "", errs.ErrUsernameTaken
+		}
 		s.logger.Error().Err(err).Str("email", sess.Email).Msg("failed to save user")
 		return "", errs.ErrServer
 	}
+	if s.referrals != nil && sess.ReferrerID != "" {
+		if err := s.referrals.Link(ctx, sess.ReferrerID, user.ID, sess.IP); err != nil {
+			s.logger.Error().Err(err).Str("user_id", user.ID).Msg("failed to link referral")
+		}
+	}
 
 	return user.ID, nil
 }
+
+// TrackRegistration - сессия с начатой регистрацией, её удалит архив, если код
+// так и не введут. Без учёта сессия просто доживёт до своего срока
+func (s *Service) TrackRegistration(ctx context.Context, sessionID string) {
+	if s.registrations == nil {
+		return
+	}
+	if err := s.registrations.Track(ctx, sessionID, s.clock.Now().Unix()); err != nil {
+		s.logger.Error().Err(err).Msg("failed to track registration")
+	}
+}
+
+// ForgetRegistration - регистрация в сессии завершена или сброшена
+func (s *Service) ForgetRegistration(ctx context.Context, sessionID string) {
+	if s.registrations == nil {
+		return
+	}
+	if err := s.registrations.Forget(ctx, sessionID); err != nil {
+		s.logger.Error().Err(err).Msg("failed to forget registration")
+	}
+}
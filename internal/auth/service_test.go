@@ -1,9 +1,11 @@
 package auth_test
 
 import (
+	"context"
 	"errors"
 	"miners_game/internal/auth"
 	"miners_game/internal/user"
+	"miners_game/pkg/clock"
 	"miners_game/pkg/errs"
 	"testing"
 	"time"
@@ -12,21 +14,55 @@ import (
 )
 
 type MockUserRepository struct {
-	MockFindByEmail func(email string) (*user.User, error)
+	MockFindByEmail    func(email string) (*user.User, error)
+	MockFindByUsername func(username string) (*user.User, error)
+	MockSaveUser       func(user *user.User) error
 }
 
-func (m *MockUserRepository) SaveUser(user *user.User) error {
-	return nil
+func (m *MockUserRepository) SaveUser(ctx context.Context, user *user.User) error {
+	if m.MockSaveUser == nil {
+		return nil
+	}
+	return m.MockSaveUser(user)
 }
 
-func (m *MockUserRepository) FindByEmail(email string) (*user.User, error) {
+func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
 	return m.MockFindByEmail(email)
 }
 
+func (m *MockUserRepository) FindByUsername(ctx context.Context, username string) (*user.User, error) {
+	if m.MockFindByUsername == nil {
+		return nil, errs.ErrUserNotFound
+	}
+	return m.MockFindByUsername(username)
+}
+
+func (m *MockUserRepository) FindByReferralCode(ctx context.Context, referralCode string) (*user.User, error) {
+	return nil, errs.ErrUserNotFound
+}
+
+func (m *MockUserRepository) SetProfileHidden(ctx context.Context, userID string, hidden bool) error {
+	return nil
+}
+
+type MockRegistrationRepository struct {
+	Started map[string]int64
+}
+
+func (m *MockRegistrationRepository) Track(ctx context.Context, sessionID string, startedAt int64) error {
+	m.Started[sessionID] = startedAt
+	return nil
+}
+
+func (m *MockRegistrationRepository) Forget(ctx context.Context, sessionID string) error {
+	delete(m.Started, sessionID)
+	return nil
+}
+
 type MockEmailService struct {
 }
 
-func (m *MockEmailService) Send(to, code string) error {
+func (m *MockEmailService) Send(ctx context.Context, to, code string) error {
 	return nil
 }
 
@@ -47,11 +83,83 @@ func TestStartRegisterSuccess(t *testing.T) {
 		UserRepository: repo,
 		EmailService:   emailService,
 	})
-	if _, err := authService.StartRegistration(form); err != nil {
+	if _, err := authService.StartRegistration(context.Background(), form, "127.0.0.1"); err != nil {
 		t.Fatalf("expected success, got error: %v", err)
 	}
 }
 
+func TestStartRegisterUsernameTaken(t *testing.T) {
+	repo := &MockUserRepository{
+		MockFindByEmail: func(email string) (*user.User, error) {
+			return nil, errs.ErrUserNotFound
+		},
+		MockFindByUsername: func(username string) (*user.User, error) {
+			return &user.User{UserName: username}, nil
+		},
+	}
+	form := auth.RegisterForm{
+		Email:           "testReg@gmail.com",
+		UserName:        "testUsername",
+		Password:        "testPass",
+		PasswordConfirm: "testPass",
+	}
+	authService := auth.NewService(auth.ServiceDeps{
+		UserRepository: repo,
+		EmailService:   &MockEmailService{},
+	})
+	_, err := authService.StartRegistration(context.Background(), form, "127.0.0.1")
+	if !errors.Is(err, errs.ErrUsernameTaken) {
+		t.Fatalf("expected ErrUsernameTaken, got %v:", err)
+	}
+}
+
+func TestStartRegisterUsernameLookupFails(t *testing.T) {
+	repo := &MockUserRepository{
+		MockFindByEmail: func(email string) (*user.User, error) {
+			return nil, errs.ErrUserNotFound
+		},
+		MockFindByUsername: func(username string) (*user.User, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+	form := auth.RegisterForm{
+		Email:           "testReg@gmail.com",
+		UserName:        "testUsername",
+		Password:        "testPass",
+		PasswordConfirm: "testPass",
+	}
+	authService := auth.NewService(auth.ServiceDeps{
+		UserRepository: repo,
+		EmailService:   &MockEmailService{},
+	})
+	_, err := authService.StartRegistration(context.Background(), form, "127.0.0.1")
+	if !errors.Is(err, errs.ErrServer) {
+		t.Fatalf("expected ErrServer, got %v:", err)
+	}
+}
+
+func TestCompleteRegistrationUsernameTaken(t *testing.T) {
+	repo := &MockUserRepository{
+		MockSaveUser: func(user *user.User) error {
+			return errs.ErrUsernameTaken
+		},
+	}
+	sess := auth.RegisterSession{
+		Email:          "testReg@gmail.com",
+		Code:           "admin",
+		Username:       "testUsername",
+		HashedPassword: "testPass",
+		ExpiresAt:      time.Now().Unix() + 100,
+	}
+	authService := auth.NewService(auth.ServiceDeps{
+		UserRepository: repo,
+	})
+	_, err := authService.CompleteRegistration(context.Background(), sess, "admin")
+	if !errors.Is(err, errs.ErrUsernameTaken) {
+		t.Fatalf("expected ErrUsernameTaken, got %v:", err)
+	}
+}
+
 func TestCompleteRegistrationSuccess(t *testing.T) {
 	repo := &MockUserRepository{}
 	code := "admin"
@@ -65,7 +173,7 @@ func TestCompleteRegistrationSuccess(t *testing.T) {
 	authService := auth.NewService(auth.ServiceDeps{
 		UserRepository: repo,
 	})
-	if _, err := authService.CompleteRegistration(sess, code); err != nil {
+	if _, err := authService.CompleteRegistration(context.Background(), sess, code); err != nil {
 		t.Fatalf("expected success, got error: %v", err)
 	}
 }
@@ -88,7 +196,7 @@ func TestLoginSuccess(t *testing.T) {
 	authService := auth.NewService(auth.ServiceDeps{
 		UserRepository: repo,
 	})
-	if _, _, err := authService.Login(form); err != nil {
+	if _, _, err := authService.Login(context.Background(), form); err != nil {
 		t.Fatalf("expected success, got error: %v", err)
 	}
 }
@@ -111,7 +219,7 @@ func TestLoginWrongPassword(t *testing.T) {
 	authService := auth.NewService(auth.ServiceDeps{
 		UserRepository: repo,
 	})
-	_, _, err := authService.Login(form)
+	_, _, err := authService.Login(context.Background(), form)
 
 	if err == nil {
 		t.Fatalf("expected error, got nil")
@@ -120,3 +228,50 @@ func TestLoginWrongPassword(t *testing.T) {
 		t.Fatalf("expected ErrIncorrectLogin, got %v:", err)
 	}
 }
+
+func TestRegistrationCodeExpires(t *testing.T) {
+	repo := &MockUserRepository{
+		MockFindByEmail: func(email string) (*user.User, error) {
+			return nil, nil
+		},
+	}
+	form := auth.RegisterForm{
+		Email:           "testReg@gmail.com",
+		UserName:        "testUsername",
+		Password:        "testPass",
+		PasswordConfirm: "testPass",
+	}
+	clk := clock.NewFake(time.Unix(1000, 0))
+	authService := auth.NewService(auth.ServiceDeps{
+		UserRepository: repo,
+		EmailService:   &MockEmailService{},
+		Clock:          clk,
+	})
+	sess, err := authService.StartRegistration(context.Background(), form, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+
+	clk.Advance(10*time.Minute + time.Second)
+	_, err = authService.CompleteRegistration(context.Background(), sess, sess.Code)
+	if !errors.Is(err, errs.ErrExpireSession) {
+		t.Fatalf("expected ErrExpireSession, got %v:", err)
+	}
+}
+
+func TestTrackRegistration(t *testing.T) {
+	registrations := &MockRegistrationRepository{Started: map[string]int64{}}
+	authService := auth.NewService(auth.ServiceDeps{
+		UserRepository: &MockUserRepository{},
+		Registrations:  registrations,
+		Clock:          clock.NewFake(time.Unix(1000, 0)),
+	})
+	authService.TrackRegistration(context.Background(), "sessionID")
+	if registrations.Started["sessionID"] != 1000 {
+		t.Fatalf("expected registration started at 1000, got %v", registrations.Started)
+	}
+	authService.ForgetRegistration(context.Background(), "sessionID")
+	if len(registrations.Started) != 0 {
+		t.Fatalf("expected registration to be forgotten, got %v", registrations.Started)
+	}
+}
@@ -0,0 +1,257 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"miners_game/config"
+	"miners_game/internal/chat/message"
+	"miners_game/pkg/errs"
+	"miners_game/pkg/middleware"
+	"miners_game/pkg/tadapter"
+	"miners_game/views/components"
+	"miners_game/views/widgets"
+	"strconv"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gookit/validate"
+	"github.com/rs/zerolog"
+)
+
+const (
+	writeWait = 10 * time.Second
+)
+
+type Handler struct {
+	router      fiber.Router
+	chatService *Service
+	adminConfig *config.AdminConfig
+}
+
+type HandlerDeps struct {
+	Router      fiber.Router
+	ChatService *Service
+	AdminConfig *config.AdminConfig
+}
+
+func NewHandler(deps HandlerDeps) {
+	h := &Handler{
+		router:      deps.Router,
+		chatService: deps.ChatService,
+		adminConfig: deps.AdminConfig,
+	}
+	chat := h.router.Group("/chat")
+	chat.Use("/ws", h.upgrade)
+	chat.Get("/ws", websocket.New(h.ws))
+	chat.Post("/guild", h.joinGuild)
+	chat.Post("/guild/leave", h.leaveGuild)
+
+	admin := chat.Group("/admin")
+	admin.Use(middleware.AdminMiddleware(h.adminConfig.UserIDs))
+	admin.Post("/sanction", h.sanction)
+	admin.Post("/lift", h.lift)
+}
+
+func (h *Handler) upgrade(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	return c.Next()
+}
+
+func (h *Handler) ws(conn *websocket.Conn) {
+	logger := conn.Locals("logger").(zerolog.Logger)
+	userID := conn.Locals("user_id").(string)
+	userName := conn.Locals("username").(string)
+	// контекст соединения: запросы чата отменяются, когда клиент ушёл
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := h.chatService.Join(ctx, userID, userName)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to join chat")
+		h.write(conn, widgets.ChatNotice(err.Error()))
+		return
+	}
+
+	history, err := h.chatService.History(ctx, client)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to load chat history")
+	}
+	h.write(conn, widgets.ChatMessages(ChannelGlobal, "innerHTML", filterChannel(history, ChannelGlobal)))
+	h.write(conn, widgets.ChatMessages(ChannelGuild, "innerHTML", filterChannel(history, ChannelGuild)))
+
+	notices := make(chan string, 1)
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		h.writeLoop(conn, client, notices, logger)
+	}()
+	// после возврата contrib/websocket отдаёт conn в пул: writeLoop
+	// останавливается через Leave, и handler ждёт его выхода
+	defer func() {
+		h.chatService.Leave(client)
+		<-written
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				logger.Warn().Err(err).Msg("chat connection closed")
+			}
+			return
+		}
+		var in IncomingMessage
+		if err := json.Unmarshal(data, &in); err != nil {
+			logger.Warn().Err(err).Msg("failed to decode chat message")
+			continue
+		}
+		if err := h.chatService.Post(ctx, client, in.Channel, in.Text); err != nil {
+			select {
+			case notices <- err.Error():
+			default:
+			}
+		}
+	}
+}
+
+// writeLoop - единственный писатель в соединение, websocket не допускает параллельную запись
+func (h *Handler) writeLoop(conn *websocket.Conn, client *Client, notices <-chan string, logger zerolog.Logger) {
+	for {
+		var component templ.Component
+		select {
+		case <-client.Done():
+			closeCode, reason := websocket.CloseNormalClosure, ""
+			if err := client.Err(); err != nil {
+				closeCode, reason = websocket.ClosePolicyViolation, err.Error()
+			}
+			conn.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(closeCode, reason),
+				time.Now().Add(writeWait),
+			)
+			conn.Close()
+			return
+		case msg := <-client.Send:
+			component = widgets.ChatMessages(msg.Channel, "beforeend", []message.Message{msg})
+		case notice := <-notices:
+			component = widgets.ChatNotice(notice)
+		}
+		if err := h.write(conn, component); err != nil {
+			logger.Warn().Err(err).Msg("failed to write chat message")
+			conn.Close()
+			return
+		}
+	}
+}
+
+func (h *Handler) write(conn *websocket.Conn, component templ.Component) error {
+	var buf bytes.Buffer
+	if err := component.Render(context.Background(), &buf); err != nil {
+		return err
+	}
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteMessage(websocket.TextMessage, buf.Bytes())
+}
+
+// joinGuild - ответ обновляет канал гильдии и подсказку чата через oob-вставки
+func (h *Handler) joinGuild(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	userID, _ := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	form := GuildForm{GuildID: c.FormValue("guild_id")}
+	history, err := h.chatService.JoinGuild(c.UserContext(), userID, form.GuildID)
+	if err != nil {
+		if !errors.Is(err, errs.ErrChatGuildName) {
+			logger.Error().Err(err).Msg("failed join guild service")
+		}
+		return tadapter.Render(c, widgets.ChatNotice(err.Error()), fiber.StatusOK)
+	}
+	return tadapter.Render(c, templ.Join(
+		widgets.ChatMessages(ChannelGuild, "innerHTML", history),
+		widgets.ChatNotice("Вы вступили в гильдию"),
+	), fiber.StatusOK)
+}
+
+func (h *Handler) leaveGuild(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	userID, _ := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	if err := h.chatService.LeaveGuild(c.UserContext(), userID); err != nil {
+		logger.Error().Err(err).Msg("failed leave guild service")
+		return tadapter.Render(c, widgets.ChatNotice(err.Error()), fiber.StatusOK)
+	}
+	return tadapter.Render(c, templ.Join(
+		widgets.ChatMessages(ChannelGuild, "innerHTML", []message.Message{}),
+		widgets.ChatNotice("Вы покинули гильдию"),
+	), fiber.StatusOK)
+}
+
+func (h *Handler) sanction(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	adminID := c.Locals("user_id").(string)
+
+	minutes, _ := strconv.Atoi(c.FormValue("minutes"))
+	form := SanctionForm{
+		UserID:  c.FormValue("user_id"),
+		Kind:    c.FormValue("kind"),
+		Minutes: minutes,
+		Reason:  c.FormValue("reason"),
+	}
+	v := validate.Struct(&form)
+	if !v.Validate() {
+		component := components.Notification(v.Errors.One(), components.NotificationFail)
+		return tadapter.Render(c, component, fiber.StatusBadRequest)
+	}
+	sanction := Sanction{
+		UserID:    form.UserID,
+		Kind:      form.Kind,
+		Reason:    form.Reason,
+		CreatedBy: adminID,
+	}
+	if form.Minutes > 0 {
+		sanction.ExpiresAt = time.Now().Add(time.Duration(form.Minutes) * time.Minute).Unix()
+	}
+	if err := h.chatService.Sanction(c.UserContext(), sanction); err != nil {
+		logger.Error().Err(err).Msg("failed sanction service")
+		component := components.Notification(err.Error(), components.NotificationFail)
+		return tadapter.Render(c, component, fiber.StatusInternalServerError)
+	}
+	component := components.Notification("Ограничение применено", components.NotificationSuccess)
+	return tadapter.Render(c, component, fiber.StatusOK)
+}
+
+func (h *Handler) lift(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+
+	if err := h.chatService.Lift(c.UserContext(), c.FormValue("user_id"), c.FormValue("kind")); err != nil {
+		logger.Error().Err(err).Msg("failed lift service")
+		component := components.Notification(err.Error(), components.NotificationFail)
+		return tadapter.Render(c, component, fiber.StatusInternalServerError)
+	}
+	component := components.Notification("Ограничение снято", components.NotificationSuccess)
+	return tadapter.Render(c, component, fiber.StatusOK)
+}
+
+func filterChannel(messages []message.Message, channel string) []message.Message {
+	filtered := []message.Message{}
+	for _, v := range messages {
+		if v.Channel == channel {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,17 @@
+package chat
+
+import (
+	"context"
+	"miners_game/internal/chat/message"
+)
+
+type IChatRepository interface {
+	SaveMessage(ctx context.Context, msg *message.Message) error
+	History(ctx context.Context, channel, guildID string, limit int) ([]message.Message, error)
+	FindGuildID(ctx context.Context, userID string) (string, error)
+	SaveGuildMember(ctx context.Context, userID, guildID string) error
+	DeleteGuildMember(ctx context.Context, userID string) error
+	SaveSanction(ctx context.Context, sanction *Sanction) error
+	DeleteSanction(ctx context.Context, userID, kind string) error
+	FindSanctions(ctx context.Context, userID string) ([]Sanction, error)
+}
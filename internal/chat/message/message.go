@@ -0,0 +1,11 @@
+package message
+
+type Message struct {
+	ID        int64
+	Channel   string
+	GuildID   string
+	UserID    string
+	Username  string
+	Text      string
+	CreatedAt int64
+}
@@ -0,0 +1,29 @@
+package chat
+
+import "github.com/prometheus/client_golang/prometheus"
+
+type Metrics struct {
+	MessagesTotal         prometheus.Counter
+	MessagesRejectedTotal prometheus.Counter
+	ConnectionsActive     prometheus.Gauge
+}
+
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		MessagesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "chat_messages_total",
+			Help: "Total chat messages",
+		}),
+		MessagesRejectedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "chat_messages_rejected_total",
+			Help: "Total rejected chat messages",
+		}),
+		ConnectionsActive: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "chat_connections_active",
+			Help: "Active chat connections",
+		}),
+	}
+	reg.MustRegister(m.MessagesTotal, m.MessagesRejectedTotal, m.ConnectionsActive)
+
+	return m
+}
@@ -0,0 +1,56 @@
+package chat
+
+import "miners_game/internal/chat/message"
+
+const (
+	ChannelGlobal = "global"
+	ChannelGuild  = "guild"
+)
+
+const (
+	SanctionMute = "mute"
+	SanctionBan  = "ban"
+)
+
+type Sanction struct {
+	UserID    string
+	Kind      string
+	Reason    string
+	CreatedBy string
+	ExpiresAt int64
+}
+
+func (s *Sanction) IsActive(now int64) bool {
+	return s.ExpiresAt == 0 || s.ExpiresAt > now
+}
+
+// Client - подключение к чату. GuildID меняется при вступлении в гильдию
+// и читается только под Service.mu
+type Client struct {
+	UserID   string
+	Username string
+	GuildID  string
+	Send     chan message.Message
+
+	done chan struct{}
+	err  error
+}
+
+func newClient(userID, username, guildID string) *Client {
+	return &Client{
+		UserID:   userID,
+		Username: username,
+		GuildID:  guildID,
+		Send:     make(chan message.Message, 32),
+		done:     make(chan struct{}),
+	}
+}
+
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err - причина отключения после закрытия Done, nil - клиент ушёл сам
+func (c *Client) Err() error {
+	return c.err
+}
@@ -0,0 +1,17 @@
+package chat
+
+type IncomingMessage struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
+type GuildForm struct {
+	GuildID string `json:"guild_id"`
+}
+
+type SanctionForm struct {
+	UserID  string `json:"user_id" validate:"required"`
+	Kind    string `json:"kind" validate:"required|in:mute,ban"`
+	Minutes int    `json:"minutes" validate:"min:0"`
+	Reason  string `json:"reason"`
+}
@@ -0,0 +1,226 @@
+package chat
+
+import (
+	"context"
+	"miners_game/internal/chat/message"
+	"miners_game/pkg/database"
+	"miners_game/pkg/errs"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+type Repository struct {
+	dbPool  *pgxpool.Pool
+	timeout time.Duration
+	logger  zerolog.Logger
+}
+
+type RepositoryDeps struct {
+	DbPool  *pgxpool.Pool
+	Timeout time.Duration
+	Logger  zerolog.Logger
+}
+
+func NewRepository(deps RepositoryDeps) *Repository {
+	return &Repository{
+		dbPool:  deps.DbPool,
+		timeout: deps.Timeout,
+		logger:  deps.Logger,
+	}
+}
+
+func (r *Repository) SaveMessage(ctx context.Context, msg *message.Message) error {
+	query := `
+		INSERT INTO chat_messages (channel, guild_id, user_id, username, text, created_at)
+		VALUES (@channel, @guild_id, @user_id, @username, @text, @created_at)
+		RETURNING id
+	`
+	args := pgx.NamedArgs{
+		"channel":    msg.Channel,
+		"guild_id":   msg.GuildID,
+		"user_id":    msg.UserID,
+		"username":   msg.Username,
+		"text":       msg.Text,
+		"created_at": msg.CreatedAt,
+	}
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if err := r.dbPool.QueryRow(ctx, query, args).Scan(&msg.ID); err != nil {
+		r.logger.Error().Err(err).Str("user_id", msg.UserID).Msg("failed to save chat message")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *Repository) History(ctx context.Context, channel, guildID string, limit int) ([]message.Message, error) {
+	query := `
+		SELECT id, user_id, username, text, created_at
+		FROM chat_messages
+		WHERE channel = @channel AND guild_id = @guild_id
+		ORDER BY id DESC
+		LIMIT @limit
+	`
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	rows, err := r.dbPool.Query(ctx, query, pgx.NamedArgs{
+		"channel":  channel,
+		"guild_id": guildID,
+		"limit":    limit,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("channel", channel).Msg("failed to load chat history")
+		return nil, errs.ErrServer
+	}
+	defer rows.Close()
+
+	messages := []message.Message{}
+	for rows.Next() {
+		msg := message.Message{
+			Channel: channel,
+			GuildID: guildID,
+		}
+		if err := rows.Scan(&msg.ID, &msg.UserID, &msg.Username, &msg.Text, &msg.CreatedAt); err != nil {
+			r.logger.Error().Err(err).Str("channel", channel).Msg("failed to scan chat message")
+			return nil, errs.ErrServer
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Str("channel", channel).Msg("failed to load chat history")
+		return nil, errs.ErrServer
+	}
+	// в базе идём от новых к старым, в чат отдаём по порядку
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+func (r *Repository) FindGuildID(ctx context.Context, userID string) (string, error) {
+	query := `
+		SELECT guild_id
+		FROM guild_members
+		WHERE user_id = @user_id
+	`
+	var guildID string
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	err := r.dbPool.QueryRow(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+	}).Scan(&guildID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to find guild")
+		return "", errs.ErrServer
+	}
+	return guildID, nil
+}
+
+// SaveGuildMember - пользователь состоит в одной гильдии, вступление в новую
+// заменяет прежнюю
+func (r *Repository) SaveGuildMember(ctx context.Context, userID, guildID string) error {
+	query := `
+		INSERT INTO guild_members (user_id, guild_id)
+		VALUES (@user_id, @guild_id)
+		ON CONFLICT (user_id) DO UPDATE SET guild_id = EXCLUDED.guild_id
+	`
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"user_id":  userID,
+		"guild_id": guildID,
+	}); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to save guild member")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *Repository) DeleteGuildMember(ctx context.Context, userID string) error {
+	query := `DELETE FROM guild_members WHERE user_id = @user_id`
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+	}); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to delete guild member")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *Repository) SaveSanction(ctx context.Context, sanction *Sanction) error {
+	query := `
+		INSERT INTO chat_sanctions (user_id, kind, reason, created_by, expires_at)
+		VALUES (@user_id, @kind, @reason, @created_by, @expires_at)
+		ON CONFLICT (user_id, kind) DO UPDATE SET reason = EXCLUDED.reason, created_by = EXCLUDED.created_by, expires_at = EXCLUDED.expires_at`
+	args := pgx.NamedArgs{
+		"user_id":    sanction.UserID,
+		"kind":       sanction.Kind,
+		"reason":     sanction.Reason,
+		"created_by": sanction.CreatedBy,
+		"expires_at": sanction.ExpiresAt,
+	}
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.dbPool.Exec(ctx, query, args); err != nil {
+		r.logger.Error().Err(err).Str("user_id", sanction.UserID).Msg("failed to save chat sanction")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *Repository) DeleteSanction(ctx context.Context, userID, kind string) error {
+	query := `
+		DELETE FROM chat_sanctions
+		WHERE user_id = @user_id AND kind = @kind
+	`
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+		"kind":    kind,
+	}); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to delete chat sanction")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *Repository) FindSanctions(ctx context.Context, userID string) ([]Sanction, error) {
+	query := `
+		SELECT kind, reason, created_by, expires_at
+		FROM chat_sanctions
+		WHERE user_id = @user_id
+	`
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	rows, err := r.dbPool.Query(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to find chat sanctions")
+		return nil, errs.ErrServer
+	}
+	defer rows.Close()
+
+	sanctions := []Sanction{}
+	for rows.Next() {
+		s := Sanction{UserID: userID}
+		if err := rows.Scan(&s.Kind, &s.Reason, &s.CreatedBy, &s.ExpiresAt); err != nil {
+			r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to scan chat sanction")
+			return nil, errs.ErrServer
+		}
+		sanctions = append(sanctions, s)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to find chat sanctions")
+		return nil, errs.ErrServer
+	}
+	return sanctions, nil
+}
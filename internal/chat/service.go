@@ -0,0 +1,298 @@
+package chat
+
+import (
+	"context"
+	"miners_game/config"
+	"miners_game/internal/chat/message"
+	"miners_game/pkg/errs"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+const maxGuildNameLength = 32
+
+type Service struct {
+	repo    IChatRepository
+	config  *config.ChatConfig
+	metrics *Metrics
+	logger  zerolog.Logger
+
+	clients map[*Client]struct{}
+	posts   map[string][]int64
+	sweptAt int64
+	mu      sync.RWMutex
+}
+
+type ServiceDeps struct {
+	Repo    IChatRepository
+	Config  *config.ChatConfig
+	Metrics *Metrics
+	Logger  zerolog.Logger
+}
+
+func NewService(deps ServiceDeps) *Service {
+	return &Service{
+		repo:    deps.Repo,
+		config:  deps.Config,
+		metrics: deps.Metrics,
+		logger:  deps.Logger,
+		clients: make(map[*Client]struct{}),
+		posts:   make(map[string][]int64),
+	}
+}
+
+func (s *Service) Join(ctx context.Context, userID, username string) (*Client, error) {
+	sanction, err := s.activeSanction(ctx, userID, SanctionBan)
+	if err != nil {
+		return nil, err
+	}
+	if sanction != nil {
+		return nil, errs.ErrChatBanned
+	}
+	guildID, err := s.repo.FindGuildID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	client := newClient(userID, username, guildID)
+
+	s.mu.Lock()
+	s.clients[client] = struct{}{}
+	s.mu.Unlock()
+
+	if s.metrics != nil {
+		s.metrics.ConnectionsActive.Inc()
+	}
+	s.logger.Debug().Str("user_id", userID).Msg("chat client joined")
+	return client, nil
+}
+
+func (s *Service) Leave(client *Client) {
+	s.mu.Lock()
+	_, ok := s.clients[client]
+	if ok {
+		delete(s.clients, client)
+		close(client.done)
+	}
+	s.mu.Unlock()
+
+	if ok && s.metrics != nil {
+		s.metrics.ConnectionsActive.Dec()
+	}
+	s.logger.Debug().Str("user_id", client.UserID).Msg("chat client left")
+}
+
+func (s *Service) History(ctx context.Context, client *Client) ([]message.Message, error) {
+	messages, err := s.repo.History(ctx, ChannelGlobal, "", s.config.HistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	guildID := s.guildOf(client)
+	if guildID == "" {
+		return messages, nil
+	}
+	guild, err := s.repo.History(ctx, ChannelGuild, guildID, s.config.HistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	return append(messages, guild...), nil
+}
+
+func (s *Service) Post(ctx context.Context, client *Client, channel, text string) (err error) {
+	defer func() {
+		if s.metrics == nil {
+			return
+		}
+		if err != nil {
+			s.metrics.MessagesRejectedTotal.Inc()
+		} else {
+			s.metrics.MessagesTotal.Inc()
+		}
+	}()
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return errs.ErrChatEmptyMessage
+	}
+	if utf8.RuneCountInString(text) > s.config.MaxMessageLength {
+		return errs.ErrChatMessageTooLong
+	}
+
+	msg := message.Message{
+		Channel:   channel,
+		UserID:    client.UserID,
+		Username:  client.Username,
+		Text:      text,
+		CreatedAt: time.Now().Unix(),
+	}
+	switch channel {
+	case ChannelGlobal:
+	case ChannelGuild:
+		msg.GuildID = s.guildOf(client)
+		if msg.GuildID == "" {
+			return errs.ErrChatNoGuild
+		}
+	default:
+		return errs.ErrChatUnknownChannel
+	}
+
+	sanction, err := s.activeSanction(ctx, client.UserID, SanctionMute)
+	if err != nil {
+		return err
+	}
+	if sanction != nil {
+		return errs.ErrChatMuted
+	}
+	if !s.allow(client.UserID, msg.CreatedAt) {
+		s.logger.Warn().Str("user_id", client.UserID).Msg("chat rate limit exceeded")
+		return errs.ErrChatRateLimit
+	}
+
+	if err := s.repo.SaveMessage(ctx, &msg); err != nil {
+		return err
+	}
+	s.broadcast(msg)
+	return nil
+}
+
+// JoinGuild - вступление в гильдию по названию, прежняя гильдия покидается.
+// Открытые чаты пользователя сразу переходят в канал новой гильдии,
+// возвращается её история
+func (s *Service) JoinGuild(ctx context.Context, userID, guildID string) ([]message.Message, error) {
+	guildID = strings.TrimSpace(guildID)
+	if guildID == "" || utf8.RuneCountInString(guildID) > maxGuildNameLength {
+		return nil, errs.ErrChatGuildName
+	}
+	if err := s.repo.SaveGuildMember(ctx, userID, guildID); err != nil {
+		return nil, err
+	}
+	s.setGuild(userID, guildID)
+	s.logger.Info().Str("user_id", userID).Str("guild_id", guildID).Msg("guild joined")
+	return s.repo.History(ctx, ChannelGuild, guildID, s.config.HistoryLimit)
+}
+
+func (s *Service) LeaveGuild(ctx context.Context, userID string) error {
+	if err := s.repo.DeleteGuildMember(ctx, userID); err != nil {
+		return err
+	}
+	s.setGuild(userID, "")
+	s.logger.Info().Str("user_id", userID).Msg("guild left")
+	return nil
+}
+
+func (s *Service) setGuild(userID, guildID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for client := range s.clients {
+		if client.UserID == userID {
+			client.GuildID = guildID
+		}
+	}
+}
+
+func (s *Service) guildOf(client *Client) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return client.GuildID
+}
+
+func (s *Service) Sanction(ctx context.Context, sanction Sanction) error {
+	if err := s.repo.SaveSanction(ctx, &sanction); err != nil {
+		return err
+	}
+	if sanction.Kind == SanctionBan {
+		s.kick(sanction.UserID)
+	}
+	s.logger.Info().Str("user_id", sanction.UserID).Str("kind", sanction.Kind).Str("by", sanction.CreatedBy).Msg("chat sanction applied")
+	return nil
+}
+
+func (s *Service) Lift(ctx context.Context, userID, kind string) error {
+	if err := s.repo.DeleteSanction(ctx, userID, kind); err != nil {
+		return err
+	}
+	s.logger.Info().Str("user_id", userID).Str("kind", kind).Msg("chat sanction lifted")
+	return nil
+}
+
+func (s *Service) activeSanction(ctx context.Context, userID, kind string) (*Sanction, error) {
+	sanctions, err := s.repo.FindSanctions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	for _, v := range sanctions {
+		if v.Kind == kind && v.IsActive(now) {
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+// allow - скользящее окно: не больше RateLimitCount сообщений за RateLimitWindow.
+// Раз в окно из posts удаляются все, кто за окно ничего не писал, иначе карта
+// хранила бы каждого, кто хоть раз писал в чат
+func (s *Service) allow(userID string, now int64) bool {
+	from := now - int64(s.config.RateLimitWindow.Seconds())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sweptAt <= from {
+		for id, posts := range s.posts {
+			if posts[len(posts)-1] <= from {
+				delete(s.posts, id)
+			}
+		}
+		s.sweptAt = now
+	}
+
+	recent := s.posts[userID][:0]
+	for _, at := range s.posts[userID] {
+		if at > from {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= s.config.RateLimitCount {
+		s.posts[userID] = recent
+		return false
+	}
+	s.posts[userID] = append(recent, now)
+	return true
+}
+
+func (s *Service) broadcast(msg message.Message) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for client := range s.clients {
+		if msg.Channel == ChannelGuild && client.GuildID != msg.GuildID {
+			continue
+		}
+		select {
+		case client.Send <- msg:
+		default:
+			s.logger.Warn().Str("user_id", client.UserID).Msg("chat client is too slow, message dropped")
+		}
+	}
+}
+
+func (s *Service) kick(userID string) {
+	s.mu.Lock()
+	kicked := 0
+	for client := range s.clients {
+		if client.UserID == userID {
+			delete(s.clients, client)
+			client.err = errs.ErrChatBanned
+			close(client.done)
+			kicked++
+		}
+	}
+	s.mu.Unlock()
+
+	if s.metrics != nil {
+		s.metrics.ConnectionsActive.Sub(float64(kicked))
+	}
+}
@@ -0,0 +1,11 @@
+package chat
+
+func Allow(s *Service, userID string, now int64) bool {
+	return s.allow(userID, now)
+}
+
+func TrackedPosters(s *Service) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.posts)
+}
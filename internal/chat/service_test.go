@@ -0,0 +1,230 @@
+package chat_test
+
+import (
+	"context"
+	"errors"
+	"miners_game/config"
+	"miners_game/internal/chat"
+	"miners_game/internal/chat/message"
+	"miners_game/pkg/errs"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockChatRepository struct {
+	SaveMessageCalled bool
+	Sanctions         []chat.Sanction
+	GuildID           string
+}
+
+func (m *MockChatRepository) SaveMessage(ctx context.Context, msg *message.Message) error {
+	m.SaveMessageCalled = true
+	return nil
+}
+
+func (m *MockChatRepository) History(ctx context.Context, channel, guildID string, limit int) ([]message.Message, error) {
+	return []message.Message{}, nil
+}
+
+func (m *MockChatRepository) FindGuildID(ctx context.Context, userID string) (string, error) {
+	return m.GuildID, nil
+}
+
+func (m *MockChatRepository) SaveGuildMember(ctx context.Context, userID, guildID string) error {
+	m.GuildID = guildID
+	return nil
+}
+
+func (m *MockChatRepository) DeleteGuildMember(ctx context.Context, userID string) error {
+	m.GuildID = ""
+	return nil
+}
+
+func (m *MockChatRepository) SaveSanction(ctx context.Context, sanction *chat.Sanction) error {
+	m.Sanctions = append(m.Sanctions, *sanction)
+	return nil
+}
+
+func (m *MockChatRepository) DeleteSanction(ctx context.Context, userID, kind string) error {
+	return nil
+}
+
+func (m *MockChatRepository) FindSanctions(ctx context.Context, userID string) ([]chat.Sanction, error) {
+	return m.Sanctions, nil
+}
+
+func newTestService(repo *MockChatRepository) *chat.Service {
+	return chat.NewService(chat.ServiceDeps{
+		Repo: repo,
+		Config: &config.ChatConfig{
+			MaxMessageLength: 10,
+			RateLimitCount:   2,
+			RateLimitWindow:  time.Minute,
+			HistoryLimit:     10,
+		},
+	})
+}
+
+func TestPostSuccess(t *testing.T) {
+	repo := &MockChatRepository{}
+	chatService := newTestService(repo)
+	client, err := chatService.Join(context.Background(), "testUserID", "testUsername")
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if err := chatService.Post(context.Background(), client, chat.ChannelGlobal, "привет"); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if !repo.SaveMessageCalled {
+		t.Fatalf("expected message to be saved")
+	}
+	select {
+	case msg := <-client.Send:
+		if msg.Text != "привет" {
+			t.Fatalf("expected broadcast message, got %q", msg.Text)
+		}
+	default:
+		t.Fatalf("expected message to be broadcast")
+	}
+}
+
+func TestPostMessageTooLong(t *testing.T) {
+	chatService := newTestService(&MockChatRepository{})
+	client, _ := chatService.Join(context.Background(), "testUserID", "testUsername")
+
+	err := chatService.Post(context.Background(), client, chat.ChannelGlobal, strings.Repeat("я", 11))
+	if !errors.Is(err, errs.ErrChatMessageTooLong) {
+		t.Fatalf("expected ErrChatMessageTooLong, got %v:", err)
+	}
+}
+
+func TestPostRateLimit(t *testing.T) {
+	chatService := newTestService(&MockChatRepository{})
+	client, _ := chatService.Join(context.Background(), "testUserID", "testUsername")
+
+	for i := 0; i < 2; i++ {
+		if err := chatService.Post(context.Background(), client, chat.ChannelGlobal, "msg"); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+	}
+	err := chatService.Post(context.Background(), client, chat.ChannelGlobal, "msg")
+	if !errors.Is(err, errs.ErrChatRateLimit) {
+		t.Fatalf("expected ErrChatRateLimit, got %v:", err)
+	}
+}
+
+func TestPostMuted(t *testing.T) {
+	repo := &MockChatRepository{
+		Sanctions: []chat.Sanction{
+			{UserID: "testUserID", Kind: chat.SanctionMute},
+		},
+	}
+	chatService := newTestService(repo)
+	client, _ := chatService.Join(context.Background(), "testUserID", "testUsername")
+
+	err := chatService.Post(context.Background(), client, chat.ChannelGlobal, "msg")
+	if !errors.Is(err, errs.ErrChatMuted) {
+		t.Fatalf("expected ErrChatMuted, got %v:", err)
+	}
+}
+
+func TestPostGuildWithoutGuild(t *testing.T) {
+	chatService := newTestService(&MockChatRepository{})
+	client, _ := chatService.Join(context.Background(), "testUserID", "testUsername")
+
+	err := chatService.Post(context.Background(), client, chat.ChannelGuild, "msg")
+	if !errors.Is(err, errs.ErrChatNoGuild) {
+		t.Fatalf("expected ErrChatNoGuild, got %v:", err)
+	}
+}
+
+func TestJoinGuildOpensGuildChannel(t *testing.T) {
+	chatService := newTestService(&MockChatRepository{})
+	client, _ := chatService.Join(context.Background(), "testUserID", "testUsername")
+
+	if _, err := chatService.JoinGuild(context.Background(), "testUserID", " Шахтёры "); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if err := chatService.Post(context.Background(), client, chat.ChannelGuild, "msg"); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	select {
+	case msg := <-client.Send:
+		if msg.GuildID != "Шахтёры" {
+			t.Fatalf("expected guild message, got %q", msg.GuildID)
+		}
+	default:
+		t.Fatalf("expected message to be broadcast")
+	}
+}
+
+func TestLeaveGuildClosesGuildChannel(t *testing.T) {
+	chatService := newTestService(&MockChatRepository{GuildID: "testGuildID"})
+	client, _ := chatService.Join(context.Background(), "testUserID", "testUsername")
+
+	if err := chatService.LeaveGuild(context.Background(), "testUserID"); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	err := chatService.Post(context.Background(), client, chat.ChannelGuild, "msg")
+	if !errors.Is(err, errs.ErrChatNoGuild) {
+		t.Fatalf("expected ErrChatNoGuild, got %v:", err)
+	}
+}
+
+func TestJoinGuildInvalidName(t *testing.T) {
+	chatService := newTestService(&MockChatRepository{})
+
+	_, err := chatService.JoinGuild(context.Background(), "testUserID", "   ")
+	if !errors.Is(err, errs.ErrChatGuildName) {
+		t.Fatalf("expected ErrChatGuildName, got %v:", err)
+	}
+}
+
+func TestBanKicksClient(t *testing.T) {
+	repo := &MockChatRepository{}
+	chatService := newTestService(repo)
+	client, _ := chatService.Join(context.Background(), "testUserID", "testUsername")
+
+	if err := chatService.Sanction(context.Background(), chat.Sanction{UserID: "testUserID", Kind: chat.SanctionBan}); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	select {
+	case <-client.Done():
+	default:
+		t.Fatalf("expected banned client to be kicked")
+	}
+	if !errors.Is(client.Err(), errs.ErrChatBanned) {
+		t.Fatalf("expected ban reason, got %v:", client.Err())
+	}
+	if _, err := chatService.Join(context.Background(), "testUserID", "testUsername"); !errors.Is(err, errs.ErrChatBanned) {
+		t.Fatalf("expected ErrChatBanned, got %v:", err)
+	}
+}
+
+func TestLeaveHasNoReason(t *testing.T) {
+	chatService := newTestService(&MockChatRepository{})
+	client, _ := chatService.Join(context.Background(), "testUserID", "testUsername")
+
+	chatService.Leave(client)
+	select {
+	case <-client.Done():
+	default:
+		t.Fatalf("expected client to be closed")
+	}
+	if client.Err() != nil {
+		t.Fatalf("expected no reason, got %v:", client.Err())
+	}
+}
+
+func TestRateLimitForgetsIdleUsers(t *testing.T) {
+	chatService := newTestService(&MockChatRepository{})
+	chat.Allow(chatService, "idleUserID", 100)
+	chat.Allow(chatService, "activeUserID", 100)
+	if !chat.Allow(chatService, "activeUserID", 161) {
+		t.Fatalf("expected post after window to be allowed")
+	}
+	if tracked := chat.TrackedPosters(chatService); tracked != 1 {
+		t.Fatalf("expected only active user to be tracked, got %d", tracked)
+	}
+}
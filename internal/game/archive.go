@@ -0,0 +1,27 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"miners_game/internal/game/domain"
+	"miners_game/pkg/errs"
+)
+
+// loadArchived - игры нет в базе: если аккаунт ушёл в архив, он возвращается
+// целиком и игра загружается снова. Пока архив не удалось вернуть, новая игра
+// поверх архивной не создаётся
+func (s *Service) loadArchived(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
+	if s.archive == nil {
+		return nil, errs.ErrGameNotFound
+	}
+	restored, restoreErr := s.archive.Restore(ctx, userID)
+	if restored == 0 && restoreErr == nil {
+		return nil, errs.ErrGameNotFound
+	}
+	game, err := s.repo.Load(ctx, userID, gameID)
+	if errors.Is(err, errs.ErrGameNotFound) && restoreErr != nil {
+		s.logger.Error().Err(restoreErr).Str("user_id", userID).Str("game_id", gameID).Msg("failed to restore archived games")
+		return nil, restoreErr
+	}
+	return game, err
+}
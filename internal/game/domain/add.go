@@ -8,10 +8,17 @@ import (
 	"github.com/google/uuid"
 )
 
-func (g *GameState) AddMiner(class string) {
+func (g *GameState) AddMiner(class string, now int64) {
 	g.Mu.Lock()
 	defer g.Mu.Unlock()
-	g.Miners[uuid.New().String()] = miners.NewMiner(class)
+	g.Miners[uuid.New().String()] = miners.NewMiner(class, now)
+	g.Revision++
+}
+
+func (g *GameState) AddBalance(amount int64, reason, item string, now int64) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	g.applyBalance(amount, reason, item, now)
 }
 
 func (g *GameState) AddEquipment(name string) {
@@ -20,6 +27,7 @@ func (g *GameState) AddEquipment(name string) {
 	for k := range g.Equipments {
 		if g.Equipments[k].Name == name {
 			g.Equipments[k].Own = true
+			g.Revision++
 			return
 		}
 	}
@@ -31,6 +39,7 @@ func (g *GameState) AddUpgrade(name string) {
 	for k := range g.Upgrades {
 		if g.Upgrades[k].Name == name {
 			g.Upgrades[k].Own = true
+			g.Revision++
 			return
 		}
 	}
@@ -0,0 +1,60 @@
+package domain
+
+import "slices"
+
+// Advance - ленивый режим: состояние доводится до now так же, как если бы
+// Tick вызывался каждую секунду. false - состояние уже не старше now
+func (g *GameState) Advance(now int64) bool {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+
+	if now <= g.LastUpdateAt {
+		return false
+	}
+	income := g.incomeBySeconds(g.LastUpdateAt, now)
+	g.IncomePerSec = g.CalcIncome(now-1, now)
+	g.Balance += income
+	g.pendingIncome += income
+	g.LastUpdateAt = now
+	g.deleteExpiredMiners(now)
+	g.Revision++
+	return true
+}
+
+// NextExpiry - ближайшее окончание работы майнера после LastUpdateAt, 0 - майнеров нет
+func (g *GameState) NextExpiry() int64 {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+
+	var next int64
+	for _, miner := range g.Miners {
+		if miner.EndAt > g.LastUpdateAt && (next == 0 || miner.EndAt < next) {
+			next = miner.EndAt
+		}
+	}
+	return next
+}
+
+// incomeBySeconds - вызывается под блокировкой. CalcIncome округляет каждый
+// вызов, поэтому доход за интервал - сумма посекундных округлений. Между
+// стартами и окончаниями майнеров доход за секунду постоянный, и интервал
+// считается по отрезкам, а не по секундам
+func (g *GameState) incomeBySeconds(from, to int64) int64 {
+	bounds := []int64{from, to}
+	for _, miner := range g.Miners {
+		for _, t := range []int64{miner.StartAt, miner.EndAt} {
+			if t > from && t < to {
+				bounds = append(bounds, t)
+			}
+		}
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	var total int64
+	for i := 0; i+1 < len(bounds); i++ {
+		start, end := bounds[i], bounds[i+1]
+		total += g.CalcIncome(start, start+1) * (end - start)
+	}
+	return total
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"miners_game/internal/miners"
+	"slices"
+)
+
+// Clone - копия состояния для чтения без блокировки оригинала
+func (g *GameState) Clone() *GameState {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+
+	minersCopy := make(map[string]*miners.Miner, len(g.Miners))
+	for k, v := range g.Miners {
+		miner := *v
+		minersCopy[k] = &miner
+	}
+	return &GameState{
+		UserID:       g.UserID,
+		GameID:       g.GameID,
+		Name:         g.Name,
+		CreatedAt:    g.CreatedAt,
+		Balance:      g.Balance,
+		IncomePerSec: g.IncomePerSec,
+		LastUpdateAt: g.LastUpdateAt,
+		Miners:       minersCopy,
+		Equipments:   slices.Clone(g.Equipments),
+		Upgrades:     slices.Clone(g.Upgrades),
+
+		Revision:      g.Revision,
+		SavedRevision: g.SavedRevision,
+		Version:       g.Version,
+	}
+}
@@ -0,0 +1,37 @@
+package domain
+
+// IsDirty - есть изменения после последнего сохранения
+func (g *GameState) IsDirty() bool {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.Revision != g.SavedRevision
+}
+
+// MarkSaved - сохранена копия с ревизией revision и первыми ledger записями
+// журнала, в базе она получила version. Изменения, сделанные во время записи,
+// увеличили Revision и оставят игру грязной
+func (g *GameState) MarkSaved(revision uint64, version int64, ledger int) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	g.Version = version
+	g.Ledger = g.Ledger[min(ledger, len(g.Ledger)):]
+	if revision > g.SavedRevision {
+		g.SavedRevision = revision
+	}
+}
+
+// MarkJournaled - состояние с ревизией revision надёжно записано в локальный журнал
+func (g *GameState) MarkJournaled(revision uint64) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if revision > g.JournaledRevision {
+		g.JournaledRevision = revision
+	}
+}
+
+// JournalPending - в журнале есть изменения, которых ещё нет в базе
+func (g *GameState) JournalPending() bool {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.JournaledRevision > g.SavedRevision
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"miners_game/internal/game/equipments"
+	"miners_game/internal/game/upgrades"
+)
+
+// IncomeBreakdown - из чего складывается доход в секунду
+type IncomeBreakdown struct {
+	Passive        int64
+	Miners         map[string]int64
+	Base           int64
+	EquipmentBonus int64
+	UpgradeBonus   int64
+	Total          int64
+}
+
+// Breakdown - доход за секунду (now-1, now] по источникам, считается как CalcIncome
+func (g *GameState) Breakdown(now int64) IncomeBreakdown {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+
+	b := IncomeBreakdown{
+		Passive: passiveIncome,
+		Miners:  make(map[string]int64),
+	}
+	b.Base = b.Passive
+	for _, v := range g.Miners {
+		income := v.CalcIncome(now-1, now)
+		b.Miners[v.Class] += income
+		b.Base += income
+	}
+	for _, v := range g.Equipments {
+		if v.Own {
+			b.EquipmentBonus += equipments.GetEquipmentConfig(v.Name).Value
+		}
+	}
+	if currUpgrade := g.GetMaxUpgrade(); currUpgrade != "0" {
+		b.UpgradeBonus = upgrades.GetUpgradesConfig(currUpgrade).Value
+	}
+	b.Total = b.Base * (100 + b.EquipmentBonus + b.UpgradeBonus) / 100
+	return b
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"strconv"
+)
+
+// Причины движения баланса в журнале экономики
+const (
+	ReasonIncome     = "income"
+	ReasonPurchase   = "purchase"
+	ReasonReward     = "reward"
+	ReasonAdminGrant = "admin_grant"
+	ReasonOpening    = "opening"
+	ReasonImport     = "import"
+	ReasonRestore    = "restore"
+)
+
+// LedgerEntry - запись журнала: изменение баланса и баланс после него
+type LedgerEntry struct {
+	Amount       int64
+	Reason       string
+	Item         string
+	BalanceAfter int64
+	At           int64
+}
+
+// Reward - награда, начисляемая в игру извне. Item записи журнала связывает
+// начисление со строкой награды, по нему она не начисляется второй раз
+type Reward struct {
+	ID     int64
+	Amount int64
+}
+
+func (r Reward) Item() string {
+	return "referral:" + strconv.FormatInt(r.ID, 10)
+}
+
+// HasLedgerItem - есть ли item среди ещё не сохранённых записей журнала
+func (g *GameState) HasLedgerItem(item string) bool {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	for _, entry := range g.Ledger {
+		if entry.Item == item {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordLedger - запись об изменении баланса, уже применённом снаружи (откат).
+// Состояние не должно иметь несброшенного дохода тиков
+func (g *GameState) RecordLedger(amount int64, reason, item string, now int64) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	g.appendLedger(amount, reason, item, now)
+}
+
+// OpenLedger - новая игра начинает журнал с записи на весь текущий баланс,
+// записи игры-источника (при копировании слота) отбрасываются
+func (g *GameState) OpenLedger(reason string, now int64) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	g.Ledger = nil
+	g.pendingIncome = 0
+	if g.Balance != 0 {
+		g.appendLedger(g.Balance, reason, "", now)
+	}
+}
+
+// CloneForSave - копия для сохранения вместе с ещё не записанными строками
+// журнала. Накопленный доход тиков становится одной записью
+func (g *GameState) CloneForSave() *GameState {
+	g.Mu.Lock()
+	g.flushIncome()
+	g.Mu.Unlock()
+
+	clone := g.Clone()
+	g.Mu.RLock()
+	clone.Ledger = append([]LedgerEntry(nil), g.Ledger...)
+	g.Mu.RUnlock()
+	return clone
+}
+
+// applyBalance - вызывается под блокировкой: изменение баланса вместе с записью журнала.
+// Доход тиков записывается раньше, чтобы BalanceAfter шли по порядку
+func (g *GameState) applyBalance(amount int64, reason, item string, now int64) {
+	g.flushIncome()
+	g.Balance += amount
+	g.Revision++
+	g.appendLedger(amount, reason, item, now)
+}
+
+func (g *GameState) appendLedger(amount int64, reason, item string, now int64) {
+	g.Ledger = append(g.Ledger, LedgerEntry{
+		Amount:       amount,
+		Reason:       reason,
+		Item:         item,
+		BalanceAfter: g.Balance,
+		At:           now,
+	})
+}
+
+// flushIncome - вызывается под блокировкой. Все остальные изменения баланса
+// сначала сбрасывают доход, поэтому текущий баланс и есть баланс после него
+func (g *GameState) flushIncome() {
+	if g.pendingIncome == 0 {
+		return
+	}
+	g.Ledger = append(g.Ledger, LedgerEntry{
+		Amount:       g.pendingIncome,
+		Reason:       ReasonIncome,
+		BalanceAfter: g.Balance,
+		At:           g.LastUpdateAt,
+	})
+	g.pendingIncome = 0
+}
@@ -5,13 +5,15 @@ import (
 	"miners_game/internal/game/upgrades"
 	"miners_game/internal/miners"
 	"sync"
-	"time"
 )
 
 type GameState struct {
 	UserID string
 	GameID string
 
+	Name      string
+	CreatedAt int64
+
 	Balance      int64
 	IncomePerSec int64
 
@@ -21,18 +23,33 @@ type GameState struct {
 	Equipments []equipments.Equipment
 	Upgrades   []upgrades.Upgrade
 
+	// Revision растёт при каждом изменении, SavedRevision - ревизия последнего сохранения
+	Revision      uint64
+	SavedRevision uint64
+	// Version - версия строки в базе, по ней сохранение проверяет, что его никто не опередил
+	Version int64
+	// JournaledRevision - ревизия последней записи в локальный журнал
+	JournaledRevision uint64
+
+	// Ledger - записи журнала экономики, ещё не сохранённые в базу,
+	// pendingIncome - доход тиков, ещё не попавший в Ledger
+	Ledger        []LedgerEntry
+	pendingIncome int64
+
 	Mu sync.RWMutex
 }
 
-func NewGameState(userID, gameID string) *GameState {
+// NewGameState - новая игра, созданная в момент now
+func NewGameState(userID, gameID string, now int64) *GameState {
 	equipments := equipments.NewEquipments()
 	upgrades := upgrades.NewUpgrades()
 	return &GameState{
 		UserID:       userID,
 		GameID:       gameID,
+		CreatedAt:    now,
 		Balance:      0,
 		IncomePerSec: 1,
-		LastUpdateAt: time.Now().Unix(),
+		LastUpdateAt: now,
 		Miners:       make(map[string]*miners.Miner),
 		Equipments:   equipments,
 		Upgrades:     upgrades,
@@ -4,12 +4,13 @@ import (
 	"miners_game/pkg/errs"
 )
 
-func (g *GameState) SpendBalance(price int64) error {
+// SpendBalance - покупка предмета item
+func (g *GameState) SpendBalance(price int64, item string, now int64) error {
 	g.Mu.Lock()
 	defer g.Mu.Unlock()
 	if g.Balance < price {
 		return errs.ErrNotEnoughBalance
 	}
-	g.Balance -= price
+	g.applyBalance(-price, ReasonPurchase, item, now)
 	return nil
 }
@@ -20,8 +20,10 @@ func (g *GameState) Tick(now int64) {
 	income := g.CalcIncome(g.LastUpdateAt, now)
 	g.IncomePerSec = g.CalcIncome(now-1, now)
 	g.Balance += income
+	g.pendingIncome += income
 	g.LastUpdateAt = now
 	g.deleteExpiredMiners(now)
+	g.Revision++
 
 }
 
@@ -0,0 +1,176 @@
+// Package gametest - общий контракт хранилищ игр. Его проходят Postgres,
+// SQLite и память, чтобы локальный запуск вёл себя как прод
+package gametest
+
+import (
+	"context"
+	"errors"
+	"miners_game/internal/game"
+	"miners_game/internal/game/domain"
+	"miners_game/pkg/errs"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RunRepositoryContract - newRepo возвращает хранилище, в котором нет игр
+// пользователей, созданных тестом. Идентификаторы случайные, поэтому общая
+// база не мешает
+func RunRepositoryContract(t *testing.T, newRepo func(t *testing.T) game.IGameRepository) {
+	ctx := context.Background()
+
+	t.Run("LoadNotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.Load(ctx, uuid.NewString(), uuid.NewString()); !errors.Is(err, errs.ErrGameNotFound) {
+			t.Fatalf("expected ErrGameNotFound, got %v:", err)
+		}
+		if _, err := repo.LoadLatest(ctx, uuid.NewString()); !errors.Is(err, errs.ErrGameNotFound) {
+			t.Fatalf("expected ErrGameNotFound, got %v:", err)
+		}
+	})
+
+	t.Run("SaveLoad", func(t *testing.T) {
+		repo := newRepo(t)
+		gameState := newGame(uuid.NewString())
+		gameState.AddMiner("strong", time.Now().Unix())
+		gameState.AddBalance(5000, domain.ReasonReward, "", time.Now().Unix())
+		if err := gameState.SpendBalance(200, "equipment:1", time.Now().Unix()); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		gameState.AddEquipment("1")
+		gameState.AddUpgrade("2")
+		if err := repo.Save(ctx, gameState.CloneForSave()); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+
+		loaded, err := repo.Load(ctx, gameState.UserID, gameState.GameID)
+		if err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		if loaded.Name != gameState.Name || loaded.CreatedAt != gameState.CreatedAt || loaded.Balance != gameState.Balance || loaded.LastUpdateAt != gameState.LastUpdateAt {
+			t.Fatalf("expected %+v, got %+v", gameState, loaded)
+		}
+		if loaded.Version != 1 {
+			t.Fatalf("expected version 1, got %d", loaded.Version)
+		}
+		if len(loaded.Miners) != 1 {
+			t.Fatalf("expected one miner, got %+v", loaded.Miners)
+		}
+		for key, miner := range gameState.Miners {
+			if got := loaded.Miners[key]; got == nil || *got != *miner {
+				t.Fatalf("expected miner %+v, got %+v", miner, got)
+			}
+		}
+		if !loaded.IsOwnEquipment("1") || loaded.IsOwnEquipment("2") || !loaded.IsOwnUpgrade("2") {
+			t.Fatalf("expected owned items to survive, got %+v %+v", loaded.Equipments, loaded.Upgrades)
+		}
+		if len(loaded.Equipments) != len(gameState.Equipments) || loaded.Equipments[0].Name != gameState.Equipments[0].Name {
+			t.Fatalf("expected equipment order to survive, got %+v", loaded.Equipments)
+		}
+		if loaded.IsDirty() {
+			t.Fatalf("expected loaded game to be clean")
+		}
+	})
+
+	t.Run("SaveConflict", func(t *testing.T) {
+		repo := newRepo(t)
+		gameState := newGame(uuid.NewString())
+		if err := repo.Save(ctx, gameState); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		first, _ := repo.Load(ctx, gameState.UserID, gameState.GameID)
+		second, _ := repo.Load(ctx, gameState.UserID, gameState.GameID)
+
+		first.AddBalance(10, domain.ReasonReward, "", time.Now().Unix())
+		if err := repo.Save(ctx, first); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		if first.Version != 2 {
+			t.Fatalf("expected version 2, got %d", first.Version)
+		}
+		second.AddBalance(20, domain.ReasonReward, "", time.Now().Unix())
+		if err := repo.Save(ctx, second); !errors.Is(err, errs.ErrSaveConflict) {
+			t.Fatalf("expected ErrSaveConflict, got %v:", err)
+		}
+		loaded, _ := repo.Load(ctx, gameState.UserID, gameState.GameID)
+		if loaded.Balance != 10 {
+			t.Fatalf("expected first save to win, got balance %d", loaded.Balance)
+		}
+	})
+
+	t.Run("SaveBatch", func(t *testing.T) {
+		repo := newRepo(t)
+		userID := uuid.NewString()
+		stale := newGame(userID)
+		if err := repo.Save(ctx, stale); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		stale.Version = 0
+		fresh := newGame(userID)
+
+		results := repo.SaveBatch(ctx, []*domain.GameState{stale, fresh})
+		if !errors.Is(results[0], errs.ErrSaveConflict) || results[1] != nil {
+			t.Fatalf("expected conflict and success, got %v", results)
+		}
+		if _, err := repo.Load(ctx, userID, fresh.GameID); err != nil {
+			t.Fatalf("expected fresh game to be saved, got %v:", err)
+		}
+	})
+
+	t.Run("SlotsAndLatest", func(t *testing.T) {
+		repo := newRepo(t)
+		userID := uuid.NewString()
+		older := newGame(userID)
+		older.CreatedAt, older.LastUpdateAt = 100, 300
+		newer := newGame(userID)
+		newer.CreatedAt, newer.LastUpdateAt = 200, 250
+		for _, g := range []*domain.GameState{older, newer} {
+			if err := repo.Save(ctx, g); err != nil {
+				t.Fatalf("expected success, got %v:", err)
+			}
+		}
+
+		latest, err := repo.LoadLatest(ctx, userID)
+		if err != nil || latest.GameID != older.GameID {
+			t.Fatalf("expected last played game %s, got %+v %v", older.GameID, latest, err)
+		}
+		if err := repo.Rename(ctx, userID, newer.GameID, "Новое имя"); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		slots, err := repo.ListByUser(ctx, userID)
+		if err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		if len(slots) != 2 || slots[0].GameID != older.GameID || slots[1].Name != "Новое имя" {
+			t.Fatalf("expected slots in creation order with new name, got %+v", slots)
+		}
+		// откат пишет название вместе с остальным состоянием
+		newer.Name = "Откат"
+		if err := repo.Save(ctx, newer); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		if loaded, err := repo.Load(ctx, userID, newer.GameID); err != nil || loaded.Name != "Откат" {
+			t.Fatalf("expected saved name, got %+v %v", loaded, err)
+		}
+
+		if err := repo.Delete(ctx, userID, older.GameID); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		if err := repo.Delete(ctx, userID, older.GameID); !errors.Is(err, errs.ErrGameNotFound) {
+			t.Fatalf("expected ErrGameNotFound, got %v:", err)
+		}
+		if err := repo.Rename(ctx, userID, older.GameID, "x"); !errors.Is(err, errs.ErrGameNotFound) {
+			t.Fatalf("expected ErrGameNotFound, got %v:", err)
+		}
+		if _, err := repo.Load(ctx, userID, older.GameID); !errors.Is(err, errs.ErrGameNotFound) {
+			t.Fatalf("expected ErrGameNotFound, got %v:", err)
+		}
+	})
+}
+
+func newGame(userID string) *domain.GameState {
+	gameState := domain.NewGameState(userID, uuid.NewString(), time.Now().Unix())
+	gameState.Name = "Сохранение"
+	return gameState
+}
@@ -1,28 +1,44 @@
 package game
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"miners_game/config"
 	"miners_game/internal/game/shop"
 	"miners_game/pkg/middleware"
 	"miners_game/pkg/tadapter"
 	"miners_game/views"
 	"miners_game/views/components"
 	"miners_game/views/widgets"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
 	"github.com/rs/zerolog"
 )
 
+const (
+	// hudResyncInterval - полный снимок даже без расхождений, чтобы поправить дрейф часов клиента
+	hudResyncInterval = 15 * time.Second
+	// hudHeartbeat - пауза без записей, после которой в поток пишется комментарий.
+	// Закрытое соединение обнаруживается только на записи, поэтому без него
+	// поток ушедшего клиента висел бы до следующего снимка
+	hudHeartbeat = 3 * time.Second
+)
+
 type Handler struct {
 	router      fiber.Router
 	gameService *Service
 	store       *session.Store
+	adminIDs    []string
 }
 
 type HandlerDeps struct {
 	Router      fiber.Router
 	GameService *Service
 	Store       *session.Store
+	AdminConfig *config.AdminConfig
 }
 
 func NewHandler(deps HandlerDeps) {
@@ -30,15 +46,31 @@ func NewHandler(deps HandlerDeps) {
 		router:      deps.Router,
 		gameService: deps.GameService,
 		store:       deps.Store,
+		adminIDs:    deps.AdminConfig.UserIDs,
 	}
 	g := h.router.Group("/game")
-	g.Use(middleware.GameMiddleware(h.store))
+	g.Use(middleware.GameMiddleware(h.store, h.gameService))
 	g.Get("/", h.game)
 	g.Get("/hud", h.hud)
+	g.Get("/hud/stream", h.hudStream)
 	g.Post("/buy", h.buy)
 	g.Get("/panel/:tab", h.shopTab)
 	g.Get("/upgrade", h.refreshUpgrade)
 	g.Get("/shop/card/:kind/:name", h.shopCard)
+
+	slots := h.router.Group("/slots", h.requireUser)
+	slots.Get("/", h.slots)
+	slots.Post("/", h.createSlot)
+	slots.Post("/:id/rename", h.renameSlot)
+	slots.Post("/:id/duplicate", h.duplicateSlot)
+	slots.Post("/:id/delete", h.deleteSlot)
+	slots.Post("/:id/play", h.playSlot)
+	slots.Get("/:id/export", h.exportSlot)
+	slots.Post("/import", h.importSlot)
+
+	admin := h.router.Group("/admin/games", middleware.AdminMiddleware(h.adminIDs))
+	admin.Get("/:userID/:gameID/export", h.adminExport)
+	admin.Post("/:userID/:gameID/grant", h.adminGrant)
 }
 
 func (h *Handler) game(c *fiber.Ctx) error {
@@ -46,7 +78,7 @@ func (h *Handler) game(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 	gameID := c.Locals("game_id").(string)
 
-	if _, err := h.gameService.EnterGame(userID, gameID); err != nil {
+	if _, err := h.gameService.EnterGame(c.UserContext(), userID, gameID); err != nil {
 		logger.Error().Err(err).Msg("failed enterGame service")
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
@@ -59,7 +91,7 @@ func (h *Handler) hud(c *fiber.Ctx) error {
 	logger := c.Locals("logger").(zerolog.Logger)
 	userID := c.Locals("user_id").(string)
 	gameID := c.Locals("game_id").(string)
-	balance, income, err := h.gameService.GetHud(userID, gameID)
+	balance, income, err := h.gameService.GetHud(c.UserContext(), userID, gameID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed getHud service")
 		return c.SendStatus(fiber.StatusNoContent)
@@ -68,6 +100,68 @@ func (h *Handler) hud(c *fiber.Ctx) error {
 	return tadapter.Render(c, component, fiber.StatusOK)
 }
 
+func (h *Handler) hudStream(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	userID := c.Locals("user_id").(string)
+	gameID := c.Locals("game_id").(string)
+
+	game, ticks, cancel, err := h.gameService.SubscribeHud(c.UserContext(), userID, gameID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed subscribeHud service")
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		var last HudSnapshot
+		var lastSent, lastWrite time.Time
+		for {
+			snapshot := h.gameService.StreamHud(userID, gameID, game)
+			// клиент анимирует счётчик сам, снимок нужен только когда его прогноз разошёлся
+			if lastSent.IsZero() || !last.Predicts(snapshot) || time.Since(lastSent) >= hudResyncInterval {
+				if err := writeEvent(w, "snapshot", snapshot); err != nil {
+					return
+				}
+				last, lastSent = snapshot, time.Now()
+				lastWrite = lastSent
+			} else if time.Since(lastWrite) >= hudHeartbeat {
+				if err := writeHeartbeat(w); err != nil {
+					return
+				}
+				lastWrite = time.Now()
+			}
+			// канал закрывается, когда игра выгружена из loop: клиент переподключится
+			if _, ok := <-ticks; !ok {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func writeEvent(w *bufio.Writer, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	w.WriteString("event: " + event + "\n")
+	w.WriteString("data: ")
+	w.Write(payload)
+	w.WriteString("\n\n")
+	return w.Flush()
+}
+
+// writeHeartbeat - SSE-комментарий, клиент его игнорирует
+func writeHeartbeat(w *bufio.Writer) error {
+	w.WriteString(": keep-alive\n\n")
+	return w.Flush()
+}
+
 func (h *Handler) buy(c *fiber.Ctx) error {
 	logger := c.Locals("logger").(zerolog.Logger)
 	userID := c.Locals("user_id").(string)
@@ -76,13 +170,13 @@ func (h *Handler) buy(c *fiber.Ctx) error {
 	name := c.FormValue("name")
 	kind := c.FormValue("kind")
 
-	cases := map[string]func(string, string, string, string) (shop.ShopCard, error){
+	cases := map[string]func(context.Context, string, string, string, string) (shop.ShopCard, error){
 		"miner":     h.gameService.BuyMiner,
 		"equipment": h.gameService.BuyEquipment,
 		"upgrade":   h.gameService.BuyUpgrade,
 	}
 	if cs, ok := cases[kind]; ok {
-		if card, err := cs(userID, gameID, name, kind); err != nil {
+		if card, err := cs(c.UserContext(), userID, gameID, name, kind); err != nil {
 			component := components.ShopCard(card)
 			logger.Warn().Err(err).Msg("failed buy service")
 			return tadapter.Render(c, component, fiber.StatusOK)
@@ -0,0 +1,44 @@
+package game
+
+import (
+	"miners_game/internal/game/domain"
+	"slices"
+)
+
+// HudSnapshot - состояние для локальной анимации счётчика на клиенте.
+// Balance верен на момент At, дальше растёт на Rate в секунду до ближайшего Expiries
+type HudSnapshot struct {
+	Balance    int64   `json:"balance"`
+	Rate       int64   `json:"rate"`
+	At         int64   `json:"at"`
+	ServerTime int64   `json:"server_time"`
+	Expiries   []int64 `json:"expiries"`
+}
+
+func newHudSnapshot(game *domain.GameState, serverTime int64) HudSnapshot {
+	game.Mu.RLock()
+	defer game.Mu.RUnlock()
+
+	snapshot := HudSnapshot{
+		Balance:    game.Balance,
+		Rate:       game.IncomePerSec,
+		At:         game.LastUpdateAt,
+		ServerTime: serverTime,
+		Expiries:   make([]int64, 0, len(game.Miners)),
+	}
+	for _, miner := range game.Miners {
+		snapshot.Expiries = append(snapshot.Expiries, miner.EndAt)
+	}
+	slices.Sort(snapshot.Expiries)
+	snapshot.Expiries = slices.Compact(snapshot.Expiries)
+	return snapshot
+}
+
+// Predicts - клиент с прошлым снимком сам покажет next: доход и набор майнеров
+// не менялись, баланс вырос ровно на Rate за прошедшие секунды
+func (s HudSnapshot) Predicts(next HudSnapshot) bool {
+	if s.Rate != next.Rate || !slices.Equal(s.Expiries, next.Expiries) {
+		return false
+	}
+	return s.Balance+s.Rate*(next.At-s.At) == next.Balance
+}
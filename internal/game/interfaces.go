@@ -1,16 +1,27 @@
 package game
 
-import "miners_game/internal/game/domain"
+import (
+	"context"
+	"miners_game/internal/game/domain"
+)
 
 type IGameRepository interface {
-	Load(userID, gameID string) (*domain.GameState, error)
-	Save(gameState *domain.GameState) error
+	Load(ctx context.Context, userID, gameID string) (*domain.GameState, error)
+	LoadLatest(ctx context.Context, userID string) (*domain.GameState, error)
+	Save(ctx context.Context, gameState *domain.GameState) error
+	SaveBatch(ctx context.Context, games []*domain.GameState) []error
+	ListByUser(ctx context.Context, userID string) ([]SaveSlot, error)
+	Rename(ctx context.Context, userID, gameID, name string) error
+	Delete(ctx context.Context, userID, gameID string) error
 }
 
 type ILoopService interface {
 	Tick(now int64)
 	Register(id string, game *domain.GameState)
 	Unregister(id string)
+	Subscribe(id string) (<-chan struct{}, func())
+	Notify(id string)
+	Advance(game *domain.GameState)
 }
 
 type ISessionService interface {
@@ -18,3 +29,28 @@ type ISessionService interface {
 	IsActive(id string) bool
 	GetExpired() []string
 }
+
+type IRewardService interface {
+	OnFirstUpgrade(ctx context.Context, userID string) (string, error)
+	PendingRewards(ctx context.Context, userID, gameID string) ([]domain.Reward, error)
+}
+
+// ILeaseService - аренда игр между инстансами. Ключи Renew - userID/gameID
+type ILeaseService interface {
+	Acquire(ctx context.Context, userID, gameID string) error
+	Renew(ctx context.Context) (lost, handoffs []string, err error)
+	Release(ctx context.Context, userID, gameID string) error
+	ReleaseAll(ctx context.Context) error
+}
+
+// IJournal - локальный журнал изменений между периодическими сохранениями
+type IJournal interface {
+	Append(ctx context.Context, game *domain.GameState) error
+	Rotate() (uint64, error)
+	Truncate(upTo uint64) error
+}
+
+// IArchiveService - возврат игр неактивного аккаунта из архива, 0 - архивных игр нет
+type IArchiveService interface {
+	Restore(ctx context.Context, userID string) (int, error)
+}
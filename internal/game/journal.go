@@ -0,0 +1,56 @@
+package game
+
+import (
+	"context"
+	"miners_game/internal/game/domain"
+)
+
+// journalGame - покупки и другие разовые изменения пишутся в локальный журнал
+// сразу, чтобы падение не потеряло их до очередного SaveAll. Ошибка журнала
+// не отменяет изменение: оно сохранится со следующим SaveAll
+func (s *Service) journalGame(ctx context.Context, game *domain.GameState) {
+	if s.journal == nil {
+		return
+	}
+	if err := s.journal.Append(ctx, game); err != nil {
+		s.logger.Error().Err(err).Str("user_id", game.UserID).Str("game_id", game.GameID).Msg("failed to journal game")
+	}
+}
+
+// rotateJournal - записи после ротации идут в новый сегмент, старые
+// сегменты можно удалить, когда их игры окажутся в базе
+func (s *Service) rotateJournal() (uint64, bool) {
+	if s.journal == nil {
+		return 0, false
+	}
+	segment, err := s.journal.Rotate()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to rotate journal")
+		return 0, false
+	}
+	return segment, true
+}
+
+// compactJournal - после сохранения старые сегменты не нужны. Игры, чьи записи
+// не попали в базу (сохранение не удалось или изменение пришло во время записи),
+// сначала переписываются в текущий сегмент с актуальной версией
+func (s *Service) compactJournal(ctx context.Context, segment uint64) {
+	s.mu.RLock()
+	pending := make([]*domain.GameState, 0)
+	for _, game := range s.games {
+		if game.JournalPending() {
+			pending = append(pending, game)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, game := range pending {
+		if err := s.journal.Append(ctx, game); err != nil {
+			s.logger.Error().Err(err).Str("user_id", game.UserID).Str("game_id", game.GameID).Msg("failed to carry game over journal rotation")
+			return
+		}
+	}
+	if err := s.journal.Truncate(segment); err != nil {
+		s.logger.Error().Err(err).Msg("failed to truncate journal")
+	}
+}
@@ -0,0 +1,278 @@
+// Package journal - локальный журнал изменений игр между периодическими
+// сохранениями. Запись подтверждается после fsync, fsync выполняется пачками
+package journal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"miners_game/internal/game/domain"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const segmentExt = ".wal"
+
+var errClosed = errors.New("journal is closed")
+
+// batch - записи, которые подтвердит один fsync
+type batch struct {
+	done chan struct{}
+	err  error
+}
+
+func newBatch() *batch {
+	return &batch{done: make(chan struct{})}
+}
+
+// Journal - сегменты dir/<seq>.wal, в каждой строке Record в JSON. Пишется
+// только последний сегмент, старые удаляются после сохранения игр в базу
+type Journal struct {
+	dir      string
+	interval time.Duration
+	metrics  *Metrics
+	logger   zerolog.Logger
+
+	seq     uint64
+	file    *os.File
+	w       *bufio.Writer
+	pending *batch
+	dirty   bool
+	closed  bool
+	mu      sync.Mutex
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+type JournalDeps struct {
+	Dir          string
+	SyncInterval time.Duration
+	Metrics      *Metrics
+	Logger       zerolog.Logger
+}
+
+// NewJournal - открывает новый сегмент после уже лежащих на диске, их
+// разбирает Replay. fsync выполняется в фоне раз в SyncInterval
+func NewJournal(deps JournalDeps) (*Journal, error) {
+	if err := os.MkdirAll(deps.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	segments, err := listSegments(deps.Dir)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{
+		dir:      deps.Dir,
+		interval: deps.SyncInterval,
+		metrics:  deps.Metrics,
+		logger:   deps.Logger,
+		pending:  newBatch(),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if len(segments) > 0 {
+		j.seq = segments[len(segments)-1]
+	}
+	if err := j.openNext(); err != nil {
+		return nil, err
+	}
+	go j.run()
+	return j, nil
+}
+
+// Append - записывает состояние игры и ждёт fsync. После подтверждения игра
+// помечается записанной в журнал
+func (j *Journal) Append(ctx context.Context, game *domain.GameState) error {
+	rec := newRecord(game)
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return errClosed
+	}
+	if _, err := j.w.Write(append(line, '\n')); err != nil {
+		j.mu.Unlock()
+		j.failed()
+		return err
+	}
+	j.dirty = true
+	b := j.pending
+	j.mu.Unlock()
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if b.err != nil {
+		return b.err
+	}
+	if j.metrics != nil {
+		j.metrics.AppendsTotal.Inc()
+	}
+	game.MarkJournaled(rec.Revision)
+	return nil
+}
+
+// Rotate - начинает новый сегмент и возвращает номер последнего закрытого.
+// Всё записанное до ротации к этому моменту уже на диске
+func (j *Journal) Rotate() (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return 0, errClosed
+	}
+	if err := j.syncLocked(); err != nil {
+		return 0, err
+	}
+	last := j.seq
+	if err := j.file.Close(); err != nil {
+		return 0, err
+	}
+	if err := j.openNext(); err != nil {
+		return 0, err
+	}
+	return last, nil
+}
+
+// Truncate - удаляет сегменты до upTo включительно, текущий не трогается
+func (j *Journal) Truncate(upTo uint64) error {
+	segments, err := listSegments(j.dir)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	current := j.seq
+	j.mu.Unlock()
+	for _, seq := range segments {
+		if seq > upTo || seq >= current {
+			break
+		}
+		if err := os.Remove(j.segmentPath(seq)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close - последний fsync и закрытие сегмента, вызывается после финального SaveAll
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return nil
+	}
+	j.closed = true
+	j.mu.Unlock()
+
+	close(j.stop)
+	<-j.stopped
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.syncLocked(); err != nil {
+		return err
+	}
+	return j.file.Close()
+}
+
+func (j *Journal) run() {
+	defer close(j.stopped)
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.stop:
+			return
+		case <-ticker.C:
+			j.mu.Lock()
+			if err := j.syncLocked(); err != nil {
+				j.logger.Error().Err(err).Msg("failed to sync journal")
+			}
+			j.mu.Unlock()
+		}
+	}
+}
+
+// syncLocked - вызывается под блокировкой: fsync накопленной пачки и
+// подтверждение её записей
+func (j *Journal) syncLocked() error {
+	if !j.dirty {
+		return nil
+	}
+	start := time.Now()
+	b := j.pending
+	j.pending = newBatch()
+	j.dirty = false
+
+	err := j.w.Flush()
+	if err == nil {
+		err = j.file.Sync()
+	}
+	if err != nil {
+		j.failed()
+	}
+	if j.metrics != nil {
+		j.metrics.SyncDuration.Observe(time.Since(start).Seconds())
+	}
+	b.err = err
+	close(b.done)
+	return err
+}
+
+func (j *Journal) openNext() error {
+	j.seq++
+	file, err := os.OpenFile(j.segmentPath(j.seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	j.file = file
+	j.w = bufio.NewWriter(file)
+	return nil
+}
+
+func (j *Journal) failed() {
+	if j.metrics != nil {
+		j.metrics.FailedTotal.Inc()
+	}
+}
+
+func (j *Journal) segmentPath(seq uint64) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	segments := []uint64{}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), segmentExt)
+		if !ok || e.IsDir() {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, seq)
+	}
+	slices.Sort(segments)
+	return segments, nil
+}
@@ -0,0 +1,133 @@
+package journal_test
+
+import (
+	"context"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/game/journal"
+	"miners_game/pkg/errs"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type MockGameStore struct {
+	Saved    map[string]*domain.GameState
+	Versions map[string]int64
+}
+
+func (m *MockGameStore) Load(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
+	version, ok := m.Versions[gameID]
+	if !ok {
+		return nil, errs.ErrGameNotFound
+	}
+	game := domain.NewGameState(userID, gameID, time.Now().Unix())
+	game.Version = version
+	return game, nil
+}
+
+func (m *MockGameStore) Save(ctx context.Context, gameState *domain.GameState) error {
+	m.Saved[gameState.GameID] = gameState
+	return nil
+}
+
+func openJournal(t *testing.T, dir string) *journal.Journal {
+	j, err := journal.NewJournal(journal.JournalDeps{
+		Dir:          dir,
+		SyncInterval: time.Millisecond,
+		Logger:       zerolog.Nop(),
+	})
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	return j
+}
+
+func TestReplayRestoresUnsavedPurchase(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	j := openJournal(t, dir)
+
+	bought := domain.NewGameState("testUserID", "boughtGameID", time.Now().Unix())
+	bought.Version = 3
+	bought.AddBalance(100, domain.ReasonReward, "", time.Now().Unix())
+	if err := bought.SpendBalance(10, "miner:small", time.Now().Unix()); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	bought.AddMiner("small", time.Now().Unix())
+	if err := j.Append(ctx, bought); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if bought.JournaledRevision != bought.Revision {
+		t.Fatalf("expected game to be marked journaled")
+	}
+	stale := domain.NewGameState("testUserID", "staleGameID", time.Now().Unix())
+	stale.Version = 1
+	stale.AddBalance(50, domain.ReasonReward, "", time.Now().Unix())
+	if err := j.Append(ctx, stale); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+
+	// staleGameID сохранили после записи в журнал, версия в базе ушла вперёд
+	store := &MockGameStore{
+		Saved:    map[string]*domain.GameState{},
+		Versions: map[string]int64{"boughtGameID": 3, "staleGameID": 2},
+	}
+	j = openJournal(t, dir)
+	defer j.Close()
+	replayed, err := j.Replay(ctx, store)
+	if err != nil || replayed != 1 {
+		t.Fatalf("expected one replayed game, got %d, %v", replayed, err)
+	}
+	restored := store.Saved["boughtGameID"]
+	if restored == nil || restored.Balance != 90 || len(restored.Miners) != 1 || restored.Version != 3 {
+		t.Fatalf("expected purchase to be restored, got %+v", restored)
+	}
+	if len(restored.Ledger) != 2 {
+		t.Fatalf("expected unsaved ledger entries, got %+v", restored.Ledger)
+	}
+	if _, ok := store.Saved["staleGameID"]; ok {
+		t.Fatalf("expected stale record to be skipped")
+	}
+
+	// накатанные сегменты удалены
+	reopened := openJournal(t, dir)
+	defer reopened.Close()
+	replayed, err = reopened.Replay(ctx, store)
+	if err != nil || replayed != 0 {
+		t.Fatalf("expected nothing to replay, got %d, %v", replayed, err)
+	}
+}
+
+func TestTruncateDropsRotatedSegments(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	j := openJournal(t, dir)
+
+	game := domain.NewGameState("testUserID", "testGameID", time.Now().Unix())
+	game.AddBalance(10, domain.ReasonReward, "", time.Now().Unix())
+	if err := j.Append(ctx, game); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	segment, err := j.Rotate()
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if err := j.Truncate(segment); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	j.Close()
+
+	store := &MockGameStore{
+		Saved:    map[string]*domain.GameState{},
+		Versions: map[string]int64{"testGameID": 0},
+	}
+	j = openJournal(t, dir)
+	defer j.Close()
+	if replayed, err := j.Replay(ctx, store); err != nil || replayed != 0 {
+		t.Fatalf("expected truncated journal to be empty, got %d, %v", replayed, err)
+	}
+}
@@ -0,0 +1,35 @@
+package journal
+
+import "github.com/prometheus/client_golang/prometheus"
+
+type Metrics struct {
+	AppendsTotal  prometheus.Counter
+	FailedTotal   prometheus.Counter
+	SyncDuration  prometheus.Histogram
+	ReplayedTotal prometheus.Counter
+}
+
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		AppendsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "game_journal_appends_total",
+			Help: "Total records appended to the local game journal",
+		}),
+		FailedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "game_journal_failed_total",
+			Help: "Total failed journal writes or fsyncs",
+		}),
+		SyncDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "game_journal_sync_duration_seconds",
+			Help:    "Duration of one batched journal fsync",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25},
+		}),
+		ReplayedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "game_journal_replayed_total",
+			Help: "Total games restored from the journal on startup",
+		}),
+	}
+	reg.MustRegister(m.AppendsTotal, m.FailedTotal, m.SyncDuration, m.ReplayedTotal)
+
+	return m
+}
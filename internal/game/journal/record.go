@@ -0,0 +1,70 @@
+package journal
+
+import (
+	"miners_game/internal/game/domain"
+	"miners_game/internal/game/equipments"
+	"miners_game/internal/game/upgrades"
+	"miners_game/internal/miners"
+)
+
+// Record - состояние игры после изменения вместе с ещё не сохранёнными строками
+// журнала экономики. Version - версия в базе, на которую запись накатывается
+type Record struct {
+	UserID       string                   `json:"user_id"`
+	GameID       string                   `json:"game_id"`
+	Version      int64                    `json:"version"`
+	Revision     uint64                   `json:"revision"`
+	Name         string                   `json:"name"`
+	CreatedAt    int64                    `json:"created_at"`
+	Balance      int64                    `json:"balance"`
+	IncomePerSec int64                    `json:"income_per_sec"`
+	LastUpdateAt int64                    `json:"last_update_at"`
+	Miners       map[string]*miners.Miner `json:"miners"`
+	Equipments   []equipments.Equipment   `json:"equipments"`
+	Upgrades     []upgrades.Upgrade       `json:"upgrades"`
+	Ledger       []domain.LedgerEntry     `json:"ledger"`
+}
+
+func newRecord(game *domain.GameState) Record {
+	snapshot := game.CloneForSave()
+	return Record{
+		UserID:       snapshot.UserID,
+		GameID:       snapshot.GameID,
+		Version:      snapshot.Version,
+		Revision:     snapshot.Revision,
+		Name:         snapshot.Name,
+		CreatedAt:    snapshot.CreatedAt,
+		Balance:      snapshot.Balance,
+		IncomePerSec: snapshot.IncomePerSec,
+		LastUpdateAt: snapshot.LastUpdateAt,
+		Miners:       snapshot.Miners,
+		Equipments:   snapshot.Equipments,
+		Upgrades:     snapshot.Upgrades,
+		Ledger:       snapshot.Ledger,
+	}
+}
+
+func (r Record) State() *domain.GameState {
+	return &domain.GameState{
+		UserID:       r.UserID,
+		GameID:       r.GameID,
+		Version:      r.Version,
+		Name:         r.Name,
+		CreatedAt:    r.CreatedAt,
+		Balance:      r.Balance,
+		IncomePerSec: r.IncomePerSec,
+		LastUpdateAt: r.LastUpdateAt,
+		Miners:       r.Miners,
+		Equipments:   r.Equipments,
+		Upgrades:     r.Upgrades,
+		Ledger:       r.Ledger,
+	}
+}
+
+// newer - из двух записей одной игры при воспроизведении берётся более поздняя
+func (r Record) newer(other Record) bool {
+	if r.Version != other.Version {
+		return r.Version > other.Version
+	}
+	return r.Revision > other.Revision
+}
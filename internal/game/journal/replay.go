@@ -0,0 +1,99 @@
+package journal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"miners_game/internal/game/domain"
+	"miners_game/pkg/errs"
+	"os"
+)
+
+// maxRecordSize - предел строки журнала, запись игры заметно меньше
+const maxRecordSize = 4 << 20
+
+type IGameStore interface {
+	Load(ctx context.Context, userID, gameID string) (*domain.GameState, error)
+	Save(ctx context.Context, gameState *domain.GameState) error
+}
+
+// Replay - накатывает сегменты, оставшиеся от прошлого запуска, на сохранённые
+// игры и удаляет их. Берётся последняя запись игры, и только если её версия
+// совпадает с версией в базе: иначе игру уже сохранили после этой записи.
+// При ошибке базы сегменты остаются до следующего запуска
+func (j *Journal) Replay(ctx context.Context, store IGameStore) (int, error) {
+	segments, err := listSegments(j.dir)
+	if err != nil {
+		return 0, err
+	}
+	j.mu.Lock()
+	current := j.seq
+	j.mu.Unlock()
+
+	latest := map[string]Record{}
+	var last uint64
+	read := 0
+	for _, seq := range segments {
+		if seq >= current {
+			break
+		}
+		if err := j.readSegment(seq, latest); err != nil {
+			return 0, err
+		}
+		last = seq
+		read++
+	}
+
+	replayed := 0
+	for _, rec := range latest {
+		saved, err := store.Load(ctx, rec.UserID, rec.GameID)
+		if errors.Is(err, errs.ErrGameNotFound) {
+			continue
+		}
+		if err != nil {
+			return replayed, err
+		}
+		if saved.Version != rec.Version {
+			continue
+		}
+		if err := store.Save(ctx, rec.State()); err != nil && !errors.Is(err, errs.ErrSaveConflict) {
+			return replayed, err
+		}
+		replayed++
+	}
+	if j.metrics != nil {
+		j.metrics.ReplayedTotal.Add(float64(replayed))
+	}
+	if last > 0 {
+		if err := j.Truncate(last); err != nil {
+			return replayed, err
+		}
+	}
+	j.logger.Info().Int("segments", read).Int("games", len(latest)).Int("replayed", replayed).Msg("journal replayed")
+	return replayed, nil
+}
+
+// readSegment - недописанная последняя строка после падения пропускается
+func (j *Journal) readSegment(seq uint64, latest map[string]Record) error {
+	file, err := os.Open(j.segmentPath(seq))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			j.logger.Warn().Err(err).Uint64("segment", seq).Msg("skipped broken journal record")
+			continue
+		}
+		key := rec.UserID + "/" + rec.GameID
+		if prev, ok := latest[key]; !ok || rec.newer(prev) {
+			latest[key] = rec
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,155 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"miners_game/internal/game/domain"
+	"miners_game/pkg/errs"
+	"strings"
+	"sync"
+)
+
+// RenewLeases - продление аренд активных игр. Потерянная игра выгружается без
+// сохранения: её уже загрузил другой инстанс. Запрошенная игра сохраняется и
+// отпускается, ждущий инстанс загрузит её из базы
+func (s *Service) RenewLeases(ctx context.Context) {
+	if s.leases == nil {
+		return
+	}
+	lost, handoffs, err := s.leases.Renew(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to renew leases")
+		return
+	}
+	for _, id := range lost {
+		if game := s.loaded(id); game != nil && s.unload(game) {
+			s.logger.Warn().Str("user_id", game.UserID).Str("game_id", game.GameID).Msg("lease lost, game unloaded")
+		}
+	}
+	if len(handoffs) == 0 {
+		return
+	}
+	// снимок для передачи не должен обогнать снимок параллельного SaveAll
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	for _, id := range handoffs {
+		game := s.loaded(id)
+		if game == nil || !s.unload(game) {
+			userID, gameID, _ := strings.Cut(id, "/")
+			s.releaseLease(ctx, userID, gameID)
+			continue
+		}
+		if !s.saveAndRelease(ctx, game) {
+			continue
+		}
+		s.logger.Info().Str("user_id", game.UserID).Str("game_id", game.GameID).Msg("game handed off")
+	}
+}
+
+// ReleaseLeases - при остановке, после финального SaveAll
+func (s *Service) ReleaseLeases(ctx context.Context) {
+	if s.leases == nil {
+		return
+	}
+	if err := s.leases.ReleaseAll(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("failed to release leases")
+	}
+}
+
+// saveAndRelease - последнее сохранение выгруженной игры. При конфликте копия
+// уже устарела и аренда отпускается, при другой ошибке игра возвращается
+// в память вместе с арендой и уйдёт в базу со следующим SaveAll
+func (s *Service) saveAndRelease(ctx context.Context, game *domain.GameState) bool {
+	s.advance(game)
+	snapshot := game.CloneForSave()
+	err := s.repo.Save(ctx, snapshot)
+	if err != nil && !errors.Is(err, errs.ErrSaveConflict) {
+		err = s.saveWithRetry(ctx, snapshot)
+	}
+	if err != nil && !errors.Is(err, errs.ErrSaveConflict) {
+		s.keep(game)
+		return false
+	}
+	s.releaseLease(ctx, game.UserID, game.GameID)
+	return true
+}
+
+// keep - возврат выгруженной игры, которую не удалось сохранить. Если за это
+// время игру загрузили заново, в памяти остаётся новая копия
+func (s *Service) keep(game *domain.GameState) {
+	id := game.UserID + "/" + game.GameID
+	s.mu.Lock()
+	if _, ok := s.games[id]; ok {
+		s.mu.Unlock()
+		s.logger.Error().Str("user_id", game.UserID).Str("game_id", game.GameID).Msg("unsaved game dropped, newer copy is loaded")
+		return
+	}
+	s.games[id] = game
+	s.mu.Unlock()
+	s.loop.Register(id, game)
+	s.logger.Warn().Str("user_id", game.UserID).Str("game_id", game.GameID).Msg("game kept in memory after failed save")
+}
+
+func (s *Service) releaseLease(ctx context.Context, userID, gameID string) {
+	if s.leases == nil {
+		return
+	}
+	if err := s.leases.Release(ctx, userID, gameID); err != nil {
+		s.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to release lease")
+	}
+}
+
+// slotLock - мьютекс одной игры, живёт, пока его кто-то держит или ждёт
+type slotLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lockSlot - загрузка, выгрузка, откат и переименование игры выполняются по одному
+func (s *Service) lockSlot(id string) func() {
+	s.mu.Lock()
+	lock, ok := s.slotLocks[id]
+	if !ok {
+		lock = &slotLock{}
+		s.slotLocks[id] = lock
+	}
+	lock.refs++
+	s.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		s.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(s.slotLocks, id)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// lockUser - создание слотов пользователя выполняется по одному. Ключ без "/"
+// не пересекается с ключами игр
+func (s *Service) lockUser(userID string) func() {
+	return s.lockSlot(userID)
+}
+
+func (s *Service) loaded(id string) *domain.GameState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.games[id]
+}
+
+// unload - выгрузка из памяти и loop без сохранения. false - в памяти уже другая копия
+func (s *Service) unload(game *domain.GameState) bool {
+	id := game.UserID + "/" + game.GameID
+	s.mu.Lock()
+	if s.games[id] != game {
+		s.mu.Unlock()
+		return false
+	}
+	delete(s.games, id)
+	s.mu.Unlock()
+	s.loop.Unregister(id)
+	return true
+}
@@ -0,0 +1,14 @@
+package legacy
+
+import (
+	"context"
+	"miners_game/internal/game/domain"
+)
+
+type ILegacyRepository interface {
+	Pending(ctx context.Context) ([]LegacySave, error)
+	Convert(ctx context.Context, game *domain.GameState, maxSlots int, now int64) (string, error)
+	MarkConflict(ctx context.Context, userID, saveID, reason string) error
+	Status(ctx context.Context) (Status, error)
+	Drop(ctx context.Context) error
+}
@@ -0,0 +1,45 @@
+package legacy
+
+// Причины, по которым старое сохранение не перенесено. Строка остаётся
+// в game_saves и переносится повторно при следующем запуске
+const (
+	ConflictGameExists  = "game_exists"
+	ConflictUnknownUser = "unknown_user"
+	ConflictBadMiners   = "bad_miners"
+	ConflictSlotLimit   = "slot_limit"
+)
+
+// LegacySave - строка game_saves: save_id становится game_id
+type LegacySave struct {
+	UserID       string
+	SaveID       string
+	Balance      int64
+	LastUpdateAt int64
+	Miners       []byte
+	UserExists   bool
+}
+
+type Conflict struct {
+	UserID string
+	SaveID string
+	Reason string
+}
+
+// Report - итог одного запуска convert
+type Report struct {
+	Converted int
+	Conflicts []Conflict
+}
+
+// Status - состояние переноса. Exists = false - таблица уже удалена
+type Status struct {
+	Exists    bool
+	Total     int
+	Converted int
+	Conflicts []Conflict
+}
+
+// Pending - строки, которые ещё не перенесены, включая конфликтные
+func (s Status) Pending() int {
+	return s.Total - s.Converted
+}
@@ -0,0 +1,209 @@
+package legacy
+
+import (
+	"context"
+	"miners_game/internal/game"
+	"miners_game/internal/game/domain"
+	"miners_game/pkg/errs"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+type Repository struct {
+	dbPool *pgxpool.Pool
+	logger zerolog.Logger
+}
+
+type RepositoryDeps struct {
+	DbPool *pgxpool.Pool
+	Logger zerolog.Logger
+}
+
+func NewRepository(deps RepositoryDeps) *Repository {
+	return &Repository{
+		dbPool: deps.DbPool,
+		logger: deps.Logger,
+	}
+}
+
+func (r *Repository) exists(ctx context.Context) (bool, error) {
+	var exists bool
+	if err := r.dbPool.QueryRow(ctx, `SELECT to_regclass('game_saves') IS NOT NULL`).Scan(&exists); err != nil {
+		r.logger.Error().Err(err).Msg("failed to check game_saves table")
+		return false, errs.ErrServer
+	}
+	return exists, nil
+}
+
+// Pending - ещё не перенесённые строки вместе с прошлыми конфликтами
+func (r *Repository) Pending(ctx context.Context) ([]LegacySave, error) {
+	exists, err := r.exists(ctx)
+	if err != nil || !exists {
+		return nil, err
+	}
+	query := `
+		SELECT s.user_id, s.save_id, s.balance, s.last_update_at, s.miners,
+			EXISTS (SELECT 1 FROM users u WHERE u.user_id = s.user_id)
+		FROM game_saves s
+		WHERE s.converted_at = 0
+		ORDER BY s.user_id, s.save_id
+	`
+	rows, err := r.dbPool.Query(ctx, query)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to select legacy saves")
+		return nil, errs.ErrServer
+	}
+	defer rows.Close()
+
+	saves := []LegacySave{}
+	for rows.Next() {
+		var s LegacySave
+		if err := rows.Scan(&s.UserID, &s.SaveID, &s.Balance, &s.LastUpdateAt, &s.Miners, &s.UserExists); err != nil {
+			r.logger.Error().Err(err).Msg("failed to scan legacy save")
+			return nil, errs.ErrServer
+		}
+		saves = append(saves, s)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("failed to select legacy saves")
+		return nil, errs.ErrServer
+	}
+	return saves, nil
+}
+
+// Convert - вставка игры и отметка о переносе в одной транзакции, поэтому
+// повторный запуск не создаст игру дважды. Непустой результат - причина
+// конфликта: игра с таким id уже есть или у пользователя больше maxSlots игр
+func (r *Repository) Convert(ctx context.Context, gameState *domain.GameState, maxSlots int, now int64) (string, error) {
+	tx, err := r.dbPool.Begin(ctx)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to begin convert transaction")
+		return "", errs.ErrServer
+	}
+	defer tx.Rollback(ctx)
+
+	inserted, err := game.InsertGame(ctx, tx, gameState)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", gameState.UserID).Str("save_id", gameState.GameID).Msg("failed to insert converted game")
+		return "", errs.ErrServer
+	}
+	if !inserted {
+		return ConflictGameExists, nil
+	}
+	if maxSlots > 0 {
+		var slots int
+		if err := tx.QueryRow(ctx, `SELECT count(*) FROM games WHERE user_id = @user_id`, pgx.NamedArgs{
+			"user_id": gameState.UserID,
+		}).Scan(&slots); err != nil {
+			r.logger.Error().Err(err).Str("user_id", gameState.UserID).Msg("failed to count user games")
+			return "", errs.ErrServer
+		}
+		if slots > maxSlots {
+			return ConflictSlotLimit, nil
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE game_saves SET converted_at = @now, conflict = ''
+		WHERE user_id = @user_id AND save_id = @save_id
+	`, pgx.NamedArgs{
+		"user_id": gameState.UserID,
+		"save_id": gameState.GameID,
+		"now":     now,
+	}); err != nil {
+		r.logger.Error().Err(err).Str("user_id", gameState.UserID).Str("save_id", gameState.GameID).Msg("failed to mark legacy save converted")
+		return "", errs.ErrServer
+	}
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error().Err(err).Str("user_id", gameState.UserID).Str("save_id", gameState.GameID).Msg("failed to commit convert transaction")
+		return "", errs.ErrServer
+	}
+	return "", nil
+}
+
+func (r *Repository) MarkConflict(ctx context.Context, userID, saveID, reason string) error {
+	query := `
+		UPDATE game_saves SET conflict = @reason
+		WHERE user_id = @user_id AND save_id = @save_id AND converted_at = 0
+	`
+	if _, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+		"save_id": saveID,
+		"reason":  reason,
+	}); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("save_id", saveID).Msg("failed to mark legacy save conflict")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *Repository) Status(ctx context.Context) (Status, error) {
+	exists, err := r.exists(ctx)
+	if err != nil || !exists {
+		return Status{}, err
+	}
+	status := Status{Exists: true, Conflicts: []Conflict{}}
+	if err := r.dbPool.QueryRow(ctx, `
+		SELECT count(*), count(*) FILTER (WHERE converted_at <> 0) FROM game_saves
+	`).Scan(&status.Total, &status.Converted); err != nil {
+		r.logger.Error().Err(err).Msg("failed to count legacy saves")
+		return Status{}, errs.ErrServer
+	}
+	rows, err := r.dbPool.Query(ctx, `
+		SELECT user_id, save_id, conflict FROM game_saves
+		WHERE converted_at = 0 AND conflict <> ''
+		ORDER BY user_id, save_id
+	`)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to select legacy save conflicts")
+		return Status{}, errs.ErrServer
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c Conflict
+		if err := rows.Scan(&c.UserID, &c.SaveID, &c.Reason); err != nil {
+			r.logger.Error().Err(err).Msg("failed to scan legacy save conflict")
+			return Status{}, errs.ErrServer
+		}
+		status.Conflicts = append(status.Conflicts, c)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("failed to select legacy save conflicts")
+		return Status{}, errs.ErrServer
+	}
+	return status, nil
+}
+
+// Drop - удаление таблицы под блокировкой, если все строки перенесены.
+// Проверка повторяется внутри транзакции, поэтому строка не потеряется
+func (r *Repository) Drop(ctx context.Context) error {
+	tx, err := r.dbPool.Begin(ctx)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to begin drop transaction")
+		return errs.ErrServer
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `LOCK TABLE game_saves IN ACCESS EXCLUSIVE MODE`); err != nil {
+		r.logger.Error().Err(err).Msg("failed to lock game_saves")
+		return errs.ErrServer
+	}
+	var pending int
+	if err := tx.QueryRow(ctx, `SELECT count(*) FROM game_saves WHERE converted_at = 0`).Scan(&pending); err != nil {
+		r.logger.Error().Err(err).Msg("failed to count pending legacy saves")
+		return errs.ErrServer
+	}
+	if pending > 0 {
+		return errs.ErrLegacySavesPending
+	}
+	if _, err := tx.Exec(ctx, `DROP TABLE game_saves`); err != nil {
+		r.logger.Error().Err(err).Msg("failed to drop game_saves")
+		return errs.ErrServer
+	}
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error().Err(err).Msg("failed to commit drop transaction")
+		return errs.ErrServer
+	}
+	return nil
+}
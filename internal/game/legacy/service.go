@@ -0,0 +1,136 @@
+package legacy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"miners_game/config"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/miners"
+	"miners_game/pkg/clock"
+	"miners_game/pkg/errs"
+
+	"github.com/rs/zerolog"
+)
+
+// legacyName - название слота для перенесённого сохранения
+const legacyName = "Старое сохранение"
+
+type Service struct {
+	repo   ILegacyRepository
+	config *config.GameConfig
+	clock  clock.Clock
+	logger zerolog.Logger
+}
+
+type ServiceDeps struct {
+	Repo   ILegacyRepository
+	Config *config.GameConfig
+	Clock  clock.Clock
+	Logger zerolog.Logger
+}
+
+func NewService(deps ServiceDeps) *Service {
+	return &Service{
+		repo:   deps.Repo,
+		config: deps.Config,
+		clock:  clock.OrReal(deps.Clock),
+		logger: deps.Logger,
+	}
+}
+
+// Convert - перенос строк game_saves в games со снаряжением и улучшениями
+// по умолчанию. Перенесённые строки отмечаются и повторно не трогаются,
+// конфликтные остаются и пробуются снова при следующем запуске. Лимит
+// сохранений тот же, что у игроков: лишнее сохранение - конфликт
+func (s *Service) Convert(ctx context.Context) (Report, error) {
+	saves, err := s.repo.Pending(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	maxSlots := 0
+	if s.config != nil {
+		maxSlots = s.config.MaxSaveSlots
+	}
+	report := Report{Conflicts: []Conflict{}}
+	now := s.clock.Now().Unix()
+	for _, save := range saves {
+		reason := ""
+		if !save.UserExists {
+			reason = ConflictUnknownUser
+		} else if game, err := toGame(save, now); err != nil {
+			s.logger.Warn().Err(err).Str("user_id", save.UserID).Str("save_id", save.SaveID).Msg("failed to parse legacy miners")
+			reason = ConflictBadMiners
+		} else {
+			conflict, err := s.repo.Convert(ctx, game, maxSlots, now)
+			if err != nil {
+				return report, err
+			}
+			if conflict == "" {
+				report.Converted++
+				continue
+			}
+			reason = conflict
+		}
+		if err := s.repo.MarkConflict(ctx, save.UserID, save.SaveID, reason); err != nil {
+			return report, err
+		}
+		report.Conflicts = append(report.Conflicts, Conflict{UserID: save.UserID, SaveID: save.SaveID, Reason: reason})
+	}
+	s.logger.Info().Int("converted", report.Converted).Int("conflicts", len(report.Conflicts)).Msg("legacy saves converted")
+	return report, nil
+}
+
+func (s *Service) Status(ctx context.Context) (Status, error) {
+	return s.repo.Status(ctx)
+}
+
+// Drop - удаление game_saves, только когда не осталось неперенесённых строк.
+// Уже удалённая таблица - не ошибка
+func (s *Service) Drop(ctx context.Context) error {
+	status, err := s.repo.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if !status.Exists {
+		return nil
+	}
+	if status.Pending() > 0 {
+		return errs.ErrLegacySavesPending
+	}
+	if err := s.repo.Drop(ctx); err != nil {
+		return err
+	}
+	s.logger.Info().Int("rows", status.Total).Msg("game_saves dropped")
+	return nil
+}
+
+// toGame - в старой схеме только баланс и шахтёры, остальное - как у новой игры.
+// Журнал экономики начинается с записи на весь баланс в момент переноса
+func toGame(save LegacySave, now int64) (*domain.GameState, error) {
+	game := domain.NewGameState(save.UserID, save.SaveID, save.LastUpdateAt)
+	game.Name = legacyName
+	game.Balance = save.Balance
+
+	if len(save.Miners) > 0 {
+		var legacyMiners map[string]*miners.Miner
+		if err := json.Unmarshal(save.Miners, &legacyMiners); err != nil {
+			return nil, err
+		}
+		for key, miner := range legacyMiners {
+			if miner == nil {
+				continue
+			}
+			if _, ok := miners.MinerPresets[miner.Class]; !ok {
+				return nil, fmt.Errorf("unknown miner class %q", miner.Class)
+			}
+			if miner.ID == "" {
+				miner.ID = key
+			}
+			game.Miners[key] = miner
+		}
+	}
+	game.IncomePerSec = game.CalcIncome(save.LastUpdateAt-1, save.LastUpdateAt)
+	game.OpenLedger(domain.ReasonOpening, now)
+	return game, nil
+}
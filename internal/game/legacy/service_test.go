@@ -0,0 +1,178 @@
+package legacy_test
+
+import (
+	"context"
+	"errors"
+	"miners_game/config"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/game/legacy"
+	"miners_game/pkg/errs"
+	"testing"
+	"time"
+)
+
+type MockLegacyRepository struct {
+	Saves     []legacy.LegacySave
+	Games     map[string]*domain.GameState
+	Converted map[string]bool
+	Conflicts map[string]string
+	Dropped   bool
+}
+
+func (m *MockLegacyRepository) Pending(ctx context.Context) ([]legacy.LegacySave, error) {
+	pending := []legacy.LegacySave{}
+	for _, s := range m.Saves {
+		if !m.Converted[s.SaveID] {
+			pending = append(pending, s)
+		}
+	}
+	return pending, nil
+}
+
+func (m *MockLegacyRepository) Convert(ctx context.Context, game *domain.GameState, maxSlots int, now int64) (string, error) {
+	if _, ok := m.Games[game.GameID]; ok {
+		return legacy.ConflictGameExists, nil
+	}
+	slots := 0
+	for _, g := range m.Games {
+		if g.UserID == game.UserID {
+			slots++
+		}
+	}
+	if maxSlots > 0 && slots >= maxSlots {
+		return legacy.ConflictSlotLimit, nil
+	}
+	m.Games[game.GameID] = game
+	m.Converted[game.GameID] = true
+	delete(m.Conflicts, game.GameID)
+	return "", nil
+}
+
+func (m *MockLegacyRepository) MarkConflict(ctx context.Context, userID, saveID, reason string) error {
+	m.Conflicts[saveID] = reason
+	return nil
+}
+
+func (m *MockLegacyRepository) Status(ctx context.Context) (legacy.Status, error) {
+	if m.Dropped {
+		return legacy.Status{}, nil
+	}
+	return legacy.Status{Exists: true, Total: len(m.Saves), Converted: len(m.Converted)}, nil
+}
+
+func (m *MockLegacyRepository) Drop(ctx context.Context) error {
+	m.Dropped = true
+	return nil
+}
+
+func TestConvertReportsConflictsAndIsIdempotent(t *testing.T) {
+	repo := &MockLegacyRepository{
+		Saves: []legacy.LegacySave{
+			{UserID: "testUserID", SaveID: "save1", Balance: 500, LastUpdateAt: 1000, Miners: []byte(`{"m1":{"Class":"small","StartAt":900,"EndAt":2000}}`), UserExists: true},
+			{UserID: "testUserID", SaveID: "taken", Balance: 10, LastUpdateAt: 1000, UserExists: true},
+			{UserID: "ghostUserID", SaveID: "save2", Balance: 10, LastUpdateAt: 1000},
+			{UserID: "testUserID", SaveID: "broken", Balance: 10, LastUpdateAt: 1000, Miners: []byte(`{"m1":{"Class":"unknown"}}`), UserExists: true},
+		},
+		Games:     map[string]*domain.GameState{"taken": domain.NewGameState("testUserID", "taken", time.Now().Unix())},
+		Converted: map[string]bool{},
+		Conflicts: map[string]string{},
+	}
+	legacyService := legacy.NewService(legacy.ServiceDeps{Repo: repo})
+
+	report, err := legacyService.Convert(context.Background())
+	if err != nil {
+		t.Fatalf("expected success, got err %v:", err)
+	}
+	if report.Converted != 1 || len(report.Conflicts) != 3 {
+		t.Fatalf("expected 1 converted and 3 conflicts, got %+v", report)
+	}
+	want := map[string]string{
+		"taken":  legacy.ConflictGameExists,
+		"save2":  legacy.ConflictUnknownUser,
+		"broken": legacy.ConflictBadMiners,
+	}
+	for saveID, reason := range want {
+		if repo.Conflicts[saveID] != reason {
+			t.Fatalf("expected conflict %s for %s, got %q", reason, saveID, repo.Conflicts[saveID])
+		}
+	}
+
+	game := repo.Games["save1"]
+	if game.Balance != 500 || game.Miners["m1"] == nil || game.Miners["m1"].ID != "m1" {
+		t.Fatalf("expected balance and miners to be converted, got %+v", game)
+	}
+	if len(game.Equipments) == 0 || len(game.Upgrades) == 0 {
+		t.Fatalf("expected default equipments and upgrades")
+	}
+	if len(game.Ledger) != 1 || game.Ledger[0].Reason != domain.ReasonOpening || game.Ledger[0].Amount != 500 {
+		t.Fatalf("expected opening ledger entry for the balance, got %+v", game.Ledger)
+	}
+
+	if err := legacyService.Drop(context.Background()); !errors.Is(err, errs.ErrLegacySavesPending) {
+		t.Fatalf("expected ErrLegacySavesPending, got %v:", err)
+	}
+
+	delete(repo.Games, "taken")
+	report, err = legacyService.Convert(context.Background())
+	if err != nil {
+		t.Fatalf("expected success, got err %v:", err)
+	}
+	if report.Converted != 1 || len(report.Conflicts) != 2 {
+		t.Fatalf("expected only the resolved conflict to be converted, got %+v", report)
+	}
+	if repo.Games["save1"] != game {
+		t.Fatalf("expected converted save not to be converted again")
+	}
+}
+
+func TestConvertRespectsSlotLimit(t *testing.T) {
+	repo := &MockLegacyRepository{
+		Saves: []legacy.LegacySave{
+			{UserID: "testUserID", SaveID: "save1", Balance: 10, LastUpdateAt: 1000, UserExists: true},
+			{UserID: "testUserID", SaveID: "save2", Balance: 20, LastUpdateAt: 1000, UserExists: true},
+		},
+		Games:     map[string]*domain.GameState{"current": domain.NewGameState("testUserID", "current", time.Now().Unix())},
+		Converted: map[string]bool{},
+		Conflicts: map[string]string{},
+	}
+	legacyService := legacy.NewService(legacy.ServiceDeps{
+		Repo:   repo,
+		Config: &config.GameConfig{MaxSaveSlots: 2},
+	})
+
+	report, err := legacyService.Convert(context.Background())
+	if err != nil {
+		t.Fatalf("expected success, got err %v:", err)
+	}
+	if report.Converted != 1 || len(report.Conflicts) != 1 || repo.Conflicts["save2"] != legacy.ConflictSlotLimit {
+		t.Fatalf("expected save over the limit to be a conflict, got %+v", report)
+	}
+	if _, ok := repo.Games["save2"]; ok {
+		t.Fatalf("expected save over the limit not to be inserted")
+	}
+}
+
+func TestDropAfterConvert(t *testing.T) {
+	repo := &MockLegacyRepository{
+		Saves: []legacy.LegacySave{
+			{UserID: "testUserID", SaveID: "save1", Balance: 500, LastUpdateAt: 1000, UserExists: true},
+		},
+		Games:     map[string]*domain.GameState{},
+		Converted: map[string]bool{},
+		Conflicts: map[string]string{},
+	}
+	legacyService := legacy.NewService(legacy.ServiceDeps{Repo: repo})
+
+	if _, err := legacyService.Convert(context.Background()); err != nil {
+		t.Fatalf("expected success, got err %v:", err)
+	}
+	if err := legacyService.Drop(context.Background()); err != nil {
+		t.Fatalf("expected success, got err %v:", err)
+	}
+	if !repo.Dropped {
+		t.Fatalf("expected game_saves to be dropped")
+	}
+	if err := legacyService.Drop(context.Background()); err != nil {
+		t.Fatalf("expected repeated drop to succeed, got err %v:", err)
+	}
+}
@@ -0,0 +1,36 @@
+package loop
+
+import "github.com/prometheus/client_golang/prometheus"
+
+type Metrics struct {
+	TickDuration     *prometheus.HistogramVec
+	TickOverruns     *prometheus.CounterVec
+	GameTickDuration prometheus.Histogram
+	ShardGames       *prometheus.GaugeVec
+}
+
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		TickDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "loop_tick_duration_seconds",
+			Help:    "Duration of one shard tick",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"shard"}),
+		TickOverruns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "loop_tick_overruns_total",
+			Help: "Total shard ticks that took longer than the tick interval",
+		}, []string{"shard"}),
+		GameTickDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "loop_game_tick_duration_seconds",
+			Help:    "Duration of one game tick including waiting for its lock",
+			Buckets: prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}),
+		ShardGames: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "loop_shard_games",
+			Help: "Games registered in the shard at the last tick",
+		}, []string{"shard"}),
+	}
+	reg.MustRegister(m.TickDuration, m.TickOverruns, m.GameTickDuration, m.ShardGames)
+
+	return m
+}
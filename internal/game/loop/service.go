@@ -1,50 +1,236 @@
 package loop
 
 import (
+	"miners_game/config"
 	"miners_game/internal/game/domain"
+	"miners_game/pkg/clock"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 )
 
+// Service - игровой цикл. Игры разбиты на шарды по хешу id, каждый шард
+// тикает свой воркер (TickShard), медленная игра задерживает только свой шард.
+// В ленивом режиме игры не тикают: состояние доводится до текущей секунды
+// при чтении и изменении (Advance), а воркер обрабатывает только окончания майнеров
 type Service struct {
-	games  map[string]*domain.GameState
-	mu     sync.RWMutex
-	logger zerolog.Logger
+	shards   []*shard
+	interval time.Duration
+	lazy     bool
+	clock    clock.Clock
+	// mu защищает только подписки, игры шардов живут без общей блокировки
+	subs    map[string]map[chan struct{}]struct{}
+	closed  bool
+	mu      sync.RWMutex
+	metrics *Metrics
+	logger  zerolog.Logger
 }
 
 type ServiceDeps struct {
-	Logger zerolog.Logger
+	Config  *config.LoopConfig
+	Clock   clock.Clock
+	Metrics *Metrics
+	Logger  zerolog.Logger
 }
 
+// defaultTickInterval - интервал тика, если LOOP_TICK_MS не больше нуля:
+// NewTicker с таким интервалом паникует
+const defaultTickInterval = time.Second
+
 func NewService(deps ServiceDeps) *Service {
+	count := deps.Config.Shards
+	if count < 1 {
+		count = 1
+	}
+	interval := deps.Config.TickInterval
+	if interval <= 0 {
+		deps.Logger.Warn().Dur("interval", interval).Msg("invalid loop tick interval, using default")
+		interval = defaultTickInterval
+	}
+	shards := make([]*shard, count)
+	for i := range shards {
+		shards[i] = newShard()
+	}
 	return &Service{
-		games:  make(map[string]*domain.GameState),
-		logger: deps.Logger,
+		shards:   shards,
+		interval: interval,
+		lazy:     deps.Config.Lazy,
+		clock:    clock.OrReal(deps.Clock),
+		subs:     make(map[string]map[chan struct{}]struct{}),
+		metrics:  deps.Metrics,
+		logger:   deps.Logger,
 	}
 }
 
+// Shards - число шардов, на каждый запускается свой воркер
+func (s *Service) Shards() int {
+	return len(s.shards)
+}
+
+// Interval - интервал тика воркеров
+func (s *Service) Interval() time.Duration {
+	return s.interval
+}
+
+// Tick - все шарды подряд в вызывающей горутине
 func (s *Service) Tick(now int64) {
+	for i := range s.shards {
+		s.TickShard(i, now)
+	}
+}
+
+// TickShard - тик одного шарда по снимку его игр. Тик дольше интервала
+// считается переполнением: следующий тик воркера уже опаздывает
+func (s *Service) TickShard(i int, now int64) {
+	start := time.Now()
+	games := s.shards[i].snapshot()
+	if s.lazy {
+		s.advanceDue(s.shards[i], games, now)
+	} else {
+		for id, game := range games {
+			gameStart := time.Now()
+			game.Tick(now)
+			if s.metrics != nil {
+				s.metrics.GameTickDuration.Observe(time.Since(gameStart).Seconds())
+			}
+			s.notifySubs(id)
+		}
+	}
+	elapsed := time.Since(start)
+	overrun := elapsed > s.interval
+	if overrun {
+		s.logger.Warn().Int("shard", i).Int("games", len(games)).Dur("duration", elapsed).Msg("shard tick overrun")
+	}
+	if s.metrics == nil {
+		return
+	}
+	shard := strconv.Itoa(i)
+	s.metrics.TickDuration.WithLabelValues(shard).Observe(elapsed.Seconds())
+	s.metrics.ShardGames.WithLabelValues(shard).Set(float64(len(games)))
+	if overrun {
+		s.metrics.TickOverruns.WithLabelValues(shard).Inc()
+	}
+}
+
+// advanceDue - ленивый режим: у игр закончился майнер, доход и HUD меняются
+func (s *Service) advanceDue(sh *shard, games map[string]*domain.GameState, now int64) {
+	for _, id := range sh.due(now) {
+		game, ok := games[id]
+		if !ok {
+			continue
+		}
+		gameStart := time.Now()
+		game.Advance(now)
+		if s.metrics != nil {
+			s.metrics.GameTickDuration.Observe(time.Since(gameStart).Seconds())
+		}
+		sh.schedule(id, game.NextExpiry())
+		s.notifySubs(id)
+	}
+}
+
+// Advance - в ленивом режиме доводит игру до текущей секунды перед чтением
+// или изменением, в обычном состояние и так обновляет тик
+func (s *Service) Advance(game *domain.GameState) {
+	if s.lazy {
+		game.Advance(s.clock.Now().Unix())
+	}
+}
+
+// Subscribe - уведомления о тиках игры. Канал с буфером 1: медленный
+// подписчик пропускает промежуточные тики и читает уже свежее состояние
+func (s *Service) Subscribe(id string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if s.subs[id] == nil {
+		s.subs[id] = make(map[chan struct{}]struct{})
+	}
+	s.subs[id][ch] = struct{}{}
+	s.mu.Unlock()
+
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subs[id][ch]; ok {
+			delete(s.subs[id], ch)
+			close(ch)
+		}
+		if len(s.subs[id]) == 0 {
+			delete(s.subs, id)
+		}
+	}
+	return ch, cancel
+}
+
+// Notify - внеочередное уведомление подписчиков, например после покупки.
+// В ленивом режиме заново планируется окончание майнеров: покупка могла его приблизить
+func (s *Service) Notify(id string) {
+	if s.lazy {
+		sh := s.shards[shardIndex(id, len(s.shards))]
+		if game, ok := sh.snapshot()[id]; ok {
+			sh.schedule(id, game.NextExpiry())
+		}
+	}
+	s.notifySubs(id)
+}
+
+func (s *Service) notifySubs(id string) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-
-	for _, game := range s.games {
-		game.Tick(now)
+	for ch := range s.subs[id] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
 	}
 }
 
+// Register и Unregister не ждут идущий тик: он работает со старым снимком шарда
 func (s *Service) Register(id string, game *domain.GameState) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.games[id] = game
+	sh := s.shards[shardIndex(id, len(s.shards))]
+	sh.update(func(games map[string]*domain.GameState) {
+		games[id] = game
+	})
+	if s.lazy {
+		sh.schedule(id, game.NextExpiry())
+	}
 
 	s.logger.Debug().Str("game_id/save_id", id).Msg("game registered in loop")
 }
 
 func (s *Service) Unregister(id string) {
+	s.shards[shardIndex(id, len(s.shards))].update(func(games map[string]*domain.GameState) {
+		delete(games, id)
+	})
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.games, id)
+	for ch := range s.subs[id] {
+		close(ch)
+	}
+	delete(s.subs, id)
 
 	s.logger.Debug().Str("game_id/save_id", id).Msg("game unregistered from loop")
 }
+
+// CloseSubscriptions - при остановке: HUD-потоки держат соединения открытыми,
+// закрытый канал завершает их, и клиенты переподключаются к другому инстансу
+func (s *Service) CloseSubscriptions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for id, subs := range s.subs {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(s.subs, id)
+	}
+}
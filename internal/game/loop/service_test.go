@@ -0,0 +1,325 @@
+package loop_test
+
+import (
+	"context"
+	"miners_game/config"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/game/equipments"
+	"miners_game/internal/game/loop"
+	"miners_game/internal/miners"
+	"miners_game/pkg/clock"
+	"miners_game/pkg/lifecycle"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func newLoopService(shards int) *loop.Service {
+	return loop.NewService(loop.ServiceDeps{
+		Config: &config.LoopConfig{Shards: shards, TickInterval: time.Second},
+	})
+}
+
+func TestTickReachesAllShards(t *testing.T) {
+	loopService := newLoopService(4)
+	games := make([]*domain.GameState, 0, 20)
+	for i := 0; i < 20; i++ {
+		game := domain.NewGameState("testUserID", strconv.Itoa(i), 100)
+		games = append(games, game)
+		loopService.Register("testUserID/"+game.GameID, game)
+	}
+
+	loopService.Tick(110)
+
+	for _, game := range games {
+		if game.LastUpdateAt != 110 || game.Balance == 0 {
+			t.Fatalf("expected game %s to be ticked, got last update %d", game.GameID, game.LastUpdateAt)
+		}
+	}
+}
+
+func TestRegisterDoesNotWaitForTick(t *testing.T) {
+	loopService := newLoopService(1)
+	locked := domain.NewGameState("testUserID", "locked", 100)
+	loopService.Register("testUserID/locked", locked)
+
+	// покупка держит блокировку игры, тик шарда встаёт на ней
+	locked.Mu.Lock()
+	tickDone := make(chan struct{})
+	go func() {
+		loopService.TickShard(0, 110)
+		close(tickDone)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	registered := make(chan struct{})
+	go func() {
+		loopService.Register("testUserID/other", domain.NewGameState("testUserID", "other", 100))
+		loopService.Unregister("testUserID/other")
+		close(registered)
+	}()
+	select {
+	case <-registered:
+	case <-time.After(time.Second):
+		t.Fatalf("expected Register and Unregister not to wait for a running tick")
+	}
+
+	locked.Mu.Unlock()
+	<-tickDone
+	if locked.LastUpdateAt != 110 {
+		t.Fatalf("expected locked game to be ticked after unlock, got %d", locked.LastUpdateAt)
+	}
+}
+
+// lazyScenario - одна и та же история игры: покупки, снаряжение и новые
+// майнеры в заданные секунды
+type lazyScenario struct {
+	spend     map[int64]int64
+	equipment map[int64]string
+	miners    map[int64]string
+}
+
+func newScenarioGame(start int64) *domain.GameState {
+	game := domain.NewGameState("testUserID", "testGameID", start)
+	game.Balance = 1000
+	game.Miners["a"] = &miners.Miner{ID: "a", Class: "normal", StartAt: start - 10, EndAt: start + 35}
+	game.Miners["b"] = &miners.Miner{ID: "b", Class: "small", StartAt: start - 3, EndAt: start + 27}
+	return game
+}
+
+func (sc lazyScenario) apply(loopService *loop.Service, game *domain.GameState, t int64) {
+	if amount, ok := sc.spend[t]; ok {
+		game.SpendBalance(amount, "test", t)
+	}
+	if name, ok := sc.equipment[t]; ok {
+		game.AddEquipment(name)
+	}
+	if class, ok := sc.miners[t]; ok {
+		game.Mu.Lock()
+		game.Miners[class+strconv.FormatInt(t, 10)] = &miners.Miner{
+			ID:      class,
+			Class:   class,
+			StartAt: t,
+			EndAt:   t + miners.GetMinerConfig(class).Energy,
+		}
+		game.Revision++
+		game.Mu.Unlock()
+	}
+	loopService.Notify("testUserID/testGameID")
+}
+
+func TestLazyBalanceMatchesEager(t *testing.T) {
+	const start int64 = 1_000_000
+	sc := lazyScenario{
+		spend:     map[int64]int64{start + 7: 300, start + 90: 50},
+		equipment: map[int64]string{start + 13: equipments.NewEquipments()[0].Name},
+		miners:    map[int64]string{start + 20: "strong", start + 41: "small", start + 42: "normal"},
+	}
+	actions := map[int64]bool{}
+	for t := range sc.spend {
+		actions[t] = true
+	}
+	for t := range sc.equipment {
+		actions[t] = true
+	}
+	for t := range sc.miners {
+		actions[t] = true
+	}
+
+	eager := newScenarioGame(start)
+	eagerLoop := loop.NewService(loop.ServiceDeps{Config: &config.LoopConfig{Shards: 2, TickInterval: time.Second}})
+	eagerLoop.Register("testUserID/testGameID", eager)
+
+	lazy := newScenarioGame(start)
+	lazyLoop := loop.NewService(loop.ServiceDeps{Config: &config.LoopConfig{Shards: 2, TickInterval: time.Second, Lazy: true}})
+	lazyLoop.Register("testUserID/testGameID", lazy)
+
+	const end = start + 150
+	for now := start + 1; now <= end; now++ {
+		eagerLoop.Tick(now)
+		lazyLoop.Tick(now)
+		if now == start+27 {
+			// майнер b закончился: событие должно довести ленивую игру без чтений
+			lazy.Mu.RLock()
+			lastUpdate, expired := lazy.LastUpdateAt, lazy.Miners["b"]
+			lazy.Mu.RUnlock()
+			if lastUpdate != now || expired != nil {
+				t.Fatalf("expected miner expiry to advance lazy game, got last update %d", lastUpdate)
+			}
+		}
+		if !actions[now] {
+			continue
+		}
+		// покупка в ленивом режиме сначала доводит игру до текущей секунды
+		lazy.Advance(now)
+		if eager.Balance != lazy.Balance {
+			t.Fatalf("expected equal balances at %d, eager %d lazy %d", now-start, eager.Balance, lazy.Balance)
+		}
+		sc.apply(eagerLoop, eager, now)
+		sc.apply(lazyLoop, lazy, now)
+	}
+	lazy.Advance(end)
+
+	eagerSaved, lazySaved := eager.CloneForSave(), lazy.CloneForSave()
+	if eagerSaved.Balance != lazySaved.Balance {
+		t.Fatalf("expected equal balances, eager %d lazy %d", eagerSaved.Balance, lazySaved.Balance)
+	}
+	if eagerSaved.IncomePerSec != lazySaved.IncomePerSec || len(eagerSaved.Miners) != len(lazySaved.Miners) {
+		t.Fatalf("expected equal income and miners, eager %d/%d lazy %d/%d",
+			eagerSaved.IncomePerSec, len(eagerSaved.Miners), lazySaved.IncomePerSec, len(lazySaved.Miners))
+	}
+	if ledgerSum(eagerSaved) != ledgerSum(lazySaved) {
+		t.Fatalf("expected equal ledger sums, eager %d lazy %d", ledgerSum(eagerSaved), ledgerSum(lazySaved))
+	}
+}
+
+func ledgerSum(game *domain.GameState) int64 {
+	var sum int64
+	for _, entry := range game.Ledger {
+		sum += entry.Amount
+	}
+	return sum
+}
+
+func TestWorkersTickOnFakeClock(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	loopService := loop.NewService(loop.ServiceDeps{
+		Config: &config.LoopConfig{Shards: 2, TickInterval: time.Second},
+		Clock:  clk,
+	})
+	game := domain.NewGameState("testUserID", "testGameID", 1000)
+	loopService.Register("testUserID/testGameID", game)
+	ticks, cancel := loopService.Subscribe("testUserID/testGameID")
+	defer cancel()
+
+	manager := lifecycle.NewManager(lifecycle.ManagerDeps{Clock: clk, Logger: zerolog.Nop()})
+	for i := 0; i < loopService.Shards(); i++ {
+		manager.Every("loop-"+strconv.Itoa(i), time.Second, func(ctx context.Context) {
+			loopService.TickShard(i, clk.Now().Unix())
+		})
+	}
+	defer manager.StopJobs(context.Background())
+
+	for i := 0; i < 5; i++ {
+		clk.Advance(time.Second)
+		<-ticks
+	}
+	game.Mu.RLock()
+	defer game.Mu.RUnlock()
+	if game.LastUpdateAt != 1005 || game.Balance != 5 {
+		t.Fatalf("expected 5 ticks by 1005, got balance %d at %d", game.Balance, game.LastUpdateAt)
+	}
+}
+
+func TestLazyAdvanceUsesClock(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	loopService := loop.NewService(loop.ServiceDeps{
+		Config: &config.LoopConfig{Shards: 1, TickInterval: time.Second, Lazy: true},
+		Clock:  clk,
+	})
+	game := domain.NewGameState("testUserID", "testGameID", 1000)
+	loopService.Register("testUserID/testGameID", game)
+
+	clk.Advance(42 * time.Second)
+	loopService.Advance(game)
+	if game.LastUpdateAt != 1042 || game.Balance != 42 {
+		t.Fatalf("expected lazy game advanced to 1042, got balance %d at %d", game.Balance, game.LastUpdateAt)
+	}
+}
+
+func TestNonPositiveTickIntervalFallsBack(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		loopService := loop.NewService(loop.ServiceDeps{
+			Config: &config.LoopConfig{Shards: 1, TickInterval: interval},
+		})
+		if loopService.Interval() != time.Second {
+			t.Fatalf("expected fallback to 1s for %v, got %v", interval, loopService.Interval())
+		}
+	}
+}
+
+// BenchmarkRegister - Register и Unregister копируют карту шарда, цена растёт
+// с числом игр в шарде
+func BenchmarkRegister(b *testing.B) {
+	for _, games := range []int{100, 1000, 10000} {
+		b.Run(strconv.Itoa(games), func(b *testing.B) {
+			loopService := newLoopService(1)
+			for i := 0; i < games; i++ {
+				loopService.Register("testUserID/"+strconv.Itoa(i), domain.NewGameState("testUserID", strconv.Itoa(i), 100))
+			}
+			game := domain.NewGameState("testUserID", "bench", 100)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				loopService.Register("testUserID/bench", game)
+				loopService.Unregister("testUserID/bench")
+			}
+		})
+	}
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	loopService := newLoopService(2)
+	id := "testUserID/testGameID"
+	loopService.Register(id, domain.NewGameState("testUserID", "testGameID", 100))
+	ch, cancel := loopService.Subscribe(id)
+
+	loopService.Tick(101)
+	if _, ok := <-ch; !ok {
+		t.Fatalf("expected tick to wake subscriber")
+	}
+	loopService.Notify(id)
+	if _, ok := <-ch; !ok {
+		t.Fatalf("expected notify to wake subscriber")
+	}
+
+	loopService.Unregister(id)
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatalf("expected channel to be closed")
+		}
+	default:
+		t.Fatalf("expected unregister to close subscription")
+	}
+	// отмена после Unregister ничего не делает
+	cancel()
+	loopService.Notify(id)
+}
+
+func TestSubscriptionCancel(t *testing.T) {
+	loopService := newLoopService(1)
+	id := "testUserID/testGameID"
+	loopService.Register(id, domain.NewGameState("testUserID", "testGameID", 100))
+	ch, cancel := loopService.Subscribe(id)
+	other, _ := loopService.Subscribe(id)
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatalf("expected cancelled channel to be closed")
+	}
+	cancel()
+	loopService.Tick(101)
+	if _, ok := <-other; !ok {
+		t.Fatalf("expected other subscriber to keep receiving ticks")
+	}
+}
+
+func TestCloseSubscriptions(t *testing.T) {
+	loopService := newLoopService(2)
+	first, _ := loopService.Subscribe("testUserID/first")
+	second, _ := loopService.Subscribe("testUserID/second")
+
+	loopService.CloseSubscriptions()
+	for _, ch := range []<-chan struct{}{first, second} {
+		if _, ok := <-ch; ok {
+			t.Fatalf("expected subscription to be closed")
+		}
+	}
+	late, cancel := loopService.Subscribe("testUserID/first")
+	if _, ok := <-late; ok {
+		t.Fatalf("expected subscription after close to be closed")
+	}
+	cancel()
+}
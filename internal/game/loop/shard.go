@@ -0,0 +1,97 @@
+package loop
+
+import (
+	"container/heap"
+	"hash/fnv"
+	"miners_game/internal/game/domain"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// shard - часть игр со своим воркером. Карта игр неизменяемая: Register и
+// Unregister под writeMu собирают новую и подменяют указатель, тик читает
+// снимок без блокировок и никого не задерживает. Цена - копия карты шарда
+// на каждую запись, O(игр в шарде): по BenchmarkRegister около 0.1 мс на
+// 1000 игр и 1 мс на 10000. Если загрузка и выгрузка игр упрутся в это,
+// шарды мельчат через LOOP_SHARDS.
+// events - очередь ленивого режима
+type shard struct {
+	games    atomic.Pointer[map[string]*domain.GameState]
+	writeMu  sync.Mutex
+	events   eventHeap
+	eventsMu sync.Mutex
+}
+
+func newShard() *shard {
+	sh := &shard{}
+	games := make(map[string]*domain.GameState)
+	sh.games.Store(&games)
+	return sh
+}
+
+func (sh *shard) snapshot() map[string]*domain.GameState {
+	return *sh.games.Load()
+}
+
+func (sh *shard) update(fn func(games map[string]*domain.GameState)) {
+	sh.writeMu.Lock()
+	defer sh.writeMu.Unlock()
+	current := sh.snapshot()
+	next := make(map[string]*domain.GameState, len(current)+1)
+	for id, game := range current {
+		next[id] = game
+	}
+	fn(next)
+	sh.games.Store(&next)
+}
+
+func shardIndex(id string, count int) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % uint32(count))
+}
+
+// event - ленивый режим: игру нужно довести до due (окончание майнера)
+type event struct {
+	due int64
+	id  string
+}
+
+type eventHeap []event
+
+func (h eventHeap) Len() int           { return len(h) }
+func (h eventHeap) Less(i, j int) bool { return h[i].due < h[j].due }
+func (h eventHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *eventHeap) Push(x any)        { *h = append(*h, x.(event)) }
+func (h *eventHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// schedule - повторы одной игры допустимы: лишнее событие только доведёт
+// её до того же момента ещё раз
+func (sh *shard) schedule(id string, due int64) {
+	if due == 0 {
+		return
+	}
+	sh.eventsMu.Lock()
+	defer sh.eventsMu.Unlock()
+	heap.Push(&sh.events, event{due: due, id: id})
+}
+
+// due - id игр с наступившими событиями, без повторов
+func (sh *shard) due(now int64) []string {
+	sh.eventsMu.Lock()
+	defer sh.eventsMu.Unlock()
+	ids := []string{}
+	for sh.events.Len() > 0 && sh.events[0].due <= now {
+		e := heap.Pop(&sh.events).(event)
+		if !slices.Contains(ids, e.id) {
+			ids = append(ids, e.id)
+		}
+	}
+	return ids
+}
@@ -3,9 +3,16 @@ package game
 import "github.com/prometheus/client_golang/prometheus"
 
 type Metrics struct {
-	BuyAttemptsTotal prometheus.Counter
-	BuySuccessTotal  prometheus.Counter
-	BuyFailedTotal   prometheus.Counter
+	BuyAttemptsTotal   prometheus.Counter
+	BuySuccessTotal    prometheus.Counter
+	BuyFailedTotal     prometheus.Counter
+	HudStreams         prometheus.Gauge
+	DirtyGames         prometheus.Gauge
+	SavedGamesTotal    prometheus.Counter
+	SaveRetriesTotal   prometheus.Counter
+	SaveFailedTotal    prometheus.Counter
+	SaveConflictsTotal prometheus.Counter
+	SaveDuration       prometheus.Histogram
 }
 
 func NewMetrics(reg prometheus.Registerer) *Metrics {
@@ -22,8 +29,39 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 			Name: "buy_failed_total",
 			Help: "Total buy failed",
 		}),
+		HudStreams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "hud_streams",
+			Help: "Open HUD SSE connections",
+		}),
+		DirtyGames: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "game_dirty_games",
+			Help: "Games changed since last save at the last flush",
+		}),
+		SavedGamesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "game_saved_total",
+			Help: "Total games saved by flush",
+		}),
+		SaveRetriesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "game_save_retries_total",
+			Help: "Total single game save retries",
+		}),
+		SaveFailedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "game_save_failed_total",
+			Help: "Total games not saved after retries",
+		}),
+		SaveConflictsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "game_save_conflicts_total",
+			Help: "Total stale games evicted after version conflict",
+		}),
+		SaveDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "game_save_duration_seconds",
+			Help: "Duration of dirty games flush",
+		}),
 	}
-	reg.MustRegister(m.BuyAttemptsTotal, m.BuySuccessTotal, m.BuyFailedTotal)
+	reg.MustRegister(
+		m.BuyAttemptsTotal, m.BuySuccessTotal, m.BuyFailedTotal, m.HudStreams,
+		m.DirtyGames, m.SavedGamesTotal, m.SaveRetriesTotal, m.SaveFailedTotal, m.SaveConflictsTotal, m.SaveDuration,
+	)
 
 	return m
 }
@@ -0,0 +1,10 @@
+package game
+
+// SaveSlot - строка списка сохранений без разбора состояния игры
+type SaveSlot struct {
+	GameID       string
+	Name         string
+	Balance      int64
+	LastUpdateAt int64
+	CreatedAt    int64
+}
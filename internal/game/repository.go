@@ -2,12 +2,13 @@ package game
 
 import (
 	"context"
-	"encoding/json"
 	"miners_game/internal/game/domain"
 	"miners_game/internal/game/equipments"
 	"miners_game/internal/game/upgrades"
 	"miners_game/internal/miners"
+	"miners_game/pkg/database"
 	"miners_game/pkg/errs"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -15,110 +16,458 @@ import (
 )
 
 type Repository struct {
-	dbPool *pgxpool.Pool
-	logger zerolog.Logger
+	dbPool  *pgxpool.Pool
+	timeout time.Duration
+	logger  zerolog.Logger
 }
 
 type RepositoryDeps struct {
-	DbPool *pgxpool.Pool
-	Logger zerolog.Logger
+	DbPool  *pgxpool.Pool
+	Timeout time.Duration
+	Logger  zerolog.Logger
 }
 
 func NewRepository(deps RepositoryDeps) *Repository {
 	return &Repository{
-		dbPool: deps.DbPool,
-		logger: deps.Logger,
+		dbPool:  deps.DbPool,
+		timeout: deps.Timeout,
+		logger:  deps.Logger,
 	}
 }
 
-func (r *Repository) Save(gameState *domain.GameState) error {
-	minersJSON, err := json.Marshal(gameState.Miners)
-	if err != nil {
-		r.logger.Error().Err(err).Msg("failed to marshal miners")
-		return errs.ErrServer
+const (
+	itemKindEquipment = "equipment"
+	itemKindUpgrade   = "upgrade"
+)
+
+// saveQuery - обновление только при совпадении версии. Если строку уже
+// переписал другой процесс, запрос не вернёт ни одной строки
+const saveQuery = `
+			INSERT INTO games (user_id, game_id, name, created_at, balance, income, last_update_at, version)
+			VAlUES (@user_id, @game_id, @name, @created_at, @balance, @income, @last_update_at, 1)
+			ON CONFLICT (user_id, game_id) DO UPDATE SET name = EXCLUDED.name, balance = EXCLUDED.balance, income = EXCLUDED.income, last_update_at = EXCLUDED.last_update_at, version = games.version + 1
+			WHERE games.version = @version
+			RETURNING version`
+
+const (
+	deleteMinersQuery = `DELETE FROM game_miners WHERE user_id = @user_id AND game_id = @game_id`
+	deleteItemsQuery  = `DELETE FROM game_items WHERE user_id = @user_id AND game_id = @game_id`
+	insertMinersQuery = `
+			INSERT INTO game_miners (user_id, game_id, miner_key, miner_id, class, start_at, end_at)
+			SELECT @user_id, @game_id, m.miner_key, m.miner_id, m.class, m.start_at, m.end_at
+			FROM unnest(@miner_keys::text[], @miner_ids::text[], @classes::text[], @start_at::bigint[], @end_at::bigint[])
+				AS m (miner_key, miner_id, class, start_at, end_at)`
+	insertItemsQuery = `
+			INSERT INTO game_items (user_id, game_id, kind, name, own, position)
+			SELECT @user_id, @game_id, i.kind, i.name, i.own, i.position
+			FROM unnest(@kinds::text[], @names::text[], @owns::bool[], @positions::int[])
+				AS i (kind, name, own, position)`
+	insertLedgerQuery = `
+			INSERT INTO game_ledger (user_id, game_id, amount, reason, item, balance_after, created_at)
+			SELECT @user_id, @game_id, l.amount, l.reason, l.item, l.balance_after, l.created_at
+			FROM unnest(@amounts::bigint[], @reasons::text[], @items::text[], @balances::bigint[], @created_at::bigint[]) WITH ORDINALITY
+				AS l (amount, reason, item, balance_after, created_at, n)
+			ORDER BY l.n`
+)
+
+// Save - условное сохранение по gameState.Version. При успехе Version
+// переданного состояния получает новую версию, при гонке - errs.ErrSaveConflict
+func (r *Repository) Save(ctx context.Context, gameState *domain.GameState) error {
+	return r.SaveBatch(ctx, []*domain.GameState{gameState})[0]
+}
+
+// SaveBatch - сохранение игр в одной транзакции. Сначала одним пакетом
+// обновляются строки games с проверкой версии, затем дочерние таблицы
+// переписываются только у игр без конфликта. Ошибки возвращаются по каждой
+// игре в порядке входного среза; ошибка базы откатывает весь пакет,
+// вызывающий повторяет такие игры поштучно
+func (r *Repository) SaveBatch(ctx context.Context, games []*domain.GameState) []error {
+	results := make([]error, len(games))
+	fail := func(err error) []error {
+		for i := range results {
+			if results[i] == nil {
+				results[i] = err
+			}
+		}
+		return results
 	}
-	equipmentsJSON, err := json.Marshal(gameState.Equipments)
+
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	tx, err := r.dbPool.Begin(ctx)
 	if err != nil {
-		r.logger.Error().Err(err).Msg("failed to marshal equipments")
-		return errs.ErrServer
+		r.logger.Error().Err(err).Int("count", len(games)).Msg("failed to begin save transaction")
+		return fail(errs.ErrServer)
+	}
+	defer tx.Rollback(ctx)
+
+	versions := make([]int64, len(games))
+	batch := &pgx.Batch{}
+	for _, game := range games {
+		batch.Queue(saveQuery, gameArgs(game))
 	}
-	upgradesJSON, err := json.Marshal(gameState.Upgrades)
+	br := tx.SendBatch(ctx, batch)
+	for i, game := range games {
+		if err := br.QueryRow().Scan(&versions[i]); err != nil {
+			if err == pgx.ErrNoRows {
+				r.logger.Warn().Str("user_id", game.UserID).Str("game_id", game.GameID).Int64("version", game.Version).Msg("game state was saved by another process")
+				results[i] = errs.ErrSaveConflict
+				continue
+			}
+			r.logger.Error().Err(err).Str("user_id", game.UserID).Str("game_id", game.GameID).Msg("failed to save game state")
+			br.Close()
+			return fail(errs.ErrServer)
+		}
+	}
+	if err := br.Close(); err != nil {
+		r.logger.Error().Err(err).Int("count", len(games)).Msg("failed to save games")
+		return fail(errs.ErrServer)
+	}
+
+	batch = &pgx.Batch{}
+	for i, game := range games {
+		if results[i] != nil {
+			continue
+		}
+		args := gameArgs(game)
+		batch.Queue(deleteMinersQuery, args)
+		batch.Queue(deleteItemsQuery, args)
+		batch.Queue(insertMinersQuery, minerArgs(game))
+		batch.Queue(insertItemsQuery, itemArgs(game))
+		if len(game.Ledger) > 0 {
+			batch.Queue(insertLedgerQuery, ledgerArgs(game))
+		}
+	}
+	if batch.Len() > 0 {
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			r.logger.Error().Err(err).Int("count", len(games)).Msg("failed to save miners and items")
+			return fail(errs.ErrServer)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error().Err(err).Int("count", len(games)).Msg("failed to commit games")
+		return fail(errs.ErrServer)
+	}
+
+	for i, game := range games {
+		if results[i] == nil {
+			game.Version = versions[i]
+		}
+	}
+	return results
+}
+
+// InsertGame - вставка новой игры с дочерними строками и журналом в транзакции
+// вызывающего, для переноса данных из других таблиц. false - игра с таким id
+// уже есть, транзакцию нужно откатить
+func InsertGame(ctx context.Context, tx pgx.Tx, gameState *domain.GameState) (bool, error) {
+	tag, err := tx.Exec(ctx, `
+			INSERT INTO games (user_id, game_id, name, created_at, balance, income, last_update_at, version)
+			VALUES (@user_id, @game_id, @name, @created_at, @balance, @income, @last_update_at, 1)
+			ON CONFLICT (user_id, game_id) DO NOTHING`, gameArgs(gameState))
 	if err != nil {
-		r.logger.Error().Err(err).Msg("failed to marshal upgrades")
-		return errs.ErrServer
+		return false, err
 	}
-	query := `
-			INSERT INTO games (user_id, game_id, balance, income, last_update_at, miners, equipments, upgrades)
-			VAlUES (@user_id, @game_id, @balance, @income, @last_update_at, @miners, @equipments, @upgrades)
-			ON CONFLICT (user_id, game_id) DO UPDATE SET balance = EXCLUDED.balance, income = EXCLUDED.income, last_update_at = EXCLUDED.last_update_at, miners = EXCLUDED.miners, equipments = EXCLUDED.equipments, upgrades = EXCLUDED.upgrades`
-	args := pgx.NamedArgs{
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	batch := &pgx.Batch{}
+	batch.Queue(insertMinersQuery, minerArgs(gameState))
+	batch.Queue(insertItemsQuery, itemArgs(gameState))
+	if len(gameState.Ledger) > 0 {
+		batch.Queue(insertLedgerQuery, ledgerArgs(gameState))
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return false, err
+	}
+	gameState.Version = 1
+	return true, nil
+}
+
+func gameArgs(gameState *domain.GameState) pgx.NamedArgs {
+	return pgx.NamedArgs{
 		"user_id":        gameState.UserID,
 		"game_id":        gameState.GameID,
+		"name":           gameState.Name,
+		"created_at":     gameState.CreatedAt,
 		"balance":        gameState.Balance,
 		"income":         gameState.IncomePerSec,
 		"last_update_at": gameState.LastUpdateAt,
-		"miners":         minersJSON,
-		"equipments":     equipmentsJSON,
-		"upgrades":       upgradesJSON,
+		"version":        gameState.Version,
+	}
+}
+
+func minerArgs(gameState *domain.GameState) pgx.NamedArgs {
+	n := len(gameState.Miners)
+	keys := make([]string, 0, n)
+	ids := make([]string, 0, n)
+	classes := make([]string, 0, n)
+	startAt := make([]int64, 0, n)
+	endAt := make([]int64, 0, n)
+	for key, miner := range gameState.Miners {
+		keys = append(keys, key)
+		ids = append(ids, miner.ID)
+		classes = append(classes, miner.Class)
+		startAt = append(startAt, miner.StartAt)
+		endAt = append(endAt, miner.EndAt)
+	}
+	return pgx.NamedArgs{
+		"user_id":    gameState.UserID,
+		"game_id":    gameState.GameID,
+		"miner_keys": keys,
+		"miner_ids":  ids,
+		"classes":    classes,
+		"start_at":   startAt,
+		"end_at":     endAt,
+	}
+}
+
+// itemArgs - снаряжение и улучшения в одной таблице, position хранит порядок в срезе
+func itemArgs(gameState *domain.GameState) pgx.NamedArgs {
+	n := len(gameState.Equipments) + len(gameState.Upgrades)
+	kinds := make([]string, 0, n)
+	names := make([]string, 0, n)
+	owns := make([]bool, 0, n)
+	positions := make([]int32, 0, n)
+	for i, e := range gameState.Equipments {
+		kinds = append(kinds, itemKindEquipment)
+		names = append(names, e.Name)
+		owns = append(owns, e.Own)
+		positions = append(positions, int32(i))
+	}
+	for i, u := range gameState.Upgrades {
+		kinds = append(kinds, itemKindUpgrade)
+		names = append(names, u.Name)
+		owns = append(owns, u.Own)
+		positions = append(positions, int32(i))
+	}
+	return pgx.NamedArgs{
+		"user_id":   gameState.UserID,
+		"game_id":   gameState.GameID,
+		"kinds":     kinds,
+		"names":     names,
+		"owns":      owns,
+		"positions": positions,
+	}
+}
+
+// ledgerArgs - записи журнала пишутся в той же транзакции, что и баланс,
+// поэтому сумма журнала в базе всегда совпадает с сохранённым балансом
+func ledgerArgs(gameState *domain.GameState) pgx.NamedArgs {
+	n := len(gameState.Ledger)
+	amounts := make([]int64, 0, n)
+	reasons := make([]string, 0, n)
+	items := make([]string, 0, n)
+	balances := make([]int64, 0, n)
+	createdAt := make([]int64, 0, n)
+	for _, entry := range gameState.Ledger {
+		amounts = append(amounts, entry.Amount)
+		reasons = append(reasons, entry.Reason)
+		items = append(items, entry.Item)
+		balances = append(balances, entry.BalanceAfter)
+		createdAt = append(createdAt, entry.At)
+	}
+	return pgx.NamedArgs{
+		"user_id":    gameState.UserID,
+		"game_id":    gameState.GameID,
+		"amounts":    amounts,
+		"reasons":    reasons,
+		"items":      items,
+		"balances":   balances,
+		"created_at": createdAt,
 	}
-	if _, err = r.dbPool.Exec(context.Background(), query, args); err != nil {
-		r.logger.Error().Err(err).Str("user_id", gameState.UserID).Str("game_id", gameState.GameID).Msg("failed to save game state")
+}
+
+const (
+	loadFilter       = `user_id = @user_id AND game_id = @game_id`
+	loadLatestFilter = `user_id = @user_id AND game_id = (
+			SELECT game_id FROM games WHERE user_id = @user_id ORDER BY last_update_at DESC LIMIT 1
+		)`
+)
+
+func (r *Repository) Load(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
+	return r.load(ctx, loadFilter, pgx.NamedArgs{
+		"user_id": userID,
+		"game_id": gameID,
+	}, userID)
+}
+
+// LoadLatest - последняя по времени игра пользователя
+func (r *Repository) LoadLatest(ctx context.Context, userID string) (*domain.GameState, error) {
+	return r.load(ctx, loadLatestFilter, pgx.NamedArgs{
+		"user_id": userID,
+	}, userID)
+}
+
+// ListByUser - слоты сохранений пользователя в порядке создания
+func (r *Repository) ListByUser(ctx context.Context, userID string) ([]SaveSlot, error) {
+	query := `
+		SELECT game_id, name, balance, last_update_at, created_at
+		FROM games
+		WHERE user_id = @user_id
+		ORDER BY created_at, game_id
+	`
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	rows, err := r.dbPool.Query(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to list games")
+		return nil, errs.ErrServer
+	}
+	defer rows.Close()
+
+	slots := []SaveSlot{}
+	for rows.Next() {
+		var slot SaveSlot
+		if err := rows.Scan(&slot.GameID, &slot.Name, &slot.Balance, &slot.LastUpdateAt, &slot.CreatedAt); err != nil {
+			r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to scan game slot")
+			return nil, errs.ErrServer
+		}
+		slots = append(slots, slot)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to list games")
+		return nil, errs.ErrServer
+	}
+	return slots, nil
+}
+
+func (r *Repository) Rename(ctx context.Context, userID, gameID, name string) error {
+	query := `
+		UPDATE games SET name = @name
+		WHERE user_id = @user_id AND game_id = @game_id
+	`
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	tag, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+		"game_id": gameID,
+		"name":    name,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to rename game")
 		return errs.ErrServer
 	}
+	if tag.RowsAffected() == 0 {
+		return errs.ErrGameNotFound
+	}
 	return nil
 }
 
-func (r *Repository) Load(userID, gameID string) (*domain.GameState, error) {
+func (r *Repository) Delete(ctx context.Context, userID, gameID string) error {
 	query := `
-		SELECT balance, income, last_update_at, miners, equipments, upgrades
-		FROM games
+		DELETE FROM games
 		WHERE user_id = @user_id AND game_id = @game_id
 	`
-	rows := r.dbPool.QueryRow(context.Background(), query, pgx.NamedArgs{
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	tag, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
 		"user_id": userID,
 		"game_id": gameID,
 	})
-	var balance int64
-	var income int64
-	var lastUpdateAt int64
-	var minersJSON []byte
-	var equipmentsJSON []byte
-	var upgradesJSON []byte
-
-	if err := rows.Scan(&balance, &income, &lastUpdateAt, &minersJSON, &equipmentsJSON, &upgradesJSON); err != nil {
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to delete game")
+		return errs.ErrServer
+	}
+	if tag.RowsAffected() == 0 {
+		return errs.ErrGameNotFound
+	}
+	return nil
+}
+
+// load - игра и дочерние строки одним пакетом: BEGIN и COMMIT идут в том же
+// пакете, поэтому чтение видит согласованный снимок за один сетевой обмен
+func (r *Repository) load(ctx context.Context, filter string, args pgx.NamedArgs, userID string) (*domain.GameState, error) {
+	batch := &pgx.Batch{}
+	batch.Queue(`BEGIN ISOLATION LEVEL REPEATABLE READ READ ONLY`)
+	batch.Queue(`
+		SELECT game_id, name, created_at, balance, income, last_update_at, version
+		FROM games
+		WHERE `+filter, args)
+	batch.Queue(`
+		SELECT miner_key, miner_id, class, start_at, end_at
+		FROM game_miners
+		WHERE `+filter, args)
+	batch.Queue(`
+		SELECT kind, name, own
+		FROM game_items
+		WHERE `+filter+`
+		ORDER BY kind, position`, args)
+	batch.Queue(`COMMIT`)
+
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	br := r.dbPool.SendBatch(ctx, batch)
+	defer br.Close()
+
+	if _, err := br.Exec(); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to begin load transaction")
+		return nil, errs.ErrServer
+	}
+	gs := &domain.GameState{
+		UserID: userID,
+		Miners: make(map[string]*miners.Miner),
+	}
+	if err := br.QueryRow().Scan(&gs.GameID, &gs.Name, &gs.CreatedAt, &gs.Balance, &gs.IncomePerSec, &gs.LastUpdateAt, &gs.Version); err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, errs.ErrGameNotFound
 		}
-		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to load game state")
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to load game state")
 		return nil, errs.ErrServer
 	}
 
-	var miners map[string]*miners.Miner
-	var equipments []equipments.Equipment
-	var upgrades []upgrades.Upgrade
-	if err := json.Unmarshal(minersJSON, &miners); err != nil {
-		r.logger.Error().Err(err).Msg("failed to unmarshal miners")
+	rows, err := br.Query()
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gs.GameID).Msg("failed to load miners")
 		return nil, errs.ErrServer
 	}
-	if err := json.Unmarshal(equipmentsJSON, &equipments); err != nil {
-		r.logger.Error().Err(err).Msg("failed to unmarshal equipments")
-		return nil, errs.ErrServer
+	for rows.Next() {
+		var key string
+		miner := &miners.Miner{}
+		if err := rows.Scan(&key, &miner.ID, &miner.Class, &miner.StartAt, &miner.EndAt); err != nil {
+			rows.Close()
+			r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gs.GameID).Msg("failed to scan miner")
+			return nil, errs.ErrServer
+		}
+		gs.Miners[key] = miner
 	}
-	if err := json.Unmarshal(upgradesJSON, &upgrades); err != nil {
-		r.logger.Error().Err(err).Msg("failed to unmarshal upgrades")
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gs.GameID).Msg("failed to load miners")
 		return nil, errs.ErrServer
 	}
 
-	gs := &domain.GameState{
-		UserID:       userID,
-		GameID:       gameID,
-		Balance:      balance,
-		IncomePerSec: income,
-		LastUpdateAt: lastUpdateAt,
-		Miners:       miners,
-		Equipments:   equipments,
-		Upgrades:     upgrades,
+	rows, err = br.Query()
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gs.GameID).Msg("failed to load items")
+		return nil, errs.ErrServer
+	}
+	for rows.Next() {
+		var kind, name string
+		var own bool
+		if err := rows.Scan(&kind, &name, &own); err != nil {
+			rows.Close()
+			r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gs.GameID).Msg("failed to scan item")
+			return nil, errs.ErrServer
+		}
+		switch kind {
+		case itemKindEquipment:
+			gs.Equipments = append(gs.Equipments, equipments.Equipment{Name: name, Own: own})
+		case itemKindUpgrade:
+			gs.Upgrades = append(gs.Upgrades, upgrades.Upgrade{Name: name, Own: own})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gs.GameID).Msg("failed to load items")
+		return nil, errs.ErrServer
 	}
 
+	if _, err := br.Exec(); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gs.GameID).Msg("failed to commit load transaction")
+		return nil, errs.ErrServer
+	}
 	return gs, nil
 }
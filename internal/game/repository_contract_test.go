@@ -0,0 +1,56 @@
+package game_test
+
+import (
+	"context"
+	"miners_game/internal/game"
+	"miners_game/internal/game/gametest"
+	"miners_game/migrations"
+	"miners_game/pkg/migrator"
+	"miners_game/pkg/sqlite"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	gametest.RunRepositoryContract(t, func(t *testing.T) game.IGameRepository {
+		return game.NewMemoryRepository()
+	})
+}
+
+func TestSQLiteRepositoryContract(t *testing.T) {
+	gametest.RunRepositoryContract(t, func(t *testing.T) game.IGameRepository {
+		db, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return game.NewSQLiteRepository(game.SQLiteRepositoryDeps{DB: db, Logger: zerolog.Nop()})
+	})
+}
+
+// TestPostgresRepositoryContract - запускается при TEST_DATABASE_URL, миграции применяются к этой базе
+func TestPostgresRepositoryContract(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	dbPool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	defer dbPool.Close()
+	m, err := migrator.NewMigrator(migrator.MigratorDeps{DbPool: dbPool, FS: migrations.FS, Logger: zerolog.Nop()})
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("expected migrations to apply, got %v:", err)
+	}
+	gametest.RunRepositoryContract(t, func(t *testing.T) game.IGameRepository {
+		return game.NewRepository(game.RepositoryDeps{DbPool: dbPool, Logger: zerolog.Nop()})
+	})
+}
@@ -0,0 +1,140 @@
+package game
+
+import (
+	"context"
+	"miners_game/internal/game/domain"
+	"miners_game/pkg/errs"
+	"sort"
+	"sync"
+)
+
+// MemoryRepository - хранилище игр в памяти процесса для локального запуска
+// и тестов. Поведение совпадает с Repository, включая проверку версии
+type MemoryRepository struct {
+	games map[string]*domain.GameState
+	mu    sync.RWMutex
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		games: make(map[string]*domain.GameState),
+	}
+}
+
+func (r *MemoryRepository) Load(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stored, ok := r.games[userID+"/"+gameID]
+	if !ok {
+		return nil, errs.ErrGameNotFound
+	}
+	return loadedCopy(stored), nil
+}
+
+// LoadLatest - последняя по времени игра пользователя
+func (r *MemoryRepository) LoadLatest(ctx context.Context, userID string) (*domain.GameState, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var latest *domain.GameState
+	for _, stored := range r.games {
+		if stored.UserID != userID {
+			continue
+		}
+		if latest == nil || stored.LastUpdateAt > latest.LastUpdateAt {
+			latest = stored
+		}
+	}
+	if latest == nil {
+		return nil, errs.ErrGameNotFound
+	}
+	return loadedCopy(latest), nil
+}
+
+func (r *MemoryRepository) Save(ctx context.Context, gameState *domain.GameState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save(gameState)
+}
+
+func (r *MemoryRepository) SaveBatch(ctx context.Context, games []*domain.GameState) []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	results := make([]error, len(games))
+	for i, game := range games {
+		results[i] = r.save(game)
+	}
+	return results
+}
+
+// save - вызывается под блокировкой. Дата создания, как и в Postgres,
+// пишется только при создании игры
+func (r *MemoryRepository) save(gameState *domain.GameState) error {
+	id := gameState.UserID + "/" + gameState.GameID
+	saved := gameState.Clone()
+	saved.Version = 1
+	if stored, ok := r.games[id]; ok {
+		if stored.Version != gameState.Version {
+			return errs.ErrSaveConflict
+		}
+		saved.CreatedAt = stored.CreatedAt
+		saved.Version = stored.Version + 1
+	}
+	r.games[id] = saved
+	gameState.Version = saved.Version
+	return nil
+}
+
+func (r *MemoryRepository) ListByUser(ctx context.Context, userID string) ([]SaveSlot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	slots := []SaveSlot{}
+	for _, stored := range r.games {
+		if stored.UserID != userID {
+			continue
+		}
+		slots = append(slots, SaveSlot{
+			GameID:       stored.GameID,
+			Name:         stored.Name,
+			Balance:      stored.Balance,
+			LastUpdateAt: stored.LastUpdateAt,
+			CreatedAt:    stored.CreatedAt,
+		})
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		if slots[i].CreatedAt != slots[j].CreatedAt {
+			return slots[i].CreatedAt < slots[j].CreatedAt
+		}
+		return slots[i].GameID < slots[j].GameID
+	})
+	return slots, nil
+}
+
+func (r *MemoryRepository) Rename(ctx context.Context, userID, gameID, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.games[userID+"/"+gameID]
+	if !ok {
+		return errs.ErrGameNotFound
+	}
+	stored.Name = name
+	return nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, userID, gameID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := userID + "/" + gameID
+	if _, ok := r.games[id]; !ok {
+		return errs.ErrGameNotFound
+	}
+	delete(r.games, id)
+	return nil
+}
+
+// loadedCopy - копия хранимой игры в том виде, в каком её вернула бы загрузка из базы
+func loadedCopy(stored *domain.GameState) *domain.GameState {
+	game := stored.Clone()
+	game.Revision = 0
+	game.SavedRevision = 0
+	return game
+}
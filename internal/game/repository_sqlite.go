@@ -0,0 +1,303 @@
+package game
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/game/equipments"
+	"miners_game/internal/game/upgrades"
+	"miners_game/internal/miners"
+	"miners_game/pkg/database"
+	"miners_game/pkg/errs"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// SQLiteRepository - хранилище игр в файле SQLite для локального запуска.
+// Схема и поведение совпадают с Repository
+type SQLiteRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+	logger  zerolog.Logger
+}
+
+type SQLiteRepositoryDeps struct {
+	DB      *sql.DB
+	Timeout time.Duration
+	Logger  zerolog.Logger
+}
+
+func NewSQLiteRepository(deps SQLiteRepositoryDeps) *SQLiteRepository {
+	return &SQLiteRepository{
+		db:      deps.DB,
+		timeout: deps.Timeout,
+		logger:  deps.Logger,
+	}
+}
+
+func (r *SQLiteRepository) Load(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
+	return r.load(ctx, userID, gameID)
+}
+
+// LoadLatest - последняя по времени игра пользователя
+func (r *SQLiteRepository) LoadLatest(ctx context.Context, userID string) (*domain.GameState, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	var gameID string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT game_id FROM games WHERE user_id = ? ORDER BY last_update_at DESC LIMIT 1`, userID).Scan(&gameID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrGameNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to load latest game")
+		return nil, errs.ErrServer
+	}
+	return r.load(ctx, userID, gameID)
+}
+
+func (r *SQLiteRepository) load(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to begin load transaction")
+		return nil, errs.ErrServer
+	}
+	defer tx.Rollback()
+
+	gs := &domain.GameState{
+		UserID: userID,
+		Miners: make(map[string]*miners.Miner),
+	}
+	err = tx.QueryRowContext(ctx, `
+		SELECT game_id, name, created_at, balance, income, last_update_at, version
+		FROM games WHERE user_id = ? AND game_id = ?`, userID, gameID).
+		Scan(&gs.GameID, &gs.Name, &gs.CreatedAt, &gs.Balance, &gs.IncomePerSec, &gs.LastUpdateAt, &gs.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrGameNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to load game state")
+		return nil, errs.ErrServer
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT miner_key, miner_id, class, start_at, end_at
+		FROM game_miners WHERE user_id = ? AND game_id = ?`, userID, gameID)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to load miners")
+		return nil, errs.ErrServer
+	}
+	for rows.Next() {
+		var key string
+		miner := &miners.Miner{}
+		if err := rows.Scan(&key, &miner.ID, &miner.Class, &miner.StartAt, &miner.EndAt); err != nil {
+			rows.Close()
+			r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to scan miner")
+			return nil, errs.ErrServer
+		}
+		gs.Miners[key] = miner
+	}
+	rows.Close()
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT kind, name, own
+		FROM game_items WHERE user_id = ? AND game_id = ?
+		ORDER BY kind, position`, userID, gameID)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to load items")
+		return nil, errs.ErrServer
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind, name string
+		var own bool
+		if err := rows.Scan(&kind, &name, &own); err != nil {
+			r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to scan item")
+			return nil, errs.ErrServer
+		}
+		switch kind {
+		case itemKindEquipment:
+			gs.Equipments = append(gs.Equipments, equipments.Equipment{Name: name, Own: own})
+		case itemKindUpgrade:
+			gs.Upgrades = append(gs.Upgrades, upgrades.Upgrade{Name: name, Own: own})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to load items")
+		return nil, errs.ErrServer
+	}
+	return gs, nil
+}
+
+func (r *SQLiteRepository) Save(ctx context.Context, gameState *domain.GameState) error {
+	return r.SaveBatch(ctx, []*domain.GameState{gameState})[0]
+}
+
+// SaveBatch - все игры в одной транзакции. Конфликт версии не прерывает
+// транзакцию, ошибка базы откатывает весь пакет
+func (r *SQLiteRepository) SaveBatch(ctx context.Context, games []*domain.GameState) []error {
+	results := make([]error, len(games))
+	fail := func(err error) []error {
+		for i := range results {
+			if results[i] == nil {
+				results[i] = err
+			}
+		}
+		return results
+	}
+
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Int("count", len(games)).Msg("failed to begin save transaction")
+		return fail(errs.ErrServer)
+	}
+	defer tx.Rollback()
+
+	versions := make([]int64, len(games))
+	for i, game := range games {
+		versions[i], err = r.saveGame(ctx, tx, game)
+		if errors.Is(err, errs.ErrSaveConflict) {
+			results[i] = err
+			continue
+		}
+		if err != nil {
+			r.logger.Error().Err(err).Str("user_id", game.UserID).Str("game_id", game.GameID).Msg("failed to save game state")
+			return fail(errs.ErrServer)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Int("count", len(games)).Msg("failed to commit games")
+		return fail(errs.ErrServer)
+	}
+	for i, game := range games {
+		if results[i] == nil {
+			game.Version = versions[i]
+		}
+	}
+	return results
+}
+
+func (r *SQLiteRepository) saveGame(ctx context.Context, tx *sql.Tx, game *domain.GameState) (int64, error) {
+	var version int64
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO games (user_id, game_id, name, created_at, balance, income, last_update_at, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT (user_id, game_id) DO UPDATE SET name = excluded.name, balance = excluded.balance, income = excluded.income, last_update_at = excluded.last_update_at, version = games.version + 1
+		WHERE games.version = ?
+		RETURNING version`,
+		game.UserID, game.GameID, game.Name, game.CreatedAt, game.Balance, game.IncomePerSec, game.LastUpdateAt, game.Version,
+	).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		r.logger.Warn().Str("user_id", game.UserID).Str("game_id", game.GameID).Int64("version", game.Version).Msg("game state was saved by another process")
+		return 0, errs.ErrSaveConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM game_miners WHERE user_id = ? AND game_id = ?`, game.UserID, game.GameID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM game_items WHERE user_id = ? AND game_id = ?`, game.UserID, game.GameID); err != nil {
+		return 0, err
+	}
+	for key, miner := range game.Miners {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO game_miners (user_id, game_id, miner_key, miner_id, class, start_at, end_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			game.UserID, game.GameID, key, miner.ID, miner.Class, miner.StartAt, miner.EndAt); err != nil {
+			return 0, err
+		}
+	}
+	for i, e := range game.Equipments {
+		if err := insertItem(ctx, tx, game, itemKindEquipment, e.Name, e.Own, i); err != nil {
+			return 0, err
+		}
+	}
+	for i, u := range game.Upgrades {
+		if err := insertItem(ctx, tx, game, itemKindUpgrade, u.Name, u.Own, i); err != nil {
+			return 0, err
+		}
+	}
+	for _, entry := range game.Ledger {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO game_ledger (user_id, game_id, amount, reason, item, balance_after, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			game.UserID, game.GameID, entry.Amount, entry.Reason, entry.Item, entry.BalanceAfter, entry.At); err != nil {
+			return 0, err
+		}
+	}
+	return version, nil
+}
+
+func insertItem(ctx context.Context, tx *sql.Tx, game *domain.GameState, kind, name string, own bool, position int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO game_items (user_id, game_id, kind, name, own, position)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		game.UserID, game.GameID, kind, name, own, position)
+	return err
+}
+
+// ListByUser - слоты сохранений пользователя в порядке создания
+func (r *SQLiteRepository) ListByUser(ctx context.Context, userID string) ([]SaveSlot, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT game_id, name, balance, last_update_at, created_at
+		FROM games WHERE user_id = ?
+		ORDER BY created_at, game_id`, userID)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to list games")
+		return nil, errs.ErrServer
+	}
+	defer rows.Close()
+
+	slots := []SaveSlot{}
+	for rows.Next() {
+		var slot SaveSlot
+		if err := rows.Scan(&slot.GameID, &slot.Name, &slot.Balance, &slot.LastUpdateAt, &slot.CreatedAt); err != nil {
+			r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to scan game slot")
+			return nil, errs.ErrServer
+		}
+		slots = append(slots, slot)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to list games")
+		return nil, errs.ErrServer
+	}
+	return slots, nil
+}
+
+func (r *SQLiteRepository) Rename(ctx context.Context, userID, gameID, name string) error {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	res, err := r.db.ExecContext(ctx, `UPDATE games SET name = ? WHERE user_id = ? AND game_id = ?`, name, userID, gameID)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to rename game")
+		return errs.ErrServer
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errs.ErrGameNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Delete(ctx context.Context, userID, gameID string) error {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	res, err := r.db.ExecContext(ctx, `DELETE FROM games WHERE user_id = ? AND game_id = ?`, userID, gameID)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to delete game")
+		return errs.ErrServer
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errs.ErrGameNotFound
+	}
+	return nil
+}
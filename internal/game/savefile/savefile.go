@@ -0,0 +1,148 @@
+package savefile
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/game/equipments"
+	"miners_game/internal/game/upgrades"
+	"miners_game/internal/miners"
+	"miners_game/pkg/errs"
+	"time"
+)
+
+// SchemaVersion - версия формата файла, при изменении GameState добавляется шаг в migrations
+const SchemaVersion = 2
+
+// File - переносимое сохранение: подпись считается по компактной записи Payload,
+// отступы в файле на неё не влияют, любое изменение значений - ломает
+type File struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature"`
+}
+
+type Payload struct {
+	Schema     int      `json:"schema"`
+	ExportedAt int64    `json:"exported_at"`
+	UserID     string   `json:"user_id"`
+	Game       GameData `json:"game"`
+}
+
+type GameData struct {
+	GameID       string                   `json:"game_id"`
+	Name         string                   `json:"name"`
+	CreatedAt    int64                    `json:"created_at"`
+	Balance      int64                    `json:"balance"`
+	IncomePerSec int64                    `json:"income_per_sec"`
+	LastUpdateAt int64                    `json:"last_update_at"`
+	Miners       map[string]*miners.Miner `json:"miners"`
+	Equipments   []equipments.Equipment   `json:"equipments"`
+	Upgrades     []upgrades.Upgrade       `json:"upgrades"`
+}
+
+// migrations - шаг с версии N на N+1 над сырым JSON, до разбора в текущие структуры
+var migrations = map[int]func(payload map[string]any){
+	// 1 -> 2: у игр появились название и время создания (слоты сохранений)
+	1: func(payload map[string]any) {
+		game, _ := payload["game"].(map[string]any)
+		if game == nil {
+			return
+		}
+		if _, ok := game["name"]; !ok {
+			game["name"] = "Импорт"
+		}
+		if _, ok := game["created_at"]; !ok {
+			game["created_at"] = game["last_update_at"]
+		}
+	},
+}
+
+func Encode(game *domain.GameState, key []byte) ([]byte, error) {
+	snapshot := game.Clone()
+	payload, err := json.Marshal(Payload{
+		Schema:     SchemaVersion,
+		ExportedAt: time.Now().Unix(),
+		UserID:     snapshot.UserID,
+		Game: GameData{
+			GameID:       snapshot.GameID,
+			Name:         snapshot.Name,
+			CreatedAt:    snapshot.CreatedAt,
+			Balance:      snapshot.Balance,
+			IncomePerSec: snapshot.IncomePerSec,
+			LastUpdateAt: snapshot.LastUpdateAt,
+			Miners:       snapshot.Miners,
+			Equipments:   snapshot.Equipments,
+			Upgrades:     snapshot.Upgrades,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(File{
+		Payload:   payload,
+		Signature: sign(payload, key),
+	}, "", "  ")
+}
+
+// Decode - проверка подписи и миграция схемы, возвращает владельца файла и состояние
+// без привязки к пользователю и слоту
+func Decode(data, key []byte) (string, *domain.GameState, error) {
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil || len(file.Payload) == 0 {
+		return "", nil, errs.ErrSaveSignature
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, file.Payload); err != nil {
+		return "", nil, errs.ErrSaveSignature
+	}
+	if !hmac.Equal([]byte(sign(compact.Bytes(), key)), []byte(file.Signature)) {
+		return "", nil, errs.ErrSaveSignature
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(compact.Bytes(), &raw); err != nil {
+		return "", nil, errs.ErrSaveSignature
+	}
+	schema, _ := raw["schema"].(float64)
+	version := int(schema)
+	if version < 1 || version > SchemaVersion {
+		return "", nil, errs.ErrSaveVersion
+	}
+	for ; version < SchemaVersion; version++ {
+		migrations[version](raw)
+	}
+	raw["schema"] = SchemaVersion
+
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		return "", nil, err
+	}
+	var payload Payload
+	if err := json.Unmarshal(upgraded, &payload); err != nil {
+		return "", nil, errs.ErrSaveVersion
+	}
+
+	g := payload.Game
+	if g.Miners == nil {
+		g.Miners = make(map[string]*miners.Miner)
+	}
+	return payload.UserID, &domain.GameState{
+		Name:         g.Name,
+		CreatedAt:    g.CreatedAt,
+		Balance:      g.Balance,
+		IncomePerSec: g.IncomePerSec,
+		LastUpdateAt: g.LastUpdateAt,
+		Miners:       g.Miners,
+		Equipments:   g.Equipments,
+		Upgrades:     g.Upgrades,
+	}, nil
+}
+
+func sign(payload, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
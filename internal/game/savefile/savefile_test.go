@@ -0,0 +1,68 @@
+package savefile_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/game/savefile"
+	"miners_game/pkg/errs"
+	"testing"
+	"time"
+)
+
+var key = []byte("test-key")
+
+func TestEncodeDecode(t *testing.T) {
+	game := domain.NewGameState("testUserID", "testGameID", time.Now().Unix())
+	game.Name = "Основа"
+	game.Balance = 1234
+	game.AddMiner("small", time.Now().Unix())
+
+	data, err := savefile.Encode(game, key)
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	owner, decoded, err := savefile.Decode(data, key)
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if owner != "testUserID" || decoded.Balance != 1234 || decoded.Name != "Основа" || len(decoded.Miners) != 1 {
+		t.Fatalf("expected round trip, got owner %q state %+v", owner, decoded)
+	}
+}
+
+func TestDecodeTampered(t *testing.T) {
+	game := domain.NewGameState("testUserID", "testGameID", time.Now().Unix())
+	game.Balance = 10
+	data, _ := savefile.Encode(game, key)
+
+	tampered := bytes.Replace(data, []byte(`"balance": 10`), []byte(`"balance": 99999`), 1)
+	if bytes.Equal(data, tampered) {
+		t.Fatalf("expected balance to be found in file")
+	}
+	if _, _, err := savefile.Decode(tampered, key); !errors.Is(err, errs.ErrSaveSignature) {
+		t.Fatalf("expected ErrSaveSignature, got %v:", err)
+	}
+	if _, _, err := savefile.Decode(data, []byte("other-key")); !errors.Is(err, errs.ErrSaveSignature) {
+		t.Fatalf("expected ErrSaveSignature, got %v:", err)
+	}
+}
+
+func TestDecodeUpgradesOldSchema(t *testing.T) {
+	payload := []byte(`{"schema":1,"exported_at":1,"user_id":"testUserID","game":{"balance":5,"last_update_at":77,"miners":{},"equipments":[],"upgrades":[]}}`)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	data, _ := json.Marshal(savefile.File{Payload: payload, Signature: hex.EncodeToString(mac.Sum(nil))})
+
+	_, decoded, err := savefile.Decode(data, key)
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if decoded.Name == "" || decoded.CreatedAt != 77 {
+		t.Fatalf("expected schema 1 to be upgraded, got %+v", decoded)
+	}
+}
@@ -1,12 +1,15 @@
 package game
 
 import (
+	"context"
 	"errors"
+	"miners_game/config"
 	"miners_game/internal/game/domain"
 	"miners_game/internal/game/equipments"
 	"miners_game/internal/game/shop"
 	"miners_game/internal/game/upgrades"
 	"miners_game/internal/miners"
+	"miners_game/pkg/clock"
 	"miners_game/pkg/errs"
 	"strconv"
 	"sync"
@@ -19,57 +22,108 @@ type Service struct {
 	repo     IGameRepository
 	loop     ILoopService
 	sessions ISessionService
-
-	games   map[string]*domain.GameState
-	logger  zerolog.Logger
-	metrics *Metrics
-	mu      sync.RWMutex
+	rewards  IRewardService
+	leases   ILeaseService
+	journal  IJournal
+	archive  IArchiveService
+	config   *config.GameConfig
+	clock    clock.Clock
+
+	games     map[string]*domain.GameState
+	slotLocks map[string]*slotLock
+	logger    zerolog.Logger
+	metrics   *Metrics
+	mu        sync.RWMutex
+	flushMu   sync.Mutex
 }
 
+const (
+	saveRetries      = 3
+	saveRetryBackoff = 200 * time.Millisecond
+	// hudKeepAlive - HUD-поток просыпается хотя бы так часто: в ленивом режиме
+	// тиков нет, а без StreamHud сессия открытого потока истечёт
+	hudKeepAlive = 5 * time.Second
+)
+
 type ServiceDeps struct {
 	Repo     IGameRepository
 	Loop     ILoopService
 	Sessions ISessionService
+	Rewards  IRewardService
+	Leases   ILeaseService
+	Journal  IJournal
+	Archive  IArchiveService
+	Config   *config.GameConfig
+	Clock    clock.Clock
 	Metrics  *Metrics
 	Logger   zerolog.Logger
 }
 
 func NewService(deps ServiceDeps) *Service {
 	return &Service{
-		repo:     deps.Repo,
-		loop:     deps.Loop,
-		sessions: deps.Sessions,
-		logger:   deps.Logger,
-		games:    make(map[string]*domain.GameState),
-		metrics:  deps.Metrics,
+		repo:      deps.Repo,
+		loop:      deps.Loop,
+		sessions:  deps.Sessions,
+		rewards:   deps.Rewards,
+		leases:    deps.Leases,
+		journal:   deps.Journal,
+		archive:   deps.Archive,
+		config:    deps.Config,
+		clock:     clock.OrReal(deps.Clock),
+		logger:    deps.Logger,
+		games:     make(map[string]*domain.GameState),
+		slotLocks: make(map[string]*slotLock),
+		metrics:   deps.Metrics,
 	}
 }
 
-func (s *Service) EnterGame(userID, gameID string) (*domain.GameState, error) {
+func (s *Service) EnterGame(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
 	id := userID + "/" + gameID
 
 	s.mu.RLock()
 	if game, ok := s.games[id]; ok {
 		s.mu.RUnlock()
+		s.advance(game)
 		s.sessions.MarkActive(id)
 		return game, nil
 	}
 	s.mu.RUnlock()
 
-	game, err := s.repo.Load(userID, gameID)
+	// загрузка не пересекается с откатом и параллельной загрузкой той же игры
+	unlock := s.lockSlot(id)
+	defer unlock()
+	if game := s.loaded(id); game != nil {
+		s.advance(game)
+		s.sessions.MarkActive(id)
+		return game, nil
+	}
+
+	// игру тикает и сохраняет только инстанс с арендой, чужую сначала надо забрать
+	if s.leases != nil {
+		if err := s.leases.Acquire(ctx, userID, gameID); err != nil {
+			return nil, err
+		}
+	}
+	game, err := s.repo.Load(ctx, userID, gameID)
+	if errors.Is(err, errs.ErrGameNotFound) {
+		game, err = s.loadArchived(ctx, userID, gameID)
+	}
 	if err != nil {
 		if !errors.Is(err, errs.ErrGameNotFound) {
+			s.releaseLease(ctx, userID, gameID)
 			return nil, err
 		}
-		game = domain.NewGameState(userID, gameID)
-		s.repo.Save(game)
+		game = domain.NewGameState(userID, gameID, s.clock.Now().Unix())
+		game.Name = "Сохранение"
+		s.repo.Save(ctx, game)
 	}
 
-	now := time.Now().Unix()
+	now := s.clock.Now().Unix()
 
 	if now-game.LastUpdateAt > 5 {
 		game.LastUpdateAt = now
 	}
+	s.claimRewards(ctx, game)
 
 	s.mu.Lock()
 	s.games[id] = game
@@ -82,7 +136,7 @@ func (s *Service) EnterGame(userID, gameID string) (*domain.GameState, error) {
 	return game, nil
 }
 
-func (s *Service) BuyMiner(userID, gameID, class, kind string) (shop.ShopCard, error) {
+func (s *Service) BuyMiner(ctx context.Context, userID, gameID, class, kind string) (shop.ShopCard, error) {
 	var err error
 	defer s.buyMetrics(&err)()
 	var game *domain.GameState
@@ -91,15 +145,18 @@ func (s *Service) BuyMiner(userID, gameID, class, kind string) (shop.ShopCard, e
 		return shop.ShopCard{}, err
 	}
 	price := miners.GetMinerConfig(class).Price
-	if err = game.SpendBalance(price); err != nil {
+	now := s.clock.Now().Unix()
+	if err = game.SpendBalance(price, "miner:"+class, now); err != nil {
 		return getErrShopCard(class, kind, err.Error()), err
 	}
-	game.AddMiner(class)
+	game.AddMiner(class, now)
+	s.journalGame(ctx, game)
+	s.notifyHud(userID, gameID)
 
 	return shop.ShopCard{}, nil
 }
 
-func (s *Service) BuyEquipment(userID, gameID, name, kind string) (shop.ShopCard, error) {
+func (s *Service) BuyEquipment(ctx context.Context, userID, gameID, name, kind string) (shop.ShopCard, error) {
 	var err error
 	defer s.buyMetrics(&err)()
 	var game *domain.GameState
@@ -112,14 +169,16 @@ func (s *Service) BuyEquipment(userID, gameID, name, kind string) (shop.ShopCard
 	}
 
 	price := equipments.GetEquipmentConfig(name).Price
-	if err = game.SpendBalance(price); err != nil {
+	if err = game.SpendBalance(price, "equipment:"+name, s.clock.Now().Unix()); err != nil {
 		return getErrShopCard(name, kind, err.Error()), err
 	}
 	game.AddEquipment(name)
+	s.journalGame(ctx, game)
+	s.notifyHud(userID, gameID)
 	return shop.ShopCard{}, nil
 }
 
-func (s *Service) BuyUpgrade(userID, gameID, name, kind string) (shop.ShopCard, error) {
+func (s *Service) BuyUpgrade(ctx context.Context, userID, gameID, name, kind string) (shop.ShopCard, error) {
 	var err error
 	defer s.buyMetrics(&err)()
 	var game *domain.GameState
@@ -131,12 +190,27 @@ func (s *Service) BuyUpgrade(userID, gameID, name, kind string) (shop.ShopCard,
 		return getErrShopCard(name, kind, errs.ErrAlreadyOwn.Error()), errs.ErrAlreadyOwn
 	}
 
+	game.Mu.RLock()
+	first := game.GetMaxUpgrade() == "0"
+	game.Mu.RUnlock()
+
 	price := upgrades.GetUpgradesConfig(name).Price
-	if err = game.SpendBalance(price); err != nil {
+	if err = game.SpendBalance(price, "upgrade:"+name, s.clock.Now().Unix()); err != nil {
 		return getErrShopCard(name, kind, err.Error()), err
 	}
 	game.AddUpgrade(name)
 
+	if first && s.rewards != nil {
+		referrerID, err := s.rewards.OnFirstUpgrade(ctx, userID)
+		if err != nil {
+			s.logger.Error().Err(err).Str("user_id", userID).Msg("failed to reward first upgrade")
+		}
+		s.claimRewards(ctx, game)
+		s.rewardOnline(ctx, referrerID)
+	}
+	s.journalGame(ctx, game)
+	s.notifyHud(userID, gameID)
+
 	return shop.ShopCard{}, nil
 }
 
@@ -151,57 +225,245 @@ func (s *Service) getCurrUpgrade(userID, gameID string) (string, error) {
 	return curr, nil
 }
 
-func (s *Service) DeleteExpiredSessions() {
+// DeleteExpiredSessions - выгрузка игр с истёкшей сессией. После отмены ctx
+// (остановка процесса) игры не выгружаются: их сохранит финальный SaveAll
+func (s *Service) DeleteExpiredSessions(ctx context.Context) {
 	expired := s.sessions.GetExpired()
 	for _, id := range expired {
-		s.loop.Unregister(id)
-
-		s.mu.Lock()
-		game := s.games[id]
-		delete(s.games, id)
-		s.mu.Unlock()
-
-		if game != nil {
-			if err := s.repo.Save(game); err != nil {
-				s.logger.Error().Err(err).Msg("failed to delete expired sessions")
-				return
-			}
+		if ctx.Err() != nil {
+			break
 		}
+		s.expire(ctx, id)
 	}
 	if len(expired) > 0 {
 		s.logger.Info().Int("count", len(expired)).Msg("expired sessions deleted")
 	}
 }
 
-func (s *Service) GetHud(userID, gameID string) (string, string, error) {
+func (s *Service) expire(ctx context.Context, id string) {
+	unlock := s.lockSlot(id)
+	defer unlock()
+	game := s.loaded(id)
+	if game == nil || !s.unload(game) {
+		return
+	}
+	// несохранённая игра снова считается активной, выгрузка повторится позже
+	if !s.saveAndRelease(ctx, game) {
+		s.sessions.MarkActive(id)
+	}
+}
+
+func (s *Service) GetHud(ctx context.Context, userID, gameID string) (string, string, error) {
 	id := userID + "/" + gameID
 
-	game, err := s.EnterGame(userID, gameID)
+	game, err := s.EnterGame(ctx, userID, gameID)
 	if err != nil {
 		return "", "", err
 	}
 
-	game.Mu.Lock()
-	balance := strconv.Itoa(int(game.Balance))
-	income := strconv.Itoa(int(game.IncomePerSec))
-	game.Mu.Unlock()
-
+	balance, income := hudOf(game)
 	s.sessions.MarkActive(id)
 
 	return balance, income, nil
 }
 
-func (s *Service) SaveAll() {
+// SubscribeHud - подписка на тики игры для SSE-потока HUD. Кроме тиков loop
+// канал срабатывает раз в hudKeepAlive и закрывается вместе с подпиской loop
+func (s *Service) SubscribeHud(ctx context.Context, userID, gameID string) (*domain.GameState, <-chan struct{}, func(), error) {
+	game, err := s.EnterGame(ctx, userID, gameID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ticks, unsubscribe := s.loop.Subscribe(userID + "/" + gameID)
+	if s.metrics != nil {
+		s.metrics.HudStreams.Inc()
+	}
+	out := make(chan struct{}, 1)
+	done := make(chan struct{})
+	keepAlive := s.clock.NewTicker(hudKeepAlive)
+	go func() {
+		defer close(out)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case _, ok := <-ticks:
+				if !ok {
+					return
+				}
+			case <-keepAlive.C():
+			case <-done:
+				return
+			}
+			select {
+			case out <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			close(done)
+			unsubscribe()
+			if s.metrics != nil {
+				s.metrics.HudStreams.Dec()
+			}
+		})
+	}
+	return game, out, cancel, nil
+}
+
+// StreamHud - снимок HUD, открытое соединение считается активностью
+func (s *Service) StreamHud(userID, gameID string, game *domain.GameState) HudSnapshot {
+	s.sessions.MarkActive(userID + "/" + gameID)
+	s.advance(game)
+	return newHudSnapshot(game, s.clock.Now().UnixMilli())
+}
+
+func hudOf(game *domain.GameState) (string, string) {
+	game.Mu.RLock()
+	defer game.Mu.RUnlock()
+	return strconv.Itoa(int(game.Balance)), strconv.Itoa(int(game.IncomePerSec))
+}
+
+// SaveAll - сохраняет изменившиеся игры. Глобальная блокировка держится только
+// на время копирования списка, запись идёт по снимкам без блокировки игр.
+// Отмена ctx прерывает запись, несохранённые игры останутся грязными
+func (s *Service) SaveAll(ctx context.Context) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	segment, rotated := s.rotateJournal()
+	s.saveDirty(ctx)
+	if rotated {
+		s.compactJournal(ctx, segment)
+	}
+}
+
+func (s *Service) saveDirty(ctx context.Context) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
+	games := make([]*domain.GameState, 0, len(s.games))
 	for _, game := range s.games {
-		game.Mu.RLock()
-		s.repo.Save(game)
-		game.Mu.RUnlock()
+		games = append(games, game)
+	}
+	s.mu.RUnlock()
+
+	sources := make([]*domain.GameState, 0, len(games))
+	snapshots := make([]*domain.GameState, 0, len(games))
+	for _, game := range games {
+		s.advance(game)
+		if !game.IsDirty() {
+			continue
+		}
+		sources = append(sources, game)
+		snapshots = append(snapshots, game.CloneForSave())
+	}
+	if s.metrics != nil {
+		s.metrics.DirtyGames.Set(float64(len(snapshots)))
 	}
-	if len(s.games) > 0 {
-		s.logger.Info().Int("count", len(s.games)).Msg("saved all games")
+	if len(snapshots) == 0 {
+		return
 	}
+
+	start := time.Now()
+	results := s.repo.SaveBatch(ctx, snapshots)
+	saved, failed := 0, 0
+	for i, err := range results {
+		if err != nil && !errors.Is(err, errs.ErrSaveConflict) {
+			err = s.saveWithRetry(ctx, snapshots[i])
+		}
+		if errors.Is(err, errs.ErrSaveConflict) {
+			s.evictStale(ctx, sources[i])
+		}
+		if err != nil {
+			failed++
+			continue
+		}
+		sources[i].MarkSaved(snapshots[i].Revision, snapshots[i].Version, len(snapshots[i].Ledger))
+		saved++
+	}
+	if s.metrics != nil {
+		s.metrics.SaveDuration.Observe(time.Since(start).Seconds())
+		s.metrics.SavedGamesTotal.Add(float64(saved))
+	}
+	s.logger.Info().Int("saved", saved).Int("failed", failed).Dur("duration", time.Since(start)).Msg("saved dirty games")
+}
+
+// evictStale - копию в памяти опередило сохранение другого процесса. Она
+// выгружается без записи, следующий вход загрузит актуальное состояние из базы
+func (s *Service) evictStale(ctx context.Context, game *domain.GameState) {
+	if !s.unload(game) {
+		return
+	}
+	s.releaseLease(ctx, game.UserID, game.GameID)
+	if s.metrics != nil {
+		s.metrics.SaveConflictsTotal.Inc()
+	}
+	s.logger.Warn().Str("user_id", game.UserID).Str("game_id", game.GameID).Msg("stale game evicted after save conflict")
+}
+
+// saveWithRetry - поштучное сохранение после неудачи в пакете
+func (s *Service) saveWithRetry(ctx context.Context, game *domain.GameState) error {
+	var err error
+	for attempt := 0; attempt < saveRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(saveRetryBackoff << (attempt - 1)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if s.metrics != nil {
+			s.metrics.SaveRetriesTotal.Inc()
+		}
+		if err = s.repo.Save(ctx, game); err == nil || errors.Is(err, errs.ErrSaveConflict) {
+			return err
+		}
+	}
+	if s.metrics != nil {
+		s.metrics.SaveFailedTotal.Inc()
+	}
+	s.logger.Error().Err(err).Str("user_id", game.UserID).Str("game_id", game.GameID).Msg("failed to save game after retries")
+	return err
+}
+
+// Snapshot - копия активной игры для JSON API, вход в игру считается активностью
+func (s *Service) Snapshot(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
+	game, err := s.EnterGame(ctx, userID, gameID)
+	if err != nil {
+		return nil, err
+	}
+	return game.Clone(), nil
+}
+
+func (s *Service) IncomeBreakdown(ctx context.Context, userID, gameID string) (domain.IncomeBreakdown, error) {
+	game, err := s.EnterGame(ctx, userID, gameID)
+	if err != nil {
+		return domain.IncomeBreakdown{}, err
+	}
+	return game.Breakdown(s.clock.Now().Unix()), nil
+}
+
+// ViewGame - чтение последней игры пользователя без регистрации в loop
+func (s *Service) ViewGame(ctx context.Context, userID string) (*domain.GameState, error) {
+	var latest *domain.GameState
+	s.mu.RLock()
+	for _, game := range s.games {
+		if game.UserID != userID {
+			continue
+		}
+		s.advance(game)
+		clone := game.Clone()
+		if latest == nil || clone.LastUpdateAt > latest.LastUpdateAt {
+			latest = clone
+		}
+	}
+	s.mu.RUnlock()
+
+	if latest != nil {
+		return latest, nil
+	}
+	return s.repo.LoadLatest(ctx, userID)
 }
 
 func (s *Service) GetGameState(userID, gameID string) (*domain.GameState, error) {
@@ -217,9 +479,110 @@ func (s *Service) GetGameState(userID, gameID string) (*domain.GameState, error)
 	if !ok {
 		return nil, errs.ErrGameNotFound
 	}
+	s.advance(game)
 	return game, nil
 }
 
+// advance - ленивый режим loop: перед чтением и изменением игра доводится
+// до текущей секунды, иначе доход за прошедшее время посчитался бы по новому
+// набору майнеров и бонусов
+func (s *Service) advance(game *domain.GameState) {
+	if s.loop == nil {
+		return
+	}
+	s.loop.Advance(game)
+}
+
+// notifyHud - покупка меняет баланс скачком, HUD синхронизируется сразу, не дожидаясь тика
+func (s *Service) notifyHud(userID, gameID string) {
+	if s.loop == nil {
+		return
+	}
+	s.loop.Notify(userID + "/" + gameID)
+}
+
+// claimRewards - начисление наград в игру. Строки наград удаляются только после
+// сохранения игры, а уже начисленная, но не сохранённая награда узнаётся по
+// записи журнала, поэтому награда не теряется и не начисляется дважды
+func (s *Service) claimRewards(ctx context.Context, game *domain.GameState) {
+	if s.rewards == nil {
+		return
+	}
+	rewards, err := s.rewards.PendingRewards(ctx, game.UserID, game.GameID)
+	if err != nil {
+		s.logger.Error().Err(err).Str("user_id", game.UserID).Msg("failed to claim rewards")
+		return
+	}
+	var amount int64
+	for _, reward := range rewards {
+		if game.HasLedgerItem(reward.Item()) {
+			continue
+		}
+		game.AddBalance(reward.Amount, domain.ReasonReward, reward.Item(), s.clock.Now().Unix())
+		amount += reward.Amount
+	}
+	if amount > 0 {
+		s.journalGame(ctx, game)
+		s.logger.Info().Str("user_id", game.UserID).Int64("amount", amount).Msg("rewards claimed")
+	}
+}
+
+// rewardOnline - награда пригласившему, чья игра загружена на этом инстансе,
+// начисляется сразу, иначе - при следующей загрузке игры
+func (s *Service) rewardOnline(ctx context.Context, userID string) {
+	if userID == "" {
+		return
+	}
+	var latest *domain.GameState
+	s.mu.RLock()
+	var latestUpdate int64
+	for _, game := range s.games {
+		if game.UserID != userID {
+			continue
+		}
+		game.Mu.RLock()
+		lastUpdate := game.LastUpdateAt
+		game.Mu.RUnlock()
+		if latest == nil || lastUpdate > latestUpdate {
+			latest, latestUpdate = game, lastUpdate
+		}
+	}
+	s.mu.RUnlock()
+	if latest == nil {
+		return
+	}
+	s.advance(latest)
+	s.claimRewards(ctx, latest)
+	s.notifyHud(latest.UserID, latest.GameID)
+}
+
+// Grant - начисление администратором adminID. Активная игра меняется в памяти
+// и сохранится с очередным SaveAll, неактивная сохраняется сразу
+func (s *Service) Grant(ctx context.Context, userID, gameID string, amount int64, adminID string) error {
+	if amount <= 0 {
+		return errs.ErrBadRequest
+	}
+	unlock := s.lockSlot(userID + "/" + gameID)
+	defer unlock()
+	if active := s.loaded(userID + "/" + gameID); active != nil {
+		s.advance(active)
+		active.AddBalance(amount, domain.ReasonAdminGrant, adminID, s.clock.Now().Unix())
+		s.journalGame(ctx, active)
+		s.notifyHud(userID, gameID)
+	} else {
+		game, err := s.repo.Load(ctx, userID, gameID)
+		if err != nil {
+			return err
+		}
+		game.AddBalance(amount, domain.ReasonAdminGrant, adminID, s.clock.Now().Unix())
+		if err := s.repo.Save(ctx, game.CloneForSave()); err != nil {
+			return err
+		}
+	}
+	s.logger.Info().Str("user_id", userID).Str("game_id", gameID).Str("admin_id", adminID).Int64("amount", amount).Msg("balance granted")
+	return nil
+}
+
 func (s *Service) getShopState(kind string) []shop.ShopCard {
 	switch kind {
 	case "miner":
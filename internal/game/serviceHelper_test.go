@@ -7,3 +7,10 @@ func PutGameToMemory(s *Service, userID, gameID string, game *domain.GameState)
 	defer s.mu.Unlock()
 	s.games[userID+"/"+gameID] = game
 }
+
+func GameInMemory(s *Service, userID, gameID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.games[userID+"/"+gameID]
+	return ok
+}
@@ -1,31 +1,72 @@
 package game_test
 
 import (
+	"context"
 	"errors"
+	"miners_game/config"
 	"miners_game/internal/game"
 	"miners_game/internal/game/domain"
+	"miners_game/internal/game/loop"
+	"miners_game/internal/game/savefile"
+	"miners_game/internal/game/sessions"
+	"miners_game/pkg/clock"
 	"miners_game/pkg/errs"
 	"testing"
+	"time"
 )
 
 // Repository:
 type MockGameRepository struct {
-	LoadCalled bool
-	SaveCalled bool
-	MockLoad   func(userID, gameID string) (*domain.GameState, error)
-	MockSave   func(gameState *domain.GameState) error
+	LoadCalled     bool
+	SaveCalled     bool
+	DeleteCalled   bool
+	Slots          []game.SaveSlot
+	BatchSaved     []string
+	MockSaveBatch  func(gameState *domain.GameState) error
+	MockLoad       func(userID, gameID string) (*domain.GameState, error)
+	MockLoadLatest func(userID string) (*domain.GameState, error)
+	MockSave       func(gameState *domain.GameState) error
 }
 
-func (m *MockGameRepository) Load(userID, gameID string) (*domain.GameState, error) {
+func (m *MockGameRepository) Load(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
 	m.LoadCalled = true
 	return m.MockLoad(userID, gameID)
 }
 
-func (m *MockGameRepository) Save(gameState *domain.GameState) error {
+func (m *MockGameRepository) LoadLatest(ctx context.Context, userID string) (*domain.GameState, error) {
+	m.LoadCalled = true
+	return m.MockLoadLatest(userID)
+}
+
+func (m *MockGameRepository) Save(ctx context.Context, gameState *domain.GameState) error {
 	m.SaveCalled = true
 	return m.MockSave(gameState)
 }
 
+func (m *MockGameRepository) SaveBatch(ctx context.Context, games []*domain.GameState) []error {
+	results := make([]error, len(games))
+	for i, g := range games {
+		m.BatchSaved = append(m.BatchSaved, g.GameID)
+		if m.MockSaveBatch != nil {
+			results[i] = m.MockSaveBatch(g)
+		}
+	}
+	return results
+}
+
+func (m *MockGameRepository) ListByUser(ctx context.Context, userID string) ([]game.SaveSlot, error) {
+	return m.Slots, nil
+}
+
+func (m *MockGameRepository) Rename(ctx context.Context, userID, gameID, name string) error {
+	return nil
+}
+
+func (m *MockGameRepository) Delete(ctx context.Context, userID, gameID string) error {
+	m.DeleteCalled = true
+	return nil
+}
+
 // Session:
 type MockSessionService struct {
 	isActive         bool
@@ -59,6 +100,15 @@ func (m *MockLoopService) Register(id string, game *domain.GameState) {
 func (m *MockLoopService) Unregister(id string) {
 	m.UnregisterCalled = true
 
+}
+func (m *MockLoopService) Subscribe(id string) (<-chan struct{}, func()) {
+	return make(chan struct{}), func() {}
+}
+func (m *MockLoopService) Notify(id string) {
+
+}
+func (m *MockLoopService) Advance(game *domain.GameState) {
+
 }
 
 func TestEnterGameSuccess(t *testing.T) {
@@ -83,7 +133,7 @@ func TestEnterGameSuccess(t *testing.T) {
 		Loop:     &loop,
 		Sessions: &sessions,
 	})
-	if _, err := gameService.EnterGame(userID, gameID); err != nil {
+	if _, err := gameService.EnterGame(context.Background(), userID, gameID); err != nil {
 		t.Fatalf("expected success, got err %v:", err)
 	}
 	if !loop.RegisterCalled {
@@ -114,9 +164,9 @@ func TestEnterGameFromMemorySuccess(t *testing.T) {
 		Loop:     &loop,
 		Sessions: &session,
 	})
-	gameState := domain.NewGameState(userID, gameID)
+	gameState := domain.NewGameState(userID, gameID, time.Now().Unix())
 	game.PutGameToMemory(gameService, userID, gameID, gameState)
-	if _, err := gameService.EnterGame(userID, gameID); err != nil {
+	if _, err := gameService.EnterGame(context.Background(), userID, gameID); err != nil {
 		t.Fatalf("expected success, got err %v:", err)
 	}
 	if repo.LoadCalled {
@@ -150,7 +200,7 @@ func TestEnterCreateNewSuccess(t *testing.T) {
 		Loop:     &loop,
 		Sessions: &sessions,
 	})
-	if _, err := gameService.EnterGame(userID, gameID); err != nil {
+	if _, err := gameService.EnterGame(context.Background(), userID, gameID); err != nil {
 		t.Fatalf("expected success, got err %v:", err)
 	}
 	if !loop.RegisterCalled {
@@ -184,7 +234,7 @@ func TestEnterRepositoryError(t *testing.T) {
 		Loop:     &loop,
 		Sessions: &sessions,
 	})
-	if _, err := gameService.EnterGame(userID, gameID); err == nil {
+	if _, err := gameService.EnterGame(context.Background(), userID, gameID); err == nil {
 		t.Fatalf("expected error")
 	}
 	if loop.RegisterCalled {
@@ -252,15 +302,42 @@ func TestBuyMinerSuccess(t *testing.T) {
 		Repo:     nil,
 		Loop:     nil,
 	})
-	gameState := domain.NewGameState(userID, gameID)
+	gameState := domain.NewGameState(userID, gameID, time.Now().Unix())
 	gameState.Balance = 1000000
 	game.PutGameToMemory(gameService, userID, gameID, gameState)
-	_, err := gameService.BuyMiner(userID, gameID, "small", "miner")
+	_, err := gameService.BuyMiner(context.Background(), userID, gameID, "small", "miner")
 	if err != nil {
 		t.Fatalf("expected success, got %v:", err)
 	}
 }
 
+func TestBuyMinerStartsAtClock(t *testing.T) {
+	userID := "testUserID"
+	gameID := "testGameID"
+
+	clk := clock.NewFake(time.Unix(1000, 0))
+	gameService := game.NewService(game.ServiceDeps{
+		Sessions: &MockSessionService{isActive: true},
+		Clock:    clk,
+	})
+	gameState := domain.NewGameState(userID, gameID, clk.Now().Unix())
+	gameState.Balance = 1000
+	game.PutGameToMemory(gameService, userID, gameID, gameState)
+
+	clk.Advance(5 * time.Second)
+	if _, err := gameService.BuyMiner(context.Background(), userID, gameID, "small", "miner"); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	for _, miner := range gameState.Miners {
+		if miner.StartAt != 1005 || miner.EndAt != 1035 {
+			t.Fatalf("expected miner from 1005 to 1035, got %d-%d", miner.StartAt, miner.EndAt)
+		}
+	}
+	if len(gameState.Miners) != 1 {
+		t.Fatalf("expected one miner, got %d", len(gameState.Miners))
+	}
+}
+
 func TestBuyMinerNotEnoughBalance(t *testing.T) {
 	userID := "testUserID"
 	gameID := "testGameID"
@@ -273,10 +350,10 @@ func TestBuyMinerNotEnoughBalance(t *testing.T) {
 		Repo:     nil,
 		Loop:     nil,
 	})
-	gameState := domain.NewGameState(userID, gameID)
+	gameState := domain.NewGameState(userID, gameID, time.Now().Unix())
 
 	game.PutGameToMemory(gameService, userID, gameID, gameState)
-	_, err := gameService.BuyMiner(userID, gameID, "small", "miner")
+	_, err := gameService.BuyMiner(context.Background(), userID, gameID, "small", "miner")
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -297,11 +374,11 @@ func TestBuyEquipmentAlreadyOwn(t *testing.T) {
 		Repo:     nil,
 		Loop:     nil,
 	})
-	gameState := domain.NewGameState(userID, gameID)
+	gameState := domain.NewGameState(userID, gameID, time.Now().Unix())
 	gameState.AddEquipment("1")
 
 	game.PutGameToMemory(gameService, userID, gameID, gameState)
-	_, err := gameService.BuyEquipment(userID, gameID, "1", "equipment")
+	_, err := gameService.BuyEquipment(context.Background(), userID, gameID, "1", "equipment")
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -331,9 +408,9 @@ func TestDeleteExpiredSessionsSuccess(t *testing.T) {
 		Repo:     &repo,
 		Loop:     &loop,
 	})
-	gameState := domain.NewGameState(userID, gameID)
+	gameState := domain.NewGameState(userID, gameID, time.Now().Unix())
 	game.PutGameToMemory(gameService, userID, gameID, gameState)
-	gameService.DeleteExpiredSessions()
+	gameService.DeleteExpiredSessions(context.Background())
 	if !loop.UnregisterCalled {
 		t.Fatalf("expected game to be unregister in loop")
 	}
@@ -343,6 +420,106 @@ func TestDeleteExpiredSessionsSuccess(t *testing.T) {
 
 }
 
+func TestDeleteExpiredSessionsKeepsUnsavedGame(t *testing.T) {
+	userID := "testUserID"
+	gameID := "testGameID"
+	repo := MockGameRepository{
+		MockSave: func(gameState *domain.GameState) error {
+			return errors.New("connection refused")
+		},
+	}
+	loop := MockLoopService{}
+	sessions := MockSessionService{
+		MockGetExpired: func() []string {
+			return []string{userID + "/" + gameID}
+		},
+	}
+	gameService := game.NewService(game.ServiceDeps{
+		Sessions: &sessions,
+		Repo:     &repo,
+		Loop:     &loop,
+	})
+	gameState := domain.NewGameState(userID, gameID, time.Now().Unix())
+	gameState.AddBalance(100, domain.ReasonReward, "", time.Now().Unix())
+	game.PutGameToMemory(gameService, userID, gameID, gameState)
+
+	gameService.DeleteExpiredSessions(context.Background())
+	if !game.GameInMemory(gameService, userID, gameID) {
+		t.Fatalf("expected unsaved game to stay in memory")
+	}
+	if !loop.RegisterCalled || !sessions.MarkActiveCalled {
+		t.Fatalf("expected unsaved game to be registered and active again")
+	}
+	if !gameState.IsDirty() {
+		t.Fatalf("expected unsaved game to stay dirty for next SaveAll")
+	}
+}
+
+func TestDeleteExpiredSessionsStopsOnCancel(t *testing.T) {
+	userID := "testUserID"
+	gameID := "testGameID"
+	repo := MockGameRepository{}
+	loop := MockLoopService{}
+	sessions := MockSessionService{
+		MockGetExpired: func() []string {
+			return []string{userID + "/" + gameID}
+		},
+	}
+	gameService := game.NewService(game.ServiceDeps{
+		Sessions: &sessions,
+		Repo:     &repo,
+		Loop:     &loop,
+	})
+	game.PutGameToMemory(gameService, userID, gameID, domain.NewGameState(userID, gameID, time.Now().Unix()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	gameService.DeleteExpiredSessions(ctx)
+	if !game.GameInMemory(gameService, userID, gameID) || loop.UnregisterCalled || repo.SaveCalled {
+		t.Fatalf("expected game to be left for the final SaveAll")
+	}
+}
+
+func TestLazyHudStreamKeepsSessionAlive(t *testing.T) {
+	userID := "testUserID"
+	gameID := "testGameID"
+	clk := clock.NewFake(time.Unix(1000, 0))
+	repo := MockGameRepository{
+		MockLoad: func(userID, gameID string) (*domain.GameState, error) {
+			return domain.NewGameState(userID, gameID, 1000), nil
+		},
+	}
+	sessionService := sessions.NewService(sessions.ServiceDeps{Timeout: time.Minute, Clock: clk})
+	loopService := loop.NewService(loop.ServiceDeps{
+		Config: &config.LoopConfig{Shards: 1, TickInterval: time.Second, Lazy: true},
+		Clock:  clk,
+	})
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:     &repo,
+		Loop:     loopService,
+		Sessions: sessionService,
+		Clock:    clk,
+	})
+	gameState, ticks, cancel, err := gameService.SubscribeHud(context.Background(), userID, gameID)
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	defer cancel()
+
+	// у игры без майнеров в ленивом режиме нет ни одного события loop
+	for i := 0; i < 30; i++ {
+		clk.Advance(5 * time.Second)
+		<-ticks
+		gameService.StreamHud(userID, gameID, gameState)
+		if expired := sessionService.GetExpired(); len(expired) != 0 {
+			t.Fatalf("expected open stream to keep session, got expired %v", expired)
+		}
+	}
+	if gameState.Balance != 150 {
+		t.Fatalf("expected balance advanced by stream, got %d", gameState.Balance)
+	}
+}
+
 func TestGetHudSuccess(t *testing.T) {
 	userID := "testUserID"
 	gameID := "testGameID"
@@ -365,7 +542,7 @@ func TestGetHudSuccess(t *testing.T) {
 		Loop:     &loop,
 	})
 
-	balance, income, err := gameService.GetHud(userID, gameID)
+	balance, income, err := gameService.GetHud(context.Background(), userID, gameID)
 	if err != nil {
 		t.Fatalf("expected success, got %v:", err)
 	}
@@ -376,3 +553,594 @@ func TestGetHudSuccess(t *testing.T) {
 		t.Fatalf("expected game to be mark active")
 	}
 }
+
+func TestViewGameDoesNotRegister(t *testing.T) {
+	userID := "testUserID"
+	repo := MockGameRepository{
+		MockLoadLatest: func(userID string) (*domain.GameState, error) {
+			return domain.NewGameState(userID, "testGameID", time.Now().Unix()), nil
+		},
+	}
+	loop := MockLoopService{}
+	sessions := MockSessionService{}
+
+	gameService := game.NewService(game.ServiceDeps{
+		Sessions: &sessions,
+		Repo:     &repo,
+		Loop:     &loop,
+	})
+	if _, err := gameService.ViewGame(context.Background(), userID); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if loop.RegisterCalled {
+		t.Fatalf("expected game to not be registered in loop")
+	}
+	if sessions.MarkActiveCalled {
+		t.Fatalf("expected session to not be marked active")
+	}
+}
+
+func TestHudSnapshotPredicts(t *testing.T) {
+	last := game.HudSnapshot{Balance: 100, Rate: 5, At: 10, Expiries: []int64{40}}
+
+	if !last.Predicts(game.HudSnapshot{Balance: 115, Rate: 5, At: 13, Expiries: []int64{40}}) {
+		t.Fatalf("expected steady growth to be predicted")
+	}
+	if last.Predicts(game.HudSnapshot{Balance: 5, Rate: 5, At: 13, Expiries: []int64{40}}) {
+		t.Fatalf("expected purchase to require resync")
+	}
+	if last.Predicts(game.HudSnapshot{Balance: 115, Rate: 1, At: 13, Expiries: []int64{}}) {
+		t.Fatalf("expected expiry to require resync")
+	}
+}
+
+func TestCreateSlotLimit(t *testing.T) {
+	repo := MockGameRepository{
+		Slots: []game.SaveSlot{{GameID: "1"}, {GameID: "2"}},
+		MockSave: func(gameState *domain.GameState) error {
+			return nil
+		},
+	}
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:   &repo,
+		Config: &config.GameConfig{MaxSaveSlots: 2},
+	})
+	if _, err := gameService.CreateSlot(context.Background(), "testUserID", "новый"); !errors.Is(err, errs.ErrSlotLimit) {
+		t.Fatalf("expected ErrSlotLimit, got %v:", err)
+	}
+	if repo.SaveCalled {
+		t.Fatalf("expected no save over the limit")
+	}
+}
+
+func TestCreateSlotLimitConcurrent(t *testing.T) {
+	repo := MockGameRepository{}
+	repo.MockSave = func(gameState *domain.GameState) error {
+		time.Sleep(time.Millisecond)
+		repo.Slots = append(repo.Slots, game.SaveSlot{GameID: gameState.GameID})
+		return nil
+	}
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:   &repo,
+		Config: &config.GameConfig{MaxSaveSlots: 1},
+	})
+
+	results := make(chan error, 4)
+	for range 4 {
+		go func() {
+			_, err := gameService.CreateSlot(context.Background(), "testUserID", "новый")
+			results <- err
+		}()
+	}
+	created := 0
+	for range 4 {
+		err := <-results
+		if err == nil {
+			created++
+		} else if !errors.Is(err, errs.ErrSlotLimit) {
+			t.Fatalf("expected ErrSlotLimit, got %v:", err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("expected 1 slot, got %d", created)
+	}
+}
+
+func TestDeleteSlotEvictsGame(t *testing.T) {
+	userID := "testUserID"
+	gameID := "testGameID"
+
+	repo := MockGameRepository{}
+	loop := MockLoopService{}
+	sessions := MockSessionService{isActive: true}
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:     &repo,
+		Loop:     &loop,
+		Sessions: &sessions,
+	})
+	game.PutGameToMemory(gameService, userID, gameID, domain.NewGameState(userID, gameID, time.Now().Unix()))
+
+	if err := gameService.DeleteSlot(context.Background(), userID, gameID); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if !repo.DeleteCalled || !loop.UnregisterCalled {
+		t.Fatalf("expected game to be deleted and unregistered")
+	}
+	if _, err := gameService.GetGameState(userID, gameID); !errors.Is(err, errs.ErrGameNotFound) {
+		t.Fatalf("expected ErrGameNotFound, got %v:", err)
+	}
+}
+
+func TestRestoreHoldsSlotUntilSaved(t *testing.T) {
+	userID := "testUserID"
+	gameID := "testGameID"
+	stored := domain.NewGameState(userID, gameID, time.Now().Unix())
+	stored.Name = "Старое"
+	saving := make(chan struct{})
+	release := make(chan struct{})
+	repo := MockGameRepository{
+		MockLoad: func(userID, gameID string) (*domain.GameState, error) {
+			return stored.Clone(), nil
+		},
+		MockSave: func(gameState *domain.GameState) error {
+			close(saving)
+			<-release
+			stored = gameState.Clone()
+			return nil
+		},
+	}
+	leases := MockLeaseService{}
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:     &repo,
+		Loop:     &MockLoopService{},
+		Sessions: &MockSessionService{},
+		Leases:   &leases,
+	})
+
+	restored := domain.NewGameState(userID, gameID, time.Now().Unix())
+	restored.Name = "Откат"
+	restored.Balance = 500
+	restoreErr := make(chan error, 1)
+	go func() {
+		restoreErr <- gameService.Restore(context.Background(), restored)
+	}()
+	<-saving
+
+	entered := make(chan *domain.GameState, 1)
+	go func() {
+		g, _ := gameService.EnterGame(context.Background(), userID, gameID)
+		entered <- g
+	}()
+	select {
+	case <-entered:
+		t.Fatalf("expected EnterGame to wait for restore")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	if err := <-restoreErr; err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	g := <-entered
+	if g == nil || g.Balance != 500 || g.Name != "Откат" {
+		t.Fatalf("expected restored game, got %+v", g)
+	}
+	if len(leases.Released) != 1 {
+		t.Fatalf("expected restore to release its lease, got %v", leases.Released)
+	}
+}
+
+func TestResolveGameIDLastPlayed(t *testing.T) {
+	repo := MockGameRepository{
+		Slots: []game.SaveSlot{
+			{GameID: "old", LastUpdateAt: 100},
+			{GameID: "last", LastUpdateAt: 300},
+			{GameID: "mid", LastUpdateAt: 200},
+		},
+	}
+	gameService := game.NewService(game.ServiceDeps{Repo: &repo})
+	gameID, err := gameService.ResolveGameID(context.Background(), "testUserID")
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if gameID != "last" {
+		t.Fatalf("expected last played game, got %q", gameID)
+	}
+}
+
+func TestImportSlotForeignOwner(t *testing.T) {
+	key := []byte("test-key")
+	data, err := savefile.Encode(domain.NewGameState("otherUserID", "otherGameID", time.Now().Unix()), key)
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	repo := MockGameRepository{
+		MockSave: func(gameState *domain.GameState) error {
+			if gameState.UserID != "testUserID" || gameState.GameID == "otherGameID" {
+				t.Fatalf("expected import into a new slot of the importer, got %s/%s", gameState.UserID, gameState.GameID)
+			}
+			return nil
+		},
+	}
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:   &repo,
+		Config: &config.GameConfig{MaxSaveSlots: 3, SaveSigningKey: key},
+	})
+	if _, err := gameService.ImportSlot(context.Background(), "testUserID", data, false); !errors.Is(err, errs.ErrSaveOwner) {
+		t.Fatalf("expected ErrSaveOwner, got %v:", err)
+	}
+	if _, err := gameService.ImportSlot(context.Background(), "testUserID", data, true); err != nil {
+		t.Fatalf("expected support import success, got %v:", err)
+	}
+}
+
+func TestSaveAllOnlyDirtyGames(t *testing.T) {
+	userID := "testUserID"
+	repo := MockGameRepository{
+		MockSaveBatch: func(gameState *domain.GameState) error {
+			if gameState.GameID == "failedGameID" {
+				return errors.New("batch failed")
+			}
+			return nil
+		},
+		MockSave: func(gameState *domain.GameState) error {
+			return nil
+		},
+	}
+	gameService := game.NewService(game.ServiceDeps{Repo: &repo})
+
+	clean := domain.NewGameState(userID, "cleanGameID", time.Now().Unix())
+	dirty := domain.NewGameState(userID, "dirtyGameID", time.Now().Unix())
+	dirty.AddBalance(10, domain.ReasonReward, "", time.Now().Unix())
+	failed := domain.NewGameState(userID, "failedGameID", time.Now().Unix())
+	failed.AddBalance(10, domain.ReasonReward, "", time.Now().Unix())
+	game.PutGameToMemory(gameService, userID, clean.GameID, clean)
+	game.PutGameToMemory(gameService, userID, dirty.GameID, dirty)
+	game.PutGameToMemory(gameService, userID, failed.GameID, failed)
+
+	gameService.SaveAll(context.Background())
+	if len(repo.BatchSaved) != 2 {
+		t.Fatalf("expected only dirty games in batch, got %v", repo.BatchSaved)
+	}
+	if !repo.SaveCalled {
+		t.Fatalf("expected failed game to be retried")
+	}
+	if dirty.IsDirty() || failed.IsDirty() {
+		t.Fatalf("expected saved games to be clean")
+	}
+
+	repo.BatchSaved = nil
+	gameService.SaveAll(context.Background())
+	if len(repo.BatchSaved) != 0 {
+		t.Fatalf("expected nothing to save, got %v", repo.BatchSaved)
+	}
+}
+
+func TestSaveAllEvictsOnConflict(t *testing.T) {
+	userID := "testUserID"
+	gameID := "testGameID"
+	repo := MockGameRepository{
+		MockSaveBatch: func(gameState *domain.GameState) error {
+			return errs.ErrSaveConflict
+		},
+	}
+	loop := MockLoopService{}
+	sessions := MockSessionService{isActive: true}
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:     &repo,
+		Loop:     &loop,
+		Sessions: &sessions,
+	})
+	gameState := domain.NewGameState(userID, gameID, time.Now().Unix())
+	gameState.AddBalance(10, domain.ReasonReward, "", time.Now().Unix())
+	game.PutGameToMemory(gameService, userID, gameID, gameState)
+
+	gameService.SaveAll(context.Background())
+	if repo.SaveCalled {
+		t.Fatalf("expected conflict not to be retried")
+	}
+	if !loop.UnregisterCalled {
+		t.Fatalf("expected stale game to be unregistered")
+	}
+	if _, err := gameService.GetGameState(userID, gameID); !errors.Is(err, errs.ErrGameNotFound) {
+		t.Fatalf("expected ErrGameNotFound, got %v:", err)
+	}
+}
+
+func TestSaveAllWritesLedger(t *testing.T) {
+	userID := "testUserID"
+	gameID := "testGameID"
+	var saved []domain.LedgerEntry
+	repo := MockGameRepository{
+		MockSaveBatch: func(gameState *domain.GameState) error {
+			saved = gameState.Ledger
+			return nil
+		},
+	}
+	gameService := game.NewService(game.ServiceDeps{Repo: &repo})
+	gameState := domain.NewGameState(userID, gameID, time.Now().Unix())
+	gameState.Tick(gameState.LastUpdateAt + 100)
+	if err := gameState.SpendBalance(10, "miner:small", time.Now().Unix()); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	game.PutGameToMemory(gameService, userID, gameID, gameState)
+
+	gameService.SaveAll(context.Background())
+	if len(saved) != 2 || saved[0].Reason != domain.ReasonIncome || saved[1].Reason != domain.ReasonPurchase {
+		t.Fatalf("expected income and purchase entries, got %+v", saved)
+	}
+	var sum int64
+	for _, entry := range saved {
+		sum += entry.Amount
+	}
+	if sum != gameState.Balance || saved[1].BalanceAfter != gameState.Balance {
+		t.Fatalf("expected ledger to match balance %d, got %+v", gameState.Balance, saved)
+	}
+	if len(gameState.Ledger) != 0 {
+		t.Fatalf("expected saved entries to be dropped, got %+v", gameState.Ledger)
+	}
+}
+
+func TestGrantLedgerUsesClock(t *testing.T) {
+	userID := "testUserID"
+	gameID := "testGameID"
+	var saved *domain.GameState
+	repo := MockGameRepository{
+		MockLoad: func(userID, gameID string) (*domain.GameState, error) {
+			return domain.NewGameState(userID, gameID, 1000), nil
+		},
+		MockSave: func(gameState *domain.GameState) error {
+			saved = gameState
+			return nil
+		},
+	}
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:  &repo,
+		Clock: clock.NewFake(time.Unix(5000, 0)),
+	})
+
+	if err := gameService.Grant(context.Background(), userID, gameID, 10, "testAdminID"); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if saved == nil || len(saved.Ledger) != 1 || saved.Ledger[0].At != 5000 {
+		t.Fatalf("expected grant entry at clock time, got %+v", saved)
+	}
+}
+
+func TestSaveAllStopsRetriesOnCancel(t *testing.T) {
+	userID := "testUserID"
+	gameID := "testGameID"
+	saves := 0
+	repo := MockGameRepository{
+		MockSaveBatch: func(gameState *domain.GameState) error {
+			return errors.New("database is unavailable")
+		},
+		MockSave: func(gameState *domain.GameState) error {
+			saves++
+			return errors.New("database is unavailable")
+		},
+	}
+	gameService := game.NewService(game.ServiceDeps{Repo: &repo})
+	gameState := domain.NewGameState(userID, gameID, time.Now().Unix())
+	gameState.AddBalance(10, domain.ReasonReward, "", time.Now().Unix())
+	game.PutGameToMemory(gameService, userID, gameID, gameState)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	gameService.SaveAll(ctx)
+	if saves != 1 {
+		t.Fatalf("expected retries to stop after cancel, got %d saves", saves)
+	}
+	if !gameState.IsDirty() {
+		t.Fatalf("expected unsaved game to stay dirty")
+	}
+}
+
+// Lease:
+type MockLeaseService struct {
+	Released []string
+	Lost     []string
+	Handoffs []string
+}
+
+func (m *MockLeaseService) Acquire(ctx context.Context, userID, gameID string) error {
+	return nil
+}
+
+func (m *MockLeaseService) Renew(ctx context.Context) ([]string, []string, error) {
+	return m.Lost, m.Handoffs, nil
+}
+
+func (m *MockLeaseService) Release(ctx context.Context, userID, gameID string) error {
+	m.Released = append(m.Released, userID+"/"+gameID)
+	return nil
+}
+
+func (m *MockLeaseService) ReleaseAll(ctx context.Context) error {
+	return nil
+}
+
+func TestRenewLeasesHandsOffGame(t *testing.T) {
+	userID := "testUserID"
+	var saved *domain.GameState
+	repo := MockGameRepository{
+		MockSave: func(gameState *domain.GameState) error {
+			saved = gameState
+			return nil
+		},
+	}
+	loop := MockLoopService{}
+	leases := MockLeaseService{
+		Lost:     []string{userID + "/lostGameID"},
+		Handoffs: []string{userID + "/handoffGameID"},
+	}
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:     &repo,
+		Loop:     &loop,
+		Sessions: &MockSessionService{isActive: true},
+		Leases:   &leases,
+	})
+	lost := domain.NewGameState(userID, "lostGameID", time.Now().Unix())
+	handoff := domain.NewGameState(userID, "handoffGameID", time.Now().Unix())
+	handoff.AddBalance(10, domain.ReasonReward, "", time.Now().Unix())
+	game.PutGameToMemory(gameService, userID, lost.GameID, lost)
+	game.PutGameToMemory(gameService, userID, handoff.GameID, handoff)
+
+	gameService.RenewLeases(context.Background())
+	if saved == nil || saved.GameID != handoff.GameID || saved.Balance != 10 {
+		t.Fatalf("expected handed off game to be saved, got %+v", saved)
+	}
+	if len(leases.Released) != 1 || leases.Released[0] != userID+"/handoffGameID" {
+		t.Fatalf("expected handoff lease to be released, got %v", leases.Released)
+	}
+	for _, gameID := range []string{lost.GameID, handoff.GameID} {
+		if _, err := gameService.GetGameState(userID, gameID); !errors.Is(err, errs.ErrGameNotFound) {
+			t.Fatalf("expected ErrGameNotFound, got %v:", err)
+		}
+	}
+}
+
+// Journal:
+type MockJournal struct {
+	Appended  []string
+	Truncated bool
+}
+
+func (m *MockJournal) Append(ctx context.Context, gameState *domain.GameState) error {
+	m.Appended = append(m.Appended, gameState.GameID)
+	gameState.MarkJournaled(gameState.Revision)
+	return nil
+}
+
+func (m *MockJournal) Rotate() (uint64, error) {
+	return 1, nil
+}
+
+func (m *MockJournal) Truncate(upTo uint64) error {
+	m.Truncated = true
+	return nil
+}
+
+func TestSaveAllCarriesUnsavedJournal(t *testing.T) {
+	userID := "testUserID"
+	repo := MockGameRepository{
+		MockSaveBatch: func(gameState *domain.GameState) error {
+			if gameState.GameID == "failedGameID" {
+				return errors.New("database is unavailable")
+			}
+			return nil
+		},
+		MockSave: func(gameState *domain.GameState) error {
+			return errors.New("database is unavailable")
+		},
+	}
+	journal := MockJournal{}
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:     &repo,
+		Sessions: &MockSessionService{isActive: true},
+		Journal:  &journal,
+	})
+	for _, gameID := range []string{"savedGameID", "failedGameID"} {
+		gameState := domain.NewGameState(userID, gameID, time.Now().Unix())
+		gameState.Balance = 1000
+		game.PutGameToMemory(gameService, userID, gameID, gameState)
+		if _, err := gameService.BuyMiner(context.Background(), userID, gameID, "small", "miner"); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+	}
+	if len(journal.Appended) != 2 {
+		t.Fatalf("expected purchases to be journaled, got %v", journal.Appended)
+	}
+
+	journal.Appended = nil
+	gameService.SaveAll(context.Background())
+	if len(journal.Appended) != 1 || journal.Appended[0] != "failedGameID" {
+		t.Fatalf("expected only unsaved game to be carried over, got %v", journal.Appended)
+	}
+	if !journal.Truncated {
+		t.Fatalf("expected old segments to be truncated")
+	}
+}
+
+// Archive:
+type MockArchiveService struct {
+	RestoreCalled bool
+	MockRestore   func(userID string) (int, error)
+}
+
+func (m *MockArchiveService) Restore(ctx context.Context, userID string) (int, error) {
+	m.RestoreCalled = true
+	return m.MockRestore(userID)
+}
+
+func TestEnterGameRestoresArchivedGame(t *testing.T) {
+	archived := domain.NewGameState("testUserID", "testGameID", time.Now().Unix())
+	archived.Balance = 4200
+	inGames := false
+	repo := MockGameRepository{
+		MockLoad: func(userID, gameID string) (*domain.GameState, error) {
+			if !inGames {
+				return nil, errs.ErrGameNotFound
+			}
+			return archived.Clone(), nil
+		},
+		MockSave: func(gameState *domain.GameState) error {
+			return nil
+		},
+	}
+	archive := MockArchiveService{
+		MockRestore: func(userID string) (int, error) {
+			inGames = true
+			return 1, nil
+		},
+	}
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:     &repo,
+		Loop:     &MockLoopService{},
+		Sessions: &MockSessionService{},
+		Archive:  &archive,
+	})
+
+	g, err := gameService.EnterGame(context.Background(), "testUserID", "testGameID")
+	if err != nil {
+		t.Fatalf("expected success, got err %v:", err)
+	}
+	if !archive.RestoreCalled {
+		t.Fatalf("expected archive to be asked for the game")
+	}
+	if g.Balance != 4200 {
+		t.Fatalf("expected archived balance 4200, got %d", g.Balance)
+	}
+	if repo.SaveCalled {
+		t.Fatalf("expected no new game to be created over the archived one")
+	}
+}
+
+func TestEnterGameArchiveErrorDoesNotCreateGame(t *testing.T) {
+	repo := MockGameRepository{
+		MockLoad: func(userID, gameID string) (*domain.GameState, error) {
+			return nil, errs.ErrGameNotFound
+		},
+		MockSave: func(gameState *domain.GameState) error {
+			return nil
+		},
+	}
+	archive := MockArchiveService{
+		MockRestore: func(userID string) (int, error) {
+			return 0, errs.ErrServer
+		},
+	}
+	gameService := game.NewService(game.ServiceDeps{
+		Repo:     &repo,
+		Loop:     &MockLoopService{},
+		Sessions: &MockSessionService{},
+		Archive:  &archive,
+	})
+
+	if _, err := gameService.EnterGame(context.Background(), "testUserID", "testGameID"); !errors.Is(err, errs.ErrServer) {
+		t.Fatalf("expected ErrServer, got %v:", err)
+	}
+	if repo.SaveCalled {
+		t.Fatalf("expected no new game to be created over the archived one")
+	}
+}
@@ -1,6 +1,7 @@
 package sessions
 
 import (
+	"miners_game/pkg/clock"
 	"sync"
 	"time"
 
@@ -14,12 +15,14 @@ type Session struct {
 type Service struct {
 	sessions map[string]*Session
 	timeout  time.Duration
+	clock    clock.Clock
 	logger   zerolog.Logger
 	mu       sync.RWMutex
 }
 
 type ServiceDeps struct {
 	Timeout time.Duration
+	Clock   clock.Clock
 	Logger  zerolog.Logger
 }
 
@@ -27,12 +30,13 @@ func NewService(deps ServiceDeps) *Service {
 	return &Service{
 		sessions: make(map[string]*Session),
 		timeout:  deps.Timeout,
+		clock:    clock.OrReal(deps.Clock),
 		logger:   deps.Logger,
 	}
 }
 
 func (s *Service) MarkActive(id string) {
-	now := time.Now()
+	now := s.clock.Now()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	session, ok := s.sessions[id]
@@ -53,11 +57,11 @@ func (s *Service) IsActive(id string) bool {
 		return false
 	}
 
-	return time.Since(session.LastSeen) <= s.timeout
+	return s.clock.Now().Sub(session.LastSeen) <= s.timeout
 }
 
 func (s *Service) GetExpired() []string {
-	now := time.Now()
+	now := s.clock.Now()
 	expired := []string{}
 
 	s.mu.Lock()
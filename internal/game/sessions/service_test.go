@@ -0,0 +1,37 @@
+package sessions_test
+
+import (
+	"miners_game/internal/game/sessions"
+	"miners_game/pkg/clock"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSessionExpiresAfterTimeout(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	sessionService := sessions.NewService(sessions.ServiceDeps{
+		Timeout: time.Minute,
+		Clock:   clk,
+		Logger:  zerolog.Nop(),
+	})
+	sessionService.MarkActive("testUserID/testGameID")
+
+	clk.Advance(59 * time.Second)
+	if !sessionService.IsActive("testUserID/testGameID") {
+		t.Fatalf("expected session to be active before timeout")
+	}
+	if expired := sessionService.GetExpired(); len(expired) != 0 {
+		t.Fatalf("expected no expired sessions, got %v", expired)
+	}
+
+	clk.Advance(time.Second)
+	expired := sessionService.GetExpired()
+	if len(expired) != 1 || expired[0] != "testUserID/testGameID" {
+		t.Fatalf("expected session to expire, got %v", expired)
+	}
+	if sessionService.IsActive("testUserID/testGameID") {
+		t.Fatalf("expected expired session to be removed")
+	}
+}
@@ -0,0 +1,313 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/game/savefile"
+	"miners_game/pkg/errs"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const maxSlotNameLength = 32
+
+// ListSlots - сохранения пользователя, для активных игр баланс берётся из памяти
+func (s *Service) ListSlots(ctx context.Context, userID string) ([]SaveSlot, error) {
+	slots, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range slots {
+		s.mu.RLock()
+		game, ok := s.games[userID+"/"+slots[i].GameID]
+		s.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		s.advance(game)
+		game.Mu.RLock()
+		slots[i].Balance = game.Balance
+		slots[i].LastUpdateAt = game.LastUpdateAt
+		game.Mu.RUnlock()
+	}
+	return slots, nil
+}
+
+// ResolveGameID - игра аккаунта для новой сессии: последняя сыгранная,
+// для нового игрока - новый id, игру создаст EnterGame. Аккаунт без игр
+// сначала возвращается из архива
+func (s *Service) ResolveGameID(ctx context.Context, userID string) (string, error) {
+	slots, err := s.ListSlots(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if len(slots) == 0 && s.archive != nil {
+		restored, err := s.archive.Restore(ctx, userID)
+		if err != nil && restored == 0 {
+			return "", err
+		}
+		if slots, err = s.ListSlots(ctx, userID); err != nil {
+			return "", err
+		}
+	}
+	if len(slots) == 0 {
+		return uuid.NewString(), nil
+	}
+	latest := slots[0]
+	for _, slot := range slots[1:] {
+		if slot.LastUpdateAt > latest.LastUpdateAt {
+			latest = slot
+		}
+	}
+	return latest.GameID, nil
+}
+
+func (s *Service) CreateSlot(ctx context.Context, userID, name string) (string, error) {
+	unlock := s.lockUser(userID)
+	defer unlock()
+	slots, err := s.checkSlotLimit(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(name) == "" {
+		name = "Сохранение " + strconv.Itoa(len(slots)+1)
+	}
+	name, err = normalizeSlotName(name)
+	if err != nil {
+		return "", err
+	}
+	game := domain.NewGameState(userID, uuid.NewString(), s.clock.Now().Unix())
+	game.Name = name
+	if err := s.repo.Save(ctx, game); err != nil {
+		return "", err
+	}
+	s.logger.Info().Str("user_id", userID).Str("game_id", game.GameID).Msg("slot created")
+	return game.GameID, nil
+}
+
+func (s *Service) RenameSlot(ctx context.Context, userID, gameID, name string) error {
+	name, err := normalizeSlotName(name)
+	if err != nil {
+		return err
+	}
+	// сохранение пишет название, поэтому снимок, снятый до переименования,
+	// не должен попасть в базу после него
+	unlock := s.lockSlot(userID + "/" + gameID)
+	defer unlock()
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	if err := s.repo.Rename(ctx, userID, gameID, name); err != nil {
+		return err
+	}
+	if game := s.loaded(userID + "/" + gameID); game != nil {
+		game.Mu.Lock()
+		game.Name = name
+		game.Mu.Unlock()
+	}
+	return nil
+}
+
+func (s *Service) DuplicateSlot(ctx context.Context, userID, gameID string) (string, error) {
+	unlock := s.lockUser(userID)
+	defer unlock()
+	if _, err := s.checkSlotLimit(ctx, userID); err != nil {
+		return "", err
+	}
+	source, err := s.SlotState(ctx, userID, gameID)
+	if err != nil {
+		return "", err
+	}
+	name := source.Name
+	if name == "" {
+		name = "Сохранение"
+	}
+	source.GameID = uuid.NewString()
+	source.Name = truncateSlotName(name + " (копия)")
+	source.CreatedAt = s.clock.Now().Unix()
+	source.OpenLedger(domain.ReasonOpening, source.CreatedAt)
+	if err := s.repo.Save(ctx, source); err != nil {
+		return "", err
+	}
+	s.logger.Info().Str("user_id", userID).Str("game_id", gameID).Str("copy_id", source.GameID).Msg("slot duplicated")
+	return source.GameID, nil
+}
+
+// ExportSlot - подписанный файл сохранения
+func (s *Service) ExportSlot(ctx context.Context, userID, gameID string) ([]byte, error) {
+	if len(s.config.SaveSigningKey) == 0 {
+		s.logger.Error().Msg("save signing key is not configured")
+		return nil, errs.ErrServer
+	}
+	game, err := s.SlotState(ctx, userID, gameID)
+	if err != nil {
+		return nil, err
+	}
+	return savefile.Encode(game, s.config.SaveSigningKey)
+}
+
+// ImportSlot - файл загружается в новый слот. Чужие файлы принимаются только
+// при anyOwner: поддержка воспроизводит у себя сохранения из баг-репортов
+func (s *Service) ImportSlot(ctx context.Context, userID string, data []byte, anyOwner bool) (string, error) {
+	if len(s.config.SaveSigningKey) == 0 {
+		s.logger.Error().Msg("save signing key is not configured")
+		return "", errs.ErrServer
+	}
+	owner, game, err := savefile.Decode(data, s.config.SaveSigningKey)
+	if err != nil {
+		s.logger.Warn().Err(err).Str("user_id", userID).Msg("rejected save file")
+		return "", err
+	}
+	if owner != userID && !anyOwner {
+		return "", errs.ErrSaveOwner
+	}
+	unlock := s.lockUser(userID)
+	defer unlock()
+	if _, err := s.checkSlotLimit(ctx, userID); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(game.Name)
+	if name == "" {
+		name = "Импорт"
+	}
+	game.UserID = userID
+	game.GameID = uuid.NewString()
+	game.Name = truncateSlotName(name)
+	game.CreatedAt = s.clock.Now().Unix()
+	game.OpenLedger(domain.ReasonImport, game.CreatedAt)
+	if err := s.repo.Save(ctx, game); err != nil {
+		return "", err
+	}
+	s.logger.Info().Str("user_id", userID).Str("owner_id", owner).Str("game_id", game.GameID).Msg("slot imported")
+	return game.GameID, nil
+}
+
+// DeleteSlot - игра выгружается из памяти без сохранения, иначе SaveAll вернёт её в базу
+func (s *Service) DeleteSlot(ctx context.Context, userID, gameID string) error {
+	// без блокировок параллельная загрузка вернула бы игру в цикл, а сброс
+	// записал бы снимок уже после удаления строки
+	id := userID + "/" + gameID
+	unlock := s.lockSlot(id)
+	defer unlock()
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.loop.Unregister(id)
+	s.mu.Lock()
+	delete(s.games, id)
+	s.mu.Unlock()
+
+	s.releaseLease(ctx, userID, gameID)
+
+	if err := s.repo.Delete(ctx, userID, gameID); err != nil {
+		return err
+	}
+	s.logger.Info().Str("user_id", userID).Str("game_id", gameID).Msg("slot deleted")
+	return nil
+}
+
+// HasSlot - проверка, что выбранный слот принадлежит пользователю
+func (s *Service) HasSlot(ctx context.Context, userID, gameID string) (bool, error) {
+	slots, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, slot := range slots {
+		if slot.GameID == gameID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SlotState - копия игры: из памяти, если она активна, иначе из базы
+func (s *Service) SlotState(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
+	s.mu.RLock()
+	active, ok := s.games[userID+"/"+gameID]
+	s.mu.RUnlock()
+	if ok {
+		s.advance(active)
+		return active.Clone(), nil
+	}
+	return s.repo.Load(ctx, userID, gameID)
+}
+
+// Restore - замена сохранения целиком (откат). Активная игра выгружается без
+// сохранения, HUD-подписчики отключаются и при переподключении загрузят новое состояние.
+// Откат намеренно перезаписывает базу, поэтому версия берётся из текущей строки.
+// Слот и аренда держатся до записи: ни этот, ни другой инстанс не загрузит
+// старое состояние между чтением версии и сохранением
+func (s *Service) Restore(ctx context.Context, game *domain.GameState) error {
+	id := game.UserID + "/" + game.GameID
+	unlock := s.lockSlot(id)
+	defer unlock()
+	if s.leases != nil {
+		if err := s.leases.Acquire(ctx, game.UserID, game.GameID); err != nil {
+			return err
+		}
+	}
+	defer s.releaseLease(ctx, game.UserID, game.GameID)
+
+	s.loop.Unregister(id)
+	s.mu.Lock()
+	delete(s.games, id)
+	s.mu.Unlock()
+	current, err := s.repo.Load(ctx, game.UserID, game.GameID)
+	if err != nil && !errors.Is(err, errs.ErrGameNotFound) {
+		return err
+	}
+	var previous int64
+	if current != nil {
+		game.Version = current.Version
+		previous = current.Balance
+	}
+	game.Ledger = nil
+	game.RecordLedger(game.Balance-previous, domain.ReasonRestore, "", s.clock.Now().Unix())
+	return s.repo.Save(ctx, game)
+}
+
+// checkSlotLimit вызывается под lockUser до записи нового слота,
+// иначе параллельные запросы пройдут проверку с одним и тем же счётчиком
+func (s *Service) checkSlotLimit(ctx context.Context, userID string) ([]SaveSlot, error) {
+	slots, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if s.config != nil && len(slots) >= s.config.MaxSaveSlots {
+		return nil, errs.ErrSlotLimit
+	}
+	return slots, nil
+}
+
+func normalizeSlotName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxSlotNameLength {
+		return "", errs.ErrSlotName
+	}
+	return name, nil
+}
+
+func truncateSlotName(name string) string {
+	runes := []rune(name)
+	if len(runes) > maxSlotNameLength {
+		return string(runes[:maxSlotNameLength])
+	}
+	return name
+}
+
+// IsSlotError - ошибки, которые показываются игроку на экране сохранений
+func IsSlotError(err error) bool {
+	for _, target := range []error{
+		errs.ErrSlotLimit, errs.ErrSlotName, errs.ErrGameNotFound,
+		errs.ErrSaveSignature, errs.ErrSaveVersion, errs.ErrSaveOwner,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
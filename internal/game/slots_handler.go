@@ -0,0 +1,210 @@
+package game
+
+import (
+	"errors"
+	"io"
+	"miners_game/pkg/errs"
+	"miners_game/pkg/tadapter"
+	"miners_game/views"
+	"miners_game/views/widgets"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+	"github.com/rs/zerolog"
+)
+
+// maxSaveFileSize - с запасом на 20 майнеров и все предметы
+const maxSaveFileSize = 64 << 10
+
+func (h *Handler) requireUser(c *fiber.Ctx) error {
+	if userID, _ := c.Locals("user_id").(string); userID == "" {
+		return c.Redirect("/login")
+	}
+	return c.Next()
+}
+
+func (h *Handler) slots(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	cards, err := h.slotCards(c)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed listSlots service")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	component := views.Slots(cards, h.gameService.config.MaxSaveSlots)
+	return tadapter.Render(c, component, fiber.StatusOK)
+}
+
+func (h *Handler) createSlot(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	_, err := h.gameService.CreateSlot(c.UserContext(), userID, c.FormValue("name"))
+	return h.renderSlots(c, err)
+}
+
+func (h *Handler) renameSlot(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	err := h.gameService.RenameSlot(c.UserContext(), userID, c.Params("id"), c.FormValue("name"))
+	return h.renderSlots(c, err)
+}
+
+func (h *Handler) duplicateSlot(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	_, err := h.gameService.DuplicateSlot(c.UserContext(), userID, c.Params("id"))
+	return h.renderSlots(c, err)
+}
+
+func (h *Handler) deleteSlot(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	userID := c.Locals("user_id").(string)
+	gameID := c.Params("id")
+
+	err := h.gameService.DeleteSlot(c.UserContext(), userID, gameID)
+	if err == nil {
+		sess := c.Locals("sess").(*session.Session)
+		if current, _ := sess.Get("game_id").(string); current == gameID {
+			sess.Delete("game_id")
+			if err := sess.Save(); err != nil {
+				logger.Error().Err(err).Msg("failed to save session")
+			}
+		}
+	}
+	return h.renderSlots(c, err)
+}
+
+func (h *Handler) exportSlot(c *fiber.Ctx) error {
+	return h.sendExport(c, c.Locals("user_id").(string), c.Params("id"))
+}
+
+// adminExport - выгрузка любой игры для поддержки
+func (h *Handler) adminExport(c *fiber.Ctx) error {
+	return h.sendExport(c, c.Params("userID"), c.Params("gameID"))
+}
+
+// adminGrant - начисление баланса, попадает в журнал экономики с id администратора
+func (h *Handler) adminGrant(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	adminID := c.Locals("user_id").(string)
+	amount, err := strconv.ParseInt(c.FormValue("amount"), 10, 64)
+	if err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	if err := h.gameService.Grant(c.UserContext(), c.Params("userID"), c.Params("gameID"), amount, adminID); err != nil {
+		switch {
+		case errors.Is(err, errs.ErrBadRequest):
+			return c.SendStatus(fiber.StatusBadRequest)
+		case errors.Is(err, errs.ErrGameNotFound):
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		logger.Error().Err(err).Msg("failed grant service")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *Handler) sendExport(c *fiber.Ctx, userID, gameID string) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	data, err := h.gameService.ExportSlot(c.UserContext(), userID, gameID)
+	if err != nil {
+		if errors.Is(err, errs.ErrGameNotFound) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		logger.Error().Err(err).Msg("failed exportSlot service")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	c.Attachment("miners-" + gameID + ".json")
+	return c.Send(data)
+}
+
+func (h *Handler) importSlot(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	header, err := c.FormFile("file")
+	if err != nil {
+		return h.renderSlots(c, errs.ErrSaveSignature)
+	}
+	if header.Size > maxSaveFileSize {
+		return h.renderSlots(c, errs.ErrSaveSignature)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return h.renderSlots(c, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxSaveFileSize))
+	if err != nil {
+		return h.renderSlots(c, err)
+	}
+	isSupport := slices.Contains(h.adminIDs, userID)
+	_, err = h.gameService.ImportSlot(c.UserContext(), userID, data, isSupport)
+	return h.renderSlots(c, err)
+}
+
+func (h *Handler) playSlot(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	userID := c.Locals("user_id").(string)
+	gameID := c.Params("id")
+
+	ok, err := h.gameService.HasSlot(c.UserContext(), userID, gameID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed hasSlot service")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	sess := c.Locals("sess").(*session.Session)
+	sess.Set("game_id", gameID)
+	if err := sess.Save(); err != nil {
+		logger.Error().Err(err).Msg("failed to save session")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	c.Set("HX-Redirect", "/game")
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// renderSlots - после любого действия список перерисовывается целиком, ошибка показывается над ним
+func (h *Handler) renderSlots(c *fiber.Ctx, actionErr error) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	notice := ""
+	if actionErr != nil {
+		if !IsSlotError(actionErr) {
+			logger.Error().Err(actionErr).Msg("failed slot action")
+		}
+		notice = actionErr.Error()
+	}
+	cards, err := h.slotCards(c)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed listSlots service")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	component := widgets.SlotList(cards, notice)
+	return tadapter.Render(c, component, fiber.StatusOK)
+}
+
+func (h *Handler) slotCards(c *fiber.Ctx) ([]widgets.SlotCard, error) {
+	userID := c.Locals("user_id").(string)
+	sess := c.Locals("sess").(*session.Session)
+	current, _ := sess.Get("game_id").(string)
+
+	slots, err := h.gameService.ListSlots(c.UserContext(), userID)
+	if err != nil {
+		return nil, err
+	}
+	cards := make([]widgets.SlotCard, 0, len(slots))
+	for _, slot := range slots {
+		name := slot.Name
+		if name == "" {
+			name = "Без названия"
+		}
+		cards = append(cards, widgets.SlotCard{
+			ID:         slot.GameID,
+			Name:       name,
+			Balance:    strconv.FormatInt(slot.Balance, 10),
+			LastPlayed: time.Unix(slot.LastUpdateAt, 0).Format("02.01.2006 15:04"),
+			Current:    slot.GameID == current,
+		})
+	}
+	return cards, nil
+}
@@ -0,0 +1,11 @@
+package lease
+
+import "context"
+
+type ILeaseRepository interface {
+	Acquire(ctx context.Context, lease Lease, now int64) (bool, error)
+	RequestHandoff(ctx context.Context, userID, gameID, owner string) error
+	Renew(ctx context.Context, owner string, expiresAt int64) ([]Lease, error)
+	Release(ctx context.Context, userID, gameID, owner string) error
+	ReleaseAll(ctx context.Context, owner string) (int64, error)
+}
@@ -0,0 +1,34 @@
+package lease
+
+import "github.com/prometheus/client_golang/prometheus"
+
+type Metrics struct {
+	LeasesHeld      prometheus.Gauge
+	HandoffsTotal   prometheus.Counter
+	HandoffTimeouts prometheus.Counter
+	LeasesLostTotal prometheus.Counter
+}
+
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		LeasesHeld: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "game_leases_held",
+			Help: "Games leased by this instance at the last renewal",
+		}),
+		HandoffsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "game_lease_handoffs_total",
+			Help: "Total handoffs requested from other instances",
+		}),
+		HandoffTimeouts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "game_lease_handoff_timeouts_total",
+			Help: "Total handoffs the owner did not complete in time",
+		}),
+		LeasesLostTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "game_leases_lost_total",
+			Help: "Total games unloaded because their lease expired or was taken",
+		}),
+	}
+	reg.MustRegister(m.LeasesHeld, m.HandoffsTotal, m.HandoffTimeouts, m.LeasesLostTotal)
+
+	return m
+}
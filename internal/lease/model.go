@@ -0,0 +1,11 @@
+package lease
+
+// Lease - аренда игры инстансом Owner до ExpiresAt. Handoff - другой инстанс
+// ждёт, когда владелец сохранит игру и отпустит её
+type Lease struct {
+	UserID    string
+	GameID    string
+	Owner     string
+	ExpiresAt int64
+	Handoff   bool
+}
@@ -0,0 +1,131 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"miners_game/pkg/errs"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+type Repository struct {
+	dbPool *pgxpool.Pool
+	logger zerolog.Logger
+}
+
+type RepositoryDeps struct {
+	DbPool *pgxpool.Pool
+	Logger zerolog.Logger
+}
+
+func NewRepository(deps RepositoryDeps) *Repository {
+	return &Repository{
+		dbPool: deps.DbPool,
+		logger: deps.Logger,
+	}
+}
+
+// Acquire - берёт свободную или истёкшую аренду, своя продлевается.
+// false - игрой владеет другой живой инстанс
+func (r *Repository) Acquire(ctx context.Context, lease Lease, now int64) (bool, error) {
+	query := `
+		INSERT INTO game_leases (user_id, game_id, owner, expires_at)
+		VALUES (@user_id, @game_id, @owner, @expires_at)
+		ON CONFLICT (user_id, game_id) DO UPDATE
+		SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at, handoff = FALSE
+		WHERE game_leases.owner = EXCLUDED.owner OR game_leases.expires_at < @now
+		RETURNING owner
+	`
+	var owner string
+	err := r.dbPool.QueryRow(ctx, query, pgx.NamedArgs{
+		"user_id":    lease.UserID,
+		"game_id":    lease.GameID,
+		"owner":      lease.Owner,
+		"expires_at": lease.ExpiresAt,
+		"now":        now,
+	}).Scan(&owner)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", lease.UserID).Str("game_id", lease.GameID).Msg("failed to acquire lease")
+		return false, errs.ErrServer
+	}
+	return true, nil
+}
+
+func (r *Repository) RequestHandoff(ctx context.Context, userID, gameID, owner string) error {
+	query := `
+		UPDATE game_leases SET handoff = TRUE
+		WHERE user_id = @user_id AND game_id = @game_id AND owner <> @owner
+	`
+	if _, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+		"game_id": gameID,
+		"owner":   owner,
+	}); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to request handoff")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+// Renew - продлевает все аренды владельца одним запросом и возвращает их.
+// Игры, которых нет в ответе, владелец потерял
+func (r *Repository) Renew(ctx context.Context, owner string, expiresAt int64) ([]Lease, error) {
+	query := `
+		UPDATE game_leases SET expires_at = @expires_at
+		WHERE owner = @owner
+		RETURNING user_id, game_id, owner, expires_at, handoff
+	`
+	rows, err := r.dbPool.Query(ctx, query, pgx.NamedArgs{
+		"owner":      owner,
+		"expires_at": expiresAt,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to renew leases")
+		return nil, errs.ErrServer
+	}
+	defer rows.Close()
+
+	leases := []Lease{}
+	for rows.Next() {
+		var l Lease
+		if err := rows.Scan(&l.UserID, &l.GameID, &l.Owner, &l.ExpiresAt, &l.Handoff); err != nil {
+			r.logger.Error().Err(err).Msg("failed to scan lease")
+			return nil, errs.ErrServer
+		}
+		leases = append(leases, l)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("failed to renew leases")
+		return nil, errs.ErrServer
+	}
+	return leases, nil
+}
+
+func (r *Repository) Release(ctx context.Context, userID, gameID, owner string) error {
+	query := `DELETE FROM game_leases WHERE user_id = @user_id AND game_id = @game_id AND owner = @owner`
+	if _, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+		"game_id": gameID,
+		"owner":   owner,
+	}); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to release lease")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *Repository) ReleaseAll(ctx context.Context, owner string) (int64, error) {
+	tag, err := r.dbPool.Exec(ctx, `DELETE FROM game_leases WHERE owner = @owner`, pgx.NamedArgs{
+		"owner": owner,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("owner", owner).Msg("failed to release leases")
+		return 0, errs.ErrServer
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,152 @@
+package lease
+
+import (
+	"context"
+	"miners_game/config"
+	"miners_game/pkg/clock"
+	"miners_game/pkg/errs"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// handoffPoll - как часто ждущий инстанс проверяет, отпустил ли владелец игру
+const handoffPoll = 200 * time.Millisecond
+
+// Service - аренды игр этого инстанса. held - игры, взятые через Acquire и ещё
+// не отпущенные, по нему Renew узнаёт потерянные аренды
+type Service struct {
+	repo    ILeaseRepository
+	config  *config.LeaseConfig
+	clock   clock.Clock
+	metrics *Metrics
+	logger  zerolog.Logger
+
+	held map[string]struct{}
+	mu   sync.Mutex
+}
+
+type ServiceDeps struct {
+	Repo    ILeaseRepository
+	Config  *config.LeaseConfig
+	Clock   clock.Clock
+	Metrics *Metrics
+	Logger  zerolog.Logger
+}
+
+func NewService(deps ServiceDeps) *Service {
+	return &Service{
+		repo:    deps.Repo,
+		config:  deps.Config,
+		clock:   clock.OrReal(deps.Clock),
+		metrics: deps.Metrics,
+		logger:  deps.Logger,
+		held:    make(map[string]struct{}),
+	}
+}
+
+// Acquire - аренда игры перед загрузкой. Если игрой владеет другой инстанс,
+// он получает запрос передачи, а Acquire ждёт, пока игру сохранят и отпустят.
+// Ожидание ограничено HandoffWait и дедлайном ctx
+func (s *Service) Acquire(ctx context.Context, userID, gameID string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.config.HandoffWait)
+	defer cancel()
+
+	requested := false
+	for {
+		now := s.clock.Now()
+		ok, err := s.repo.Acquire(ctx, Lease{
+			UserID:    userID,
+			GameID:    gameID,
+			Owner:     s.config.InstanceID,
+			ExpiresAt: now.Add(s.config.TTL).Unix(),
+		}, now.Unix())
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if ok {
+			s.mu.Lock()
+			s.held[key(userID, gameID)] = struct{}{}
+			s.mu.Unlock()
+			return nil
+		}
+		if !requested && err == nil {
+			if err := s.repo.RequestHandoff(ctx, userID, gameID, s.config.InstanceID); err != nil {
+				return err
+			}
+			requested = true
+			if s.metrics != nil {
+				s.metrics.HandoffsTotal.Inc()
+			}
+			s.logger.Info().Str("user_id", userID).Str("game_id", gameID).Msg("handoff requested")
+		}
+		select {
+		case <-time.After(handoffPoll):
+		case <-ctx.Done():
+			if s.metrics != nil {
+				s.metrics.HandoffTimeouts.Inc()
+			}
+			s.logger.Warn().Str("user_id", userID).Str("game_id", gameID).Msg("handoff timed out")
+			return errs.ErrGameLeased
+		}
+	}
+}
+
+// Renew - продление аренд. lost - игры, аренду которых перехватил другой
+// инстанс, handoffs - игры, которые просят отдать. Ключи - userID/gameID
+func (s *Service) Renew(ctx context.Context) (lost, handoffs []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	leases, err := s.repo.Renew(ctx, s.config.InstanceID, s.clock.Now().Add(s.config.TTL).Unix())
+	if err != nil {
+		return nil, nil, err
+	}
+	renewed := make(map[string]bool, len(leases))
+	for _, l := range leases {
+		k := key(l.UserID, l.GameID)
+		if _, ok := s.held[k]; !ok {
+			continue
+		}
+		renewed[k] = true
+		if l.Handoff {
+			handoffs = append(handoffs, k)
+		}
+	}
+	for k := range s.held {
+		if !renewed[k] {
+			lost = append(lost, k)
+			delete(s.held, k)
+		}
+	}
+	if s.metrics != nil {
+		s.metrics.LeasesHeld.Set(float64(len(s.held)))
+		s.metrics.LeasesLostTotal.Add(float64(len(lost)))
+	}
+	return lost, handoffs, nil
+}
+
+func (s *Service) Release(ctx context.Context, userID, gameID string) error {
+	s.mu.Lock()
+	delete(s.held, key(userID, gameID))
+	s.mu.Unlock()
+	return s.repo.Release(ctx, userID, gameID, s.config.InstanceID)
+}
+
+// ReleaseAll - при остановке инстанса, чтобы игры не ждали истечения TTL
+func (s *Service) ReleaseAll(ctx context.Context) error {
+	s.mu.Lock()
+	s.held = make(map[string]struct{})
+	s.mu.Unlock()
+	count, err := s.repo.ReleaseAll(ctx, s.config.InstanceID)
+	if err != nil {
+		return err
+	}
+	s.logger.Info().Int64("count", count).Msg("leases released")
+	return nil
+}
+
+func key(userID, gameID string) string {
+	return userID + "/" + gameID
+}
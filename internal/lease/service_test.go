@@ -0,0 +1,110 @@
+package lease_test
+
+import (
+	"context"
+	"errors"
+	"miners_game/config"
+	"miners_game/internal/lease"
+	"miners_game/pkg/errs"
+	"testing"
+	"time"
+)
+
+// MockLeaseRepository - одна аренда: owner пустой, пока игра свободна
+type MockLeaseRepository struct {
+	owner   string
+	handoff bool
+	// handoffDelay - через сколько после запроса владелец отпустит игру, 0 - никогда
+	handoffDelay time.Duration
+	releaseAt    time.Time
+}
+
+func (m *MockLeaseRepository) Acquire(ctx context.Context, l lease.Lease, now int64) (bool, error) {
+	if !m.releaseAt.IsZero() && time.Now().After(m.releaseAt) {
+		m.owner, m.releaseAt = "", time.Time{}
+	}
+	if m.owner != "" && m.owner != l.Owner {
+		return false, nil
+	}
+	m.owner = l.Owner
+	return true, nil
+}
+
+func (m *MockLeaseRepository) RequestHandoff(ctx context.Context, userID, gameID, owner string) error {
+	m.handoff = true
+	if m.handoffDelay > 0 {
+		m.releaseAt = time.Now().Add(m.handoffDelay)
+	}
+	return nil
+}
+
+func (m *MockLeaseRepository) Renew(ctx context.Context, owner string, expiresAt int64) ([]lease.Lease, error) {
+	if m.owner != owner {
+		return nil, nil
+	}
+	return []lease.Lease{{UserID: "testUserID", GameID: "testGameID", Owner: owner, Handoff: m.handoff}}, nil
+}
+
+func (m *MockLeaseRepository) Release(ctx context.Context, userID, gameID, owner string) error {
+	if m.owner == owner {
+		m.owner = ""
+	}
+	return nil
+}
+
+func (m *MockLeaseRepository) ReleaseAll(ctx context.Context, owner string) (int64, error) {
+	return 0, m.Release(ctx, "", "", owner)
+}
+
+func newLeaseService(repo *MockLeaseRepository, wait time.Duration) *lease.Service {
+	return lease.NewService(lease.ServiceDeps{
+		Repo: repo,
+		Config: &config.LeaseConfig{
+			InstanceID:  "instanceA",
+			TTL:         time.Minute,
+			HandoffWait: wait,
+		},
+	})
+}
+
+func TestAcquireWaitsForHandoff(t *testing.T) {
+	repo := &MockLeaseRepository{owner: "instanceB", handoffDelay: 50 * time.Millisecond}
+	leaseService := newLeaseService(repo, time.Second)
+
+	if err := leaseService.Acquire(context.Background(), "testUserID", "testGameID"); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if !repo.handoff || repo.owner != "instanceA" {
+		t.Fatalf("expected lease to be handed off, got owner %q", repo.owner)
+	}
+}
+
+func TestAcquireTimesOut(t *testing.T) {
+	repo := &MockLeaseRepository{owner: "instanceB"}
+	leaseService := newLeaseService(repo, 100*time.Millisecond)
+
+	err := leaseService.Acquire(context.Background(), "testUserID", "testGameID")
+	if !errors.Is(err, errs.ErrGameLeased) {
+		t.Fatalf("expected ErrGameLeased, got %v:", err)
+	}
+}
+
+func TestRenewReportsLostLease(t *testing.T) {
+	repo := &MockLeaseRepository{}
+	leaseService := newLeaseService(repo, time.Second)
+	if err := leaseService.Acquire(context.Background(), "testUserID", "testGameID"); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+
+	repo.handoff = true
+	_, handoffs, err := leaseService.Renew(context.Background())
+	if err != nil || len(handoffs) != 1 || handoffs[0] != "testUserID/testGameID" {
+		t.Fatalf("expected handoff request, got %v, %v", handoffs, err)
+	}
+
+	repo.owner = "instanceB"
+	lost, _, err := leaseService.Renew(context.Background())
+	if err != nil || len(lost) != 1 {
+		t.Fatalf("expected lost lease, got %v, %v", lost, err)
+	}
+}
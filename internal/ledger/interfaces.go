@@ -0,0 +1,7 @@
+package ledger
+
+import "context"
+
+type ILedgerRepository interface {
+	Drifts(ctx context.Context, limit int) ([]Drift, error)
+}
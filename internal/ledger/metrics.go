@@ -0,0 +1,24 @@
+package ledger
+
+import "github.com/prometheus/client_golang/prometheus"
+
+type Metrics struct {
+	DriftGames      prometheus.Gauge
+	ReconcileFailed prometheus.Counter
+}
+
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		DriftGames: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ledger_drift_games",
+			Help: "Games whose balance differs from the ledger sum at the last reconciliation",
+		}),
+		ReconcileFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ledger_reconcile_failed_total",
+			Help: "Total failed ledger reconciliations",
+		}),
+	}
+	reg.MustRegister(m.DriftGames, m.ReconcileFailed)
+
+	return m
+}
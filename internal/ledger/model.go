@@ -0,0 +1,13 @@
+package ledger
+
+// Drift - игра, у которой сохранённый баланс не совпадает с суммой журнала
+type Drift struct {
+	UserID    string
+	GameID    string
+	Balance   int64
+	LedgerSum int64
+}
+
+func (d Drift) Delta() int64 {
+	return d.Balance - d.LedgerSum
+}
@@ -0,0 +1,67 @@
+package ledger
+
+import (
+	"context"
+	"miners_game/pkg/errs"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+type Repository struct {
+	dbPool *pgxpool.Pool
+	logger zerolog.Logger
+}
+
+type RepositoryDeps struct {
+	DbPool *pgxpool.Pool
+	Logger zerolog.Logger
+}
+
+func NewRepository(deps RepositoryDeps) *Repository {
+	return &Repository{
+		dbPool: deps.DbPool,
+		logger: deps.Logger,
+	}
+}
+
+// Drifts - игры, баланс которых расходится с суммой журнала. Журнал пишется
+// в одной транзакции с балансом, поэтому любое расхождение - ошибка
+func (r *Repository) Drifts(ctx context.Context, limit int) ([]Drift, error) {
+	query := `
+		SELECT g.user_id, g.game_id, g.balance, COALESCE(l.total, 0)
+		FROM games g
+		LEFT JOIN (
+			SELECT user_id, game_id, SUM(amount)::BIGINT AS total
+			FROM game_ledger
+			GROUP BY user_id, game_id
+		) l ON l.user_id = g.user_id AND l.game_id = g.game_id
+		WHERE g.balance <> COALESCE(l.total, 0)
+		ORDER BY g.user_id, g.game_id
+		LIMIT @limit
+	`
+	rows, err := r.dbPool.Query(ctx, query, pgx.NamedArgs{
+		"limit": limit,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to reconcile ledger")
+		return nil, errs.ErrServer
+	}
+	defer rows.Close()
+
+	drifts := []Drift{}
+	for rows.Next() {
+		var d Drift
+		if err := rows.Scan(&d.UserID, &d.GameID, &d.Balance, &d.LedgerSum); err != nil {
+			r.logger.Error().Err(err).Msg("failed to scan ledger drift")
+			return nil, errs.ErrServer
+		}
+		drifts = append(drifts, d)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("failed to reconcile ledger")
+		return nil, errs.ErrServer
+	}
+	return drifts, nil
+}
@@ -0,0 +1,56 @@
+package ledger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// driftLimit - сколько расхождений разбирать за один проход, остальное покажет метрика
+const driftLimit = 1000
+
+type Service struct {
+	repo    ILedgerRepository
+	metrics *Metrics
+	logger  zerolog.Logger
+}
+
+type ServiceDeps struct {
+	Repo    ILedgerRepository
+	Metrics *Metrics
+	Logger  zerolog.Logger
+}
+
+func NewService(deps ServiceDeps) *Service {
+	return &Service{
+		repo:    deps.Repo,
+		metrics: deps.Metrics,
+		logger:  deps.Logger,
+	}
+}
+
+// Reconcile - сверка балансов с журналом. Каждое расхождение пишется
+// в лог с разницей, число расхождений - в метрику
+func (s *Service) Reconcile(ctx context.Context) ([]Drift, error) {
+	drifts, err := s.repo.Drifts(ctx, driftLimit)
+	if err != nil {
+		if s.metrics != nil {
+			s.metrics.ReconcileFailed.Inc()
+		}
+		return nil, err
+	}
+	for _, d := range drifts {
+		s.logger.Warn().
+			Str("user_id", d.UserID).
+			Str("game_id", d.GameID).
+			Int64("balance", d.Balance).
+			Int64("ledger_sum", d.LedgerSum).
+			Int64("delta", d.Delta()).
+			Msg("ledger drift")
+	}
+	if s.metrics != nil {
+		s.metrics.DriftGames.Set(float64(len(drifts)))
+	}
+	s.logger.Info().Int("drifts", len(drifts)).Msg("ledger reconciled")
+	return drifts, nil
+}
@@ -0,0 +1,45 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"miners_game/internal/ledger"
+	"miners_game/pkg/errs"
+	"testing"
+)
+
+type MockLedgerRepository struct {
+	MockDrifts func(limit int) ([]ledger.Drift, error)
+}
+
+func (m *MockLedgerRepository) Drifts(ctx context.Context, limit int) ([]ledger.Drift, error) {
+	return m.MockDrifts(limit)
+}
+
+func TestReconcileReportsDrift(t *testing.T) {
+	repo := MockLedgerRepository{
+		MockDrifts: func(limit int) ([]ledger.Drift, error) {
+			return []ledger.Drift{{UserID: "testUserID", GameID: "testGameID", Balance: 150, LedgerSum: 100}}, nil
+		},
+	}
+	ledgerService := ledger.NewService(ledger.ServiceDeps{Repo: &repo})
+	drifts, err := ledgerService.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if len(drifts) != 1 || drifts[0].Delta() != 50 {
+		t.Fatalf("expected one drift of 50, got %+v", drifts)
+	}
+}
+
+func TestReconcileRepositoryError(t *testing.T) {
+	repo := MockLedgerRepository{
+		MockDrifts: func(limit int) ([]ledger.Drift, error) {
+			return nil, errs.ErrServer
+		},
+	}
+	ledgerService := ledger.NewService(ledger.ServiceDeps{Repo: &repo})
+	if _, err := ledgerService.Reconcile(context.Background()); !errors.Is(err, errs.ErrServer) {
+		t.Fatalf("expected ErrServer, got %v:", err)
+	}
+}
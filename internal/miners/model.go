@@ -4,7 +4,6 @@ import (
 	"miners_game/internal/game/shop"
 	"sort"
 	"strconv"
-	"time"
 
 	"github.com/google/uuid"
 )
@@ -52,11 +51,11 @@ func GetMinerConfig(class string) MinerConfig {
 	return MinerPresets[class]
 }
 
-func NewMiner(class string) *Miner {
+// NewMiner - майнер работает Energy секунд с момента покупки now
+func NewMiner(class string, now int64) *Miner {
 
 	cfg := GetMinerConfig(class)
 
-	now := time.Now().Unix()
 	miner := &Miner{
 		ID:      uuid.NewString(),
 		Class:   class,
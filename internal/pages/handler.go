@@ -44,6 +44,6 @@ func (h *Handler) login(c *fiber.Ctx) error {
 }
 
 func (h *Handler) register(c *fiber.Ctx) error {
-	component := views.Register()
+	component := views.Register(c.Query("ref"))
 	return tadapter.Render(c, component, fiber.StatusOK)
 }
@@ -0,0 +1,110 @@
+package profile
+
+import (
+	"errors"
+	"miners_game/internal/game/equipments"
+	"miners_game/internal/miners"
+	"miners_game/pkg/errs"
+	"miners_game/pkg/tadapter"
+	"miners_game/views"
+	"miners_game/views/widgets"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+type Handler struct {
+	router         fiber.Router
+	profileService *Service
+}
+
+type HandlerDeps struct {
+	Router         fiber.Router
+	ProfileService *Service
+}
+
+func NewHandler(deps HandlerDeps) {
+	h := &Handler{
+		router:         deps.Router,
+		profileService: deps.ProfileService,
+	}
+	h.router.Get("/u/:username", h.profile)
+	h.router.Post("/profile/privacy", h.privacy)
+}
+
+func (h *Handler) profile(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	viewerID, _ := c.Locals("user_id").(string)
+	username := c.Params("username")
+
+	p, err := h.profileService.GetProfile(c.UserContext(), username, viewerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrUserNotFound):
+			return tadapter.Render(c, views.ProfileNotice(err.Error()), fiber.StatusNotFound)
+		case errors.Is(err, errs.ErrProfileHidden):
+			return tadapter.Render(c, views.ProfileNotice(err.Error()), fiber.StatusForbidden)
+		}
+		logger.Error().Err(err).Msg("failed getProfile service")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	component := views.Profile(toProfileProps(p, viewerID))
+	return tadapter.Render(c, component, fiber.StatusOK)
+}
+
+func (h *Handler) privacy(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	userID, _ := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	hidden, _ := strconv.ParseBool(c.FormValue("hidden"))
+	if err := h.profileService.SetHidden(c.UserContext(), userID, hidden); err != nil {
+		logger.Error().Err(err).Msg("failed setHidden service")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return tadapter.Render(c, widgets.PrivacyToggle(hidden), fiber.StatusOK)
+}
+
+func toProfileProps(p *Profile, viewerID string) views.ProfileProps {
+	props := views.ProfileProps{
+		Username: p.User.UserName,
+		IsOwner:  p.User.ID == viewerID,
+		Hidden:   p.User.ProfileHidden,
+		Stage:    "0",
+		Balance:  "0",
+		Income:   "0",
+
+		ReferralCode: p.User.ReferralCode,
+	}
+	if p.Game == nil {
+		return props
+	}
+	game := p.Game
+	now := time.Now().Unix()
+
+	props.HasGame = true
+	props.Stage = game.GetMaxUpgrade()
+	props.Balance = strconv.Itoa(int(game.Balance))
+	props.Income = strconv.Itoa(int(game.CalcIncome(now-1, now)))
+	for _, v := range game.Equipments {
+		if v.Own {
+			props.Equipments = append(props.Equipments, equipments.GetEquipmentConfig(v.Name).Title)
+		}
+	}
+	for _, card := range miners.MinerShopCards() {
+		count := 0
+		for _, m := range game.Miners {
+			if m.Class == card.Name && m.EndAt > now {
+				count++
+			}
+		}
+		props.Miners = append(props.Miners, views.ProfileMiner{
+			Title: card.Title,
+			Count: count,
+		})
+	}
+	return props
+}
@@ -0,0 +1,10 @@
+package profile
+
+import (
+	"context"
+	"miners_game/internal/game/domain"
+)
+
+type IGameViewer interface {
+	ViewGame(ctx context.Context, userID string) (*domain.GameState, error)
+}
@@ -0,0 +1,63 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/user"
+	"miners_game/pkg/errs"
+
+	"github.com/rs/zerolog"
+)
+
+type Profile struct {
+	User *user.User
+	Game *domain.GameState
+}
+
+type Service struct {
+	userRepo user.IUserRepository
+	games    IGameViewer
+	logger   zerolog.Logger
+}
+
+type ServiceDeps struct {
+	UserRepository user.IUserRepository
+	Games          IGameViewer
+	Logger         zerolog.Logger
+}
+
+func NewService(deps ServiceDeps) *Service {
+	return &Service{
+		userRepo: deps.UserRepository,
+		games:    deps.Games,
+		logger:   deps.Logger,
+	}
+}
+
+func (s *Service) GetProfile(ctx context.Context, username, viewerID string) (*Profile, error) {
+	u, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if u.ProfileHidden && u.ID != viewerID {
+		return nil, errs.ErrProfileHidden
+	}
+	game, err := s.games.ViewGame(ctx, u.ID)
+	if err != nil && !errors.Is(err, errs.ErrGameNotFound) {
+		s.logger.Error().Err(err).Str("user_id", u.ID).Msg("failed to view game")
+		return nil, err
+	}
+	return &Profile{
+		User: u,
+		Game: game,
+	}, nil
+}
+
+func (s *Service) SetHidden(ctx context.Context, userID string, hidden bool) error {
+	if err := s.userRepo.SetProfileHidden(ctx, userID, hidden); err != nil {
+		return err
+	}
+	s.logger.Info().Str("user_id", userID).Bool("hidden", hidden).Msg("profile privacy changed")
+	return nil
+}
@@ -0,0 +1,13 @@
+package referral
+
+import (
+	"context"
+	"miners_game/internal/game/domain"
+)
+
+type IReferralRepository interface {
+	Save(ctx context.Context, ref *Referral) error
+	CountByIPSince(ctx context.Context, ip string, since int64) (int, error)
+	Reward(ctx context.Context, refereeID string, refereeAmount, referrerAmount, at int64) (*Referral, error)
+	PendingRewards(ctx context.Context, userID, gameID string) ([]domain.Reward, error)
+}
@@ -0,0 +1,9 @@
+package referral
+
+type Referral struct {
+	RefereeID  string
+	ReferrerID string
+	IP         string
+	CreatedAt  int64
+	RewardedAt int64
+}
@@ -0,0 +1,181 @@
+package referral
+
+import (
+	"context"
+	"miners_game/internal/game/domain"
+	"miners_game/pkg/database"
+	"miners_game/pkg/errs"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+type Repository struct {
+	dbPool  *pgxpool.Pool
+	timeout time.Duration
+	logger  zerolog.Logger
+}
+
+type RepositoryDeps struct {
+	DbPool  *pgxpool.Pool
+	Timeout time.Duration
+	Logger  zerolog.Logger
+}
+
+func NewRepository(deps RepositoryDeps) *Repository {
+	return &Repository{
+		dbPool:  deps.DbPool,
+		timeout: deps.Timeout,
+		logger:  deps.Logger,
+	}
+}
+
+func (r *Repository) Save(ctx context.Context, ref *Referral) error {
+	query := `
+		INSERT INTO referrals (referee_id, referrer_id, ip, created_at)
+		VALUES (@referee_id, @referrer_id, @ip, @created_at)
+		ON CONFLICT (referee_id) DO NOTHING
+	`
+	args := pgx.NamedArgs{
+		"referee_id":  ref.RefereeID,
+		"referrer_id": ref.ReferrerID,
+		"ip":          ref.IP,
+		"created_at":  ref.CreatedAt,
+	}
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.dbPool.Exec(ctx, query, args); err != nil {
+		r.logger.Error().Err(err).Str("user_id", ref.RefereeID).Msg("failed to save referral")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *Repository) CountByIPSince(ctx context.Context, ip string, since int64) (int, error) {
+	query := `
+		SELECT count(*)
+		FROM referrals
+		WHERE ip = @ip AND created_at >= @since
+	`
+	var count int
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if err := r.dbPool.QueryRow(ctx, query, pgx.NamedArgs{
+		"ip":    ip,
+		"since": since,
+	}).Scan(&count); err != nil {
+		r.logger.Error().Err(err).Msg("failed to count referrals by ip")
+		return 0, errs.ErrServer
+	}
+	return count, nil
+}
+
+// Reward - отметка о награде и награды обеим сторонам в одной транзакции.
+// nil, если награды нет или она уже выдана
+func (r *Repository) Reward(ctx context.Context, refereeID string, refereeAmount, referrerAmount, at int64) (*Referral, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	tx, err := r.dbPool.Begin(ctx)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", refereeID).Msg("failed to begin reward transaction")
+		return nil, errs.ErrServer
+	}
+	defer tx.Rollback(ctx)
+
+	ref := Referral{
+		RefereeID:  refereeID,
+		RewardedAt: at,
+	}
+	err = tx.QueryRow(ctx, `
+		UPDATE referrals SET rewarded_at = @at
+		WHERE referee_id = @referee_id AND rewarded_at = 0
+		RETURNING referrer_id, ip, created_at
+	`, pgx.NamedArgs{
+		"referee_id": refereeID,
+		"at":         at,
+	}).Scan(&ref.ReferrerID, &ref.IP, &ref.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error().Err(err).Str("user_id", refereeID).Msg("failed to mark referral rewarded")
+		return nil, errs.ErrServer
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO referral_rewards (user_id, amount, created_at)
+		VALUES (@referee_id, @referee_amount, @at), (@referrer_id, @referrer_amount, @at)
+	`, pgx.NamedArgs{
+		"referee_id":      ref.RefereeID,
+		"referee_amount":  refereeAmount,
+		"referrer_id":     ref.ReferrerID,
+		"referrer_amount": referrerAmount,
+		"at":              at,
+	}); err != nil {
+		r.logger.Error().Err(err).Str("user_id", refereeID).Msg("failed to add referral rewards")
+		return nil, errs.ErrServer
+	}
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error().Err(err).Str("user_id", refereeID).Msg("failed to commit reward transaction")
+		return nil, errs.ErrServer
+	}
+	return &ref, nil
+}
+
+// PendingRewards - награды пользователя, ещё не начисленные в игру gameID.
+// Строки, уже попавшие в сохранённый журнал игр (item из domain.Reward.Item),
+// удаляются, свободные закрепляются за gameID: две игры одного пользователя
+// не получат одну награду
+func (r *Repository) PendingRewards(ctx context.Context, userID, gameID string) ([]domain.Reward, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	tx, err := r.dbPool.Begin(ctx)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to begin rewards transaction")
+		return nil, errs.ErrServer
+	}
+	defer tx.Rollback(ctx)
+
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"game_id": gameID,
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM referral_rewards r
+		WHERE r.user_id = @user_id AND EXISTS (
+			SELECT 1 FROM game_ledger l
+			WHERE l.user_id = r.user_id AND l.item = 'referral:' || r.id
+		)
+	`, args); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to delete credited referral rewards")
+		return nil, errs.ErrServer
+	}
+	rows, err := tx.Query(ctx, `
+		UPDATE referral_rewards SET game_id = @game_id
+		WHERE user_id = @user_id AND game_id IN ('', @game_id)
+		RETURNING id, amount
+	`, args)
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to claim referral rewards")
+		return nil, errs.ErrServer
+	}
+	rewards, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Reward, error) {
+		var reward domain.Reward
+		err := row.Scan(&reward.ID, &reward.Amount)
+		return reward, err
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to scan referral rewards")
+		return nil, errs.ErrServer
+	}
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to commit rewards transaction")
+		return nil, errs.ErrServer
+	}
+	sort.Slice(rewards, func(i, j int) bool {
+		return rewards[i].ID < rewards[j].ID
+	})
+	return rewards, nil
+}
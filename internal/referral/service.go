@@ -0,0 +1,149 @@
+package referral
+
+import (
+	"context"
+	"errors"
+	"miners_game/config"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/user"
+	"miners_game/pkg/clock"
+	"miners_game/pkg/errs"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog"
+)
+
+type Service struct {
+	repo     IReferralRepository
+	userRepo user.IUserRepository
+	config   *config.ReferralConfig
+	clock    clock.Clock
+	logger   zerolog.Logger
+}
+
+type ServiceDeps struct {
+	Repo           IReferralRepository
+	UserRepository user.IUserRepository
+	Config         *config.ReferralConfig
+	Clock          clock.Clock
+	Logger         zerolog.Logger
+}
+
+func NewService(deps ServiceDeps) *Service {
+	return &Service{
+		repo:     deps.Repo,
+		userRepo: deps.UserRepository,
+		config:   deps.Config,
+		clock:    clock.OrReal(deps.Clock),
+		logger:   deps.Logger,
+	}
+}
+
+// Validate - проверяет код до отправки письма, возвращает id пригласившего
+func (s *Service) Validate(ctx context.Context, referralCode, email, ip string) (string, error) {
+	referralCode = strings.ToUpper(strings.TrimSpace(referralCode))
+	if referralCode == "" {
+		return "", nil
+	}
+	referrer, err := s.userRepo.FindByReferralCode(ctx, referralCode)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			return "", errs.ErrReferralNotFound
+		}
+		return "", err
+	}
+	if sameOwnerEmails(referrer.Email, email) {
+		s.logger.Warn().Str("referrer_id", referrer.ID).Msg("referral rejected: similar email")
+		return "", errs.ErrReferralAbuse
+	}
+	if ip != "" && referrer.RegisterIP == ip {
+		s.logger.Warn().Str("referrer_id", referrer.ID).Msg("referral rejected: same ip as referrer")
+		return "", errs.ErrReferralAbuse
+	}
+	since := s.clock.Now().Add(-s.config.IPWindow).Unix()
+	count, err := s.repo.CountByIPSince(ctx, ip, since)
+	if err != nil {
+		return "", err
+	}
+	if count >= s.config.MaxPerIP {
+		s.logger.Warn().Str("referrer_id", referrer.ID).Msg("referral rejected: too many from ip")
+		return "", errs.ErrReferralAbuse
+	}
+	return referrer.ID, nil
+}
+
+func (s *Service) Link(ctx context.Context, referrerID, refereeID, ip string) error {
+	if referrerID == "" {
+		return nil
+	}
+	if referrerID == refereeID {
+		return errs.ErrReferralAbuse
+	}
+	ref := &Referral{
+		RefereeID:  refereeID,
+		ReferrerID: referrerID,
+		IP:         ip,
+		CreatedAt:  s.clock.Now().Unix(),
+	}
+	if err := s.repo.Save(ctx, ref); err != nil {
+		return err
+	}
+	s.logger.Info().Str("referrer_id", referrerID).Str("referee_id", refereeID).Msg("referral linked")
+	return nil
+}
+
+// OnFirstUpgrade - веха приглашённого, награда обеим сторонам выдаётся один раз.
+// Возвращает id пригласившего, если награда выдана сейчас
+func (s *Service) OnFirstUpgrade(ctx context.Context, userID string) (string, error) {
+	now := s.clock.Now().Unix()
+	ref, err := s.repo.Reward(ctx, userID, s.config.RewardReferee, s.config.RewardReferrer, now)
+	if err != nil || ref == nil {
+		return "", err
+	}
+	s.logger.Info().Str("referrer_id", ref.ReferrerID).Str("referee_id", ref.RefereeID).Msg("referral rewarded")
+	return ref.ReferrerID, nil
+}
+
+// PendingRewards - награды для начисления в игру gameID. Строка награды живёт,
+// пока её запись журнала не сохранится, поэтому падение до сохранения её не теряет
+func (s *Service) PendingRewards(ctx context.Context, userID, gameID string) ([]domain.Reward, error) {
+	return s.repo.PendingRewards(ctx, userID, gameID)
+}
+
+// sameOwnerEmails - один ящик с разными алиасами или одна схема имени на том же домене
+func sameOwnerEmails(a, b string) bool {
+	localA, domainA := splitEmail(a)
+	localB, domainB := splitEmail(b)
+	if domainA != domainB {
+		return false
+	}
+	if localA == localB {
+		return true
+	}
+	baseA, baseB := stripDigits(localA), stripDigits(localB)
+	return baseA != "" && baseA == baseB
+}
+
+func splitEmail(email string) (string, string) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email, ""
+	}
+	local, domain := email[:at], email[at+1:]
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+	if domain == "googlemail.com" {
+		domain = "gmail.com"
+	}
+	if domain == "gmail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local, domain
+}
+
+func stripDigits(s string) string {
+	return strings.TrimFunc(s, unicode.IsDigit)
+}
@@ -0,0 +1,139 @@
+package referral_test
+
+import (
+	"context"
+	"errors"
+	"miners_game/config"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/referral"
+	"miners_game/internal/user"
+	"miners_game/pkg/errs"
+	"testing"
+	"time"
+)
+
+type MockReferralRepository struct {
+	IPCount  int
+	Referral *referral.Referral
+	Rewards  map[string]int64
+}
+
+func (m *MockReferralRepository) Save(ctx context.Context, ref *referral.Referral) error {
+	m.Referral = ref
+	return nil
+}
+
+func (m *MockReferralRepository) CountByIPSince(ctx context.Context, ip string, since int64) (int, error) {
+	return m.IPCount, nil
+}
+
+func (m *MockReferralRepository) Reward(ctx context.Context, refereeID string, refereeAmount, referrerAmount, at int64) (*referral.Referral, error) {
+	if m.Referral == nil || m.Referral.RewardedAt != 0 {
+		return nil, nil
+	}
+	m.Referral.RewardedAt = at
+	m.Rewards[m.Referral.RefereeID] += refereeAmount
+	m.Rewards[m.Referral.ReferrerID] += referrerAmount
+	return m.Referral, nil
+}
+
+func (m *MockReferralRepository) PendingRewards(ctx context.Context, userID, gameID string) ([]domain.Reward, error) {
+	if m.Rewards[userID] == 0 {
+		return nil, nil
+	}
+	return []domain.Reward{{ID: 1, Amount: m.Rewards[userID]}}, nil
+}
+
+type MockUserRepository struct {
+	user.IUserRepository
+	Referrer *user.User
+}
+
+func (m *MockUserRepository) FindByReferralCode(ctx context.Context, referralCode string) (*user.User, error) {
+	if m.Referrer == nil || m.Referrer.ReferralCode != referralCode {
+		return nil, errs.ErrUserNotFound
+	}
+	return m.Referrer, nil
+}
+
+func newTestService(repo *MockReferralRepository) *referral.Service {
+	return referral.NewService(referral.ServiceDeps{
+		Repo: repo,
+		UserRepository: &MockUserRepository{
+			Referrer: &user.User{
+				ID:           "referrerID",
+				Email:        "ivan1@mail.ru",
+				ReferralCode: "ABCD2345",
+				RegisterIP:   "10.0.0.1",
+			},
+		},
+		Config: &config.ReferralConfig{
+			RewardReferrer: 1000,
+			RewardReferee:  500,
+			IPWindow:       time.Hour,
+			MaxPerIP:       2,
+		},
+	})
+}
+
+func TestValidateSuccess(t *testing.T) {
+	referralService := newTestService(&MockReferralRepository{})
+	referrerID, err := referralService.Validate(context.Background(), "abcd2345", "petr@gmail.com", "10.0.0.2")
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if referrerID != "referrerID" {
+		t.Fatalf("expected referrerID, got %q", referrerID)
+	}
+}
+
+func TestValidateAbuse(t *testing.T) {
+	cases := map[string]struct {
+		email   string
+		ip      string
+		ipCount int
+	}{
+		"same email pattern": {email: "ivan2@mail.ru", ip: "10.0.0.2"},
+		"same email alias":   {email: "ivan1+alt@mail.ru", ip: "10.0.0.2"},
+		"same ip":            {email: "petr@gmail.com", ip: "10.0.0.1"},
+		"ip window":          {email: "petr@gmail.com", ip: "10.0.0.2", ipCount: 2},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			referralService := newTestService(&MockReferralRepository{IPCount: tc.ipCount})
+			_, err := referralService.Validate(context.Background(), "ABCD2345", tc.email, tc.ip)
+			if !errors.Is(err, errs.ErrReferralAbuse) {
+				t.Fatalf("expected ErrReferralAbuse, got %v:", err)
+			}
+		})
+	}
+}
+
+func TestValidateUnknownCode(t *testing.T) {
+	referralService := newTestService(&MockReferralRepository{})
+	_, err := referralService.Validate(context.Background(), "ZZZZZZZZ", "petr@gmail.com", "10.0.0.2")
+	if !errors.Is(err, errs.ErrReferralNotFound) {
+		t.Fatalf("expected ErrReferralNotFound, got %v:", err)
+	}
+}
+
+func TestFirstUpgradeRewardsOnce(t *testing.T) {
+	repo := &MockReferralRepository{Rewards: map[string]int64{}}
+	referralService := newTestService(repo)
+	if err := referralService.Link(context.Background(), "referrerID", "refereeID", "10.0.0.2"); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	referrerID, err := referralService.OnFirstUpgrade(context.Background(), "refereeID")
+	if err != nil || referrerID != "referrerID" {
+		t.Fatalf("expected referrer to be rewarded, got %q %v:", referrerID, err)
+	}
+	if referrerID, err := referralService.OnFirstUpgrade(context.Background(), "refereeID"); err != nil || referrerID != "" {
+		t.Fatalf("expected second upgrade not to reward, got %q %v:", referrerID, err)
+	}
+	if rewards, _ := referralService.PendingRewards(context.Background(), "refereeID", "testGameID"); len(rewards) != 1 || rewards[0].Amount != 500 {
+		t.Fatalf("expected referee reward 500, got %v", rewards)
+	}
+	if rewards, _ := referralService.PendingRewards(context.Background(), "referrerID", "testGameID"); len(rewards) != 1 || rewards[0].Amount != 1000 {
+		t.Fatalf("expected referrer reward 1000, got %v", rewards)
+	}
+}
@@ -0,0 +1,116 @@
+package snapshot
+
+import (
+	"errors"
+	"miners_game/config"
+	"miners_game/pkg/errs"
+	"miners_game/pkg/middleware"
+	"miners_game/pkg/tadapter"
+	"miners_game/views"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+type Handler struct {
+	router          fiber.Router
+	snapshotService *Service
+	adminConfig     *config.AdminConfig
+}
+
+type HandlerDeps struct {
+	Router          fiber.Router
+	SnapshotService *Service
+	AdminConfig     *config.AdminConfig
+}
+
+func NewHandler(deps HandlerDeps) {
+	h := &Handler{
+		router:          deps.Router,
+		snapshotService: deps.SnapshotService,
+		adminConfig:     deps.AdminConfig,
+	}
+	admin := h.router.Group("/admin/snapshots", middleware.AdminMiddleware(h.adminConfig.UserIDs))
+	admin.Get("/:userID/:gameID", h.list)
+	admin.Get("/:userID/:gameID/:id", h.diff)
+	admin.Post("/:userID/:gameID/:id/rollback", h.rollback)
+}
+
+func (h *Handler) list(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	userID := c.Params("userID")
+	gameID := c.Params("gameID")
+
+	snapshots, err := h.snapshotService.List(c.UserContext(), userID, gameID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed list snapshots service")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	props := views.SnapshotListProps{UserID: userID, GameID: gameID}
+	for _, s := range snapshots {
+		props.Snapshots = append(props.Snapshots, views.SnapshotItem{
+			ID:      strconv.FormatInt(s.ID, 10),
+			Kind:    s.Kind,
+			TakenAt: formatTime(s.TakenAt),
+			Balance: strconv.FormatInt(s.Balance, 10),
+		})
+	}
+	return tadapter.Render(c, views.SnapshotList(props), fiber.StatusOK)
+}
+
+func (h *Handler) diff(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	userID := c.Params("userID")
+	gameID := c.Params("gameID")
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	snap, rows, err := h.snapshotService.Diff(c.UserContext(), id, userID, gameID)
+	if err != nil {
+		if errors.Is(err, errs.ErrSnapshotNotFound) || errors.Is(err, errs.ErrGameNotFound) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		logger.Error().Err(err).Msg("failed diff snapshot service")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	props := views.SnapshotDiffProps{
+		UserID:  userID,
+		GameID:  gameID,
+		ID:      c.Params("id"),
+		Kind:    snap.Kind,
+		TakenAt: formatTime(snap.TakenAt),
+	}
+	for _, r := range rows {
+		props.Rows = append(props.Rows, views.SnapshotDiffRow{
+			Field:    r.Field,
+			Current:  r.Current,
+			Snapshot: r.Snapshot,
+			Changed:  r.Changed,
+		})
+	}
+	return tadapter.Render(c, views.SnapshotDiff(props), fiber.StatusOK)
+}
+
+func (h *Handler) rollback(c *fiber.Ctx) error {
+	logger := c.Locals("logger").(zerolog.Logger)
+	adminID := c.Locals("user_id").(string)
+	userID := c.Params("userID")
+	gameID := c.Params("gameID")
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	if err := h.snapshotService.Rollback(c.UserContext(), id, userID, gameID, adminID); err != nil {
+		if errors.Is(err, errs.ErrSnapshotNotFound) || errors.Is(err, errs.ErrGameNotFound) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		logger.Error().Err(err).Msg("failed rollback snapshot service")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	c.Set("HX-Redirect", "/admin/snapshots/"+userID+"/"+gameID)
+	return c.SendStatus(fiber.StatusOK)
+}
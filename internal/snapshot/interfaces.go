@@ -0,0 +1,19 @@
+package snapshot
+
+import (
+	"context"
+	"miners_game/internal/game/domain"
+)
+
+type ISnapshotRepository interface {
+	Take(ctx context.Context, kind string, now, minInterval int64) (int64, error)
+	Prune(ctx context.Context, kind string, before int64) (int64, error)
+	Save(ctx context.Context, game *domain.GameState, kind string, now int64) error
+	List(ctx context.Context, userID, gameID string) ([]Snapshot, error)
+	Load(ctx context.Context, id int64, userID, gameID string) (*Snapshot, *domain.GameState, error)
+}
+
+type IGameStore interface {
+	SlotState(ctx context.Context, userID, gameID string) (*domain.GameState, error)
+	Restore(ctx context.Context, game *domain.GameState) error
+}
@@ -0,0 +1,25 @@
+package snapshot
+
+const (
+	KindHourly = "hourly"
+	KindDaily  = "daily"
+	// KindManual - состояние перед откатом, чтобы откат можно было отменить
+	KindManual = "manual"
+)
+
+type Snapshot struct {
+	ID      int64
+	UserID  string
+	GameID  string
+	Kind    string
+	TakenAt int64
+	Balance int64
+}
+
+// DiffRow - строка сравнения текущего состояния со снимком
+type DiffRow struct {
+	Field    string
+	Current  string
+	Snapshot string
+	Changed  bool
+}
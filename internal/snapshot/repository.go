@@ -0,0 +1,200 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/miners"
+	"miners_game/pkg/database"
+	"miners_game/pkg/errs"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+type Repository struct {
+	dbPool  *pgxpool.Pool
+	timeout time.Duration
+	logger  zerolog.Logger
+}
+
+type RepositoryDeps struct {
+	DbPool  *pgxpool.Pool
+	Timeout time.Duration
+	Logger  zerolog.Logger
+}
+
+func NewRepository(deps RepositoryDeps) *Repository {
+	return &Repository{
+		dbPool:  deps.DbPool,
+		timeout: deps.Timeout,
+		logger:  deps.Logger,
+	}
+}
+
+// Take - снимки всех игр, изменившихся с прошлого снимка этого вида
+// и не снятых за последние minInterval секунд. Шахтёры и предметы
+// собираются из дочерних таблиц в тот же JSONB, что пишет Save.
+// Take и Prune - массовые фоновые операции, их ограничивает только ctx задачи
+func (r *Repository) Take(ctx context.Context, kind string, now, minInterval int64) (int64, error) {
+	query := `
+		INSERT INTO game_snapshots (user_id, game_id, kind, taken_at, name, balance, income, last_update_at, miners, equipments, upgrades)
+		SELECT g.user_id, g.game_id, @kind, @now, g.name, g.balance, g.income, g.last_update_at,
+			COALESCE((
+				SELECT jsonb_object_agg(m.miner_key, jsonb_build_object('ID', m.miner_id, 'Class', m.class, 'StartAt', m.start_at, 'EndAt', m.end_at))
+				FROM game_miners m
+				WHERE m.user_id = g.user_id AND m.game_id = g.game_id
+			), '{}'::jsonb),
+			(
+				SELECT jsonb_agg(jsonb_build_object('Name', i.name, 'Own', i.own) ORDER BY i.position)
+				FROM game_items i
+				WHERE i.user_id = g.user_id AND i.game_id = g.game_id AND i.kind = 'equipment'
+			),
+			(
+				SELECT jsonb_agg(jsonb_build_object('Name', i.name, 'Own', i.own) ORDER BY i.position)
+				FROM game_items i
+				WHERE i.user_id = g.user_id AND i.game_id = g.game_id AND i.kind = 'upgrade'
+			)
+		FROM games g
+		WHERE NOT EXISTS (
+			SELECT 1 FROM game_snapshots s
+			WHERE s.user_id = g.user_id AND s.game_id = g.game_id AND s.kind = @kind
+			  AND (s.taken_at > @since OR s.last_update_at >= g.last_update_at)
+		)
+	`
+	tag, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"kind":  kind,
+		"now":   now,
+		"since": now - minInterval,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("kind", kind).Msg("failed to take snapshots")
+		return 0, errs.ErrServer
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *Repository) Prune(ctx context.Context, kind string, before int64) (int64, error) {
+	query := `
+		DELETE FROM game_snapshots
+		WHERE kind = @kind AND taken_at < @before
+	`
+	tag, err := r.dbPool.Exec(ctx, query, pgx.NamedArgs{
+		"kind":   kind,
+		"before": before,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("kind", kind).Msg("failed to prune snapshots")
+		return 0, errs.ErrServer
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *Repository) Save(ctx context.Context, game *domain.GameState, kind string, now int64) error {
+	minersJSON, _ := json.Marshal(game.Miners)
+	equipmentsJSON, _ := json.Marshal(game.Equipments)
+	upgradesJSON, _ := json.Marshal(game.Upgrades)
+	query := `
+		INSERT INTO game_snapshots (user_id, game_id, kind, taken_at, name, balance, income, last_update_at, miners, equipments, upgrades)
+		VALUES (@user_id, @game_id, @kind, @now, @name, @balance, @income, @last_update_at, @miners, @equipments, @upgrades)
+	`
+	args := pgx.NamedArgs{
+		"user_id":        game.UserID,
+		"game_id":        game.GameID,
+		"kind":           kind,
+		"now":            now,
+		"name":           game.Name,
+		"balance":        game.Balance,
+		"income":         game.IncomePerSec,
+		"last_update_at": game.LastUpdateAt,
+		"miners":         minersJSON,
+		"equipments":     equipmentsJSON,
+		"upgrades":       upgradesJSON,
+	}
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.dbPool.Exec(ctx, query, args); err != nil {
+		r.logger.Error().Err(err).Str("user_id", game.UserID).Str("game_id", game.GameID).Msg("failed to save snapshot")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *Repository) List(ctx context.Context, userID, gameID string) ([]Snapshot, error) {
+	query := `
+		SELECT id, kind, taken_at, balance
+		FROM game_snapshots
+		WHERE user_id = @user_id AND game_id = @game_id
+		ORDER BY taken_at DESC, id DESC
+	`
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	rows, err := r.dbPool.Query(ctx, query, pgx.NamedArgs{
+		"user_id": userID,
+		"game_id": gameID,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Str("game_id", gameID).Msg("failed to list snapshots")
+		return nil, errs.ErrServer
+	}
+	defer rows.Close()
+
+	snapshots := []Snapshot{}
+	for rows.Next() {
+		s := Snapshot{UserID: userID, GameID: gameID}
+		if err := rows.Scan(&s.ID, &s.Kind, &s.TakenAt, &s.Balance); err != nil {
+			r.logger.Error().Err(err).Msg("failed to scan snapshot")
+			return nil, errs.ErrServer
+		}
+		snapshots = append(snapshots, s)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("failed to list snapshots")
+		return nil, errs.ErrServer
+	}
+	return snapshots, nil
+}
+
+func (r *Repository) Load(ctx context.Context, id int64, userID, gameID string) (*Snapshot, *domain.GameState, error) {
+	query := `
+		SELECT kind, taken_at, name, balance, income, last_update_at, miners, equipments, upgrades
+		FROM game_snapshots
+		WHERE id = @id AND user_id = @user_id AND game_id = @game_id
+	`
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	row := r.dbPool.QueryRow(ctx, query, pgx.NamedArgs{
+		"id":      id,
+		"user_id": userID,
+		"game_id": gameID,
+	})
+	s := &Snapshot{ID: id, UserID: userID, GameID: gameID}
+	game := &domain.GameState{UserID: userID, GameID: gameID}
+	var minersJSON, equipmentsJSON, upgradesJSON []byte
+	if err := row.Scan(&s.Kind, &s.TakenAt, &game.Name, &game.Balance, &game.IncomePerSec, &game.LastUpdateAt, &minersJSON, &equipmentsJSON, &upgradesJSON); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, errs.ErrSnapshotNotFound
+		}
+		r.logger.Error().Err(err).Int64("snapshot_id", id).Msg("failed to load snapshot")
+		return nil, nil, errs.ErrServer
+	}
+	s.Balance = game.Balance
+	if err := json.Unmarshal(minersJSON, &game.Miners); err != nil {
+		r.logger.Error().Err(err).Msg("failed to unmarshal miners")
+		return nil, nil, errs.ErrServer
+	}
+	if game.Miners == nil {
+		game.Miners = make(map[string]*miners.Miner)
+	}
+	if err := json.Unmarshal(equipmentsJSON, &game.Equipments); err != nil {
+		r.logger.Error().Err(err).Msg("failed to unmarshal equipments")
+		return nil, nil, errs.ErrServer
+	}
+	if err := json.Unmarshal(upgradesJSON, &game.Upgrades); err != nil {
+		r.logger.Error().Err(err).Msg("failed to unmarshal upgrades")
+		return nil, nil, errs.ErrServer
+	}
+	return s, game, nil
+}
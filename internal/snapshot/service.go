@@ -0,0 +1,181 @@
+package snapshot
+
+import (
+	"context"
+	"miners_game/config"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/game/equipments"
+	"miners_game/internal/game/upgrades"
+	"miners_game/internal/miners"
+	"miners_game/pkg/clock"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	hourlyInterval = int64(time.Hour / time.Second)
+	dailyInterval  = int64(24 * time.Hour / time.Second)
+	// intervalSlack - тикер не точен, снимок не должен пропускаться из-за пары секунд
+	intervalSlack = 60
+)
+
+type Service struct {
+	repo   ISnapshotRepository
+	games  IGameStore
+	config *config.SnapshotConfig
+	clock  clock.Clock
+	logger zerolog.Logger
+}
+
+type ServiceDeps struct {
+	Repo   ISnapshotRepository
+	Games  IGameStore
+	Config *config.SnapshotConfig
+	Clock  clock.Clock
+	Logger zerolog.Logger
+}
+
+func NewService(deps ServiceDeps) *Service {
+	return &Service{
+		repo:   deps.Repo,
+		games:  deps.Games,
+		config: deps.Config,
+		clock:  clock.OrReal(deps.Clock),
+		logger: deps.Logger,
+	}
+}
+
+// Rotate - вызывается раз в час: снимает часовые и суточные снимки, удаляет устаревшие
+func (s *Service) Rotate(ctx context.Context, now int64) {
+	hourly, err := s.repo.Take(ctx, KindHourly, now, hourlyInterval-intervalSlack)
+	if err != nil {
+		return
+	}
+	daily, err := s.repo.Take(ctx, KindDaily, now, dailyInterval-intervalSlack)
+	if err != nil {
+		return
+	}
+	prunedHourly, _ := s.repo.Prune(ctx, KindHourly, now-int64(s.config.HourlyRetention/time.Second))
+	prunedDaily, _ := s.repo.Prune(ctx, KindDaily, now-int64(s.config.DailyRetention/time.Second))
+	prunedManual, _ := s.repo.Prune(ctx, KindManual, now-int64(s.config.DailyRetention/time.Second))
+	s.logger.Info().
+		Int64("hourly", hourly).
+		Int64("daily", daily).
+		Int64("pruned", prunedHourly+prunedDaily+prunedManual).
+		Msg("snapshots rotated")
+}
+
+func (s *Service) List(ctx context.Context, userID, gameID string) ([]Snapshot, error) {
+	return s.repo.List(ctx, userID, gameID)
+}
+
+// Diff - текущее состояние игры против снимка
+func (s *Service) Diff(ctx context.Context, id int64, userID, gameID string) (*Snapshot, []DiffRow, error) {
+	snap, state, err := s.repo.Load(ctx, id, userID, gameID)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := s.games.SlotState(ctx, userID, gameID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return snap, Diff(current, state), nil
+}
+
+// Rollback - текущее состояние сохраняется ручным снимком, затем игра заменяется снимком
+func (s *Service) Rollback(ctx context.Context, id int64, userID, gameID, adminID string) error {
+	_, state, err := s.repo.Load(ctx, id, userID, gameID)
+	if err != nil {
+		return err
+	}
+	current, err := s.games.SlotState(ctx, userID, gameID)
+	if err != nil {
+		return err
+	}
+	now := s.clock.Now().Unix()
+	if err := s.repo.Save(ctx, current, KindManual, now); err != nil {
+		return err
+	}
+	state.CreatedAt = current.CreatedAt
+	state.LastUpdateAt = now
+	if err := s.games.Restore(ctx, state); err != nil {
+		return err
+	}
+	s.logger.Warn().
+		Str("admin_id", adminID).
+		Str("user_id", userID).
+		Str("game_id", gameID).
+		Int64("snapshot_id", id).
+		Msg("game rolled back to snapshot")
+	return nil
+}
+
+func Diff(current, snap *domain.GameState) []DiffRow {
+	current, snap = current.Clone(), snap.Clone()
+	rows := []DiffRow{
+		row("Название", current.Name, snap.Name),
+		row("Баланс", strconv.FormatInt(current.Balance, 10), strconv.FormatInt(snap.Balance, 10)),
+		row("Доход/сек", strconv.FormatInt(current.IncomePerSec, 10), strconv.FormatInt(snap.IncomePerSec, 10)),
+		row("Обновлено", formatTime(current.LastUpdateAt), formatTime(snap.LastUpdateAt)),
+	}
+	classes := make([]string, 0, len(miners.MinerPresets))
+	for class := range miners.MinerPresets {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return miners.GetMinerConfig(classes[i]).Price < miners.GetMinerConfig(classes[j]).Price
+	})
+	currentMiners, snapMiners := countMiners(current), countMiners(snap)
+	for _, class := range classes {
+		rows = append(rows, row(
+			"Шахтёры: "+miners.GetMinerConfig(class).Title,
+			strconv.Itoa(currentMiners[class]),
+			strconv.Itoa(snapMiners[class]),
+		))
+	}
+	rows = append(rows,
+		row("Инструменты", ownedEquipments(current), ownedEquipments(snap)),
+		row("Улучшение", upgradeTitle(current.GetMaxUpgrade()), upgradeTitle(snap.GetMaxUpgrade())),
+	)
+	return rows
+}
+
+func row(field, current, snap string) DiffRow {
+	return DiffRow{Field: field, Current: current, Snapshot: snap, Changed: current != snap}
+}
+
+func countMiners(game *domain.GameState) map[string]int {
+	counts := make(map[string]int)
+	for _, m := range game.Miners {
+		counts[m.Class]++
+	}
+	return counts
+}
+
+func ownedEquipments(game *domain.GameState) string {
+	titles := []string{}
+	for _, e := range game.Equipments {
+		if e.Own {
+			titles = append(titles, equipments.GetEquipmentConfig(e.Name).Title)
+		}
+	}
+	if len(titles) == 0 {
+		return "—"
+	}
+	return strings.Join(titles, ", ")
+}
+
+func upgradeTitle(name string) string {
+	if name == "0" {
+		return "—"
+	}
+	return upgrades.GetUpgradesConfig(name).Title
+}
+
+func formatTime(unix int64) string {
+	return time.Unix(unix, 0).Format("02.01.2006 15:04:05")
+}
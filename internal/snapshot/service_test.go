@@ -0,0 +1,89 @@
+package snapshot_test
+
+import (
+	"context"
+	"miners_game/internal/game/domain"
+	"miners_game/internal/snapshot"
+	"testing"
+	"time"
+)
+
+type MockSnapshotRepository struct {
+	Saved    []string
+	Snapshot *domain.GameState
+}
+
+func (m *MockSnapshotRepository) Take(ctx context.Context, kind string, now, minInterval int64) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockSnapshotRepository) Prune(ctx context.Context, kind string, before int64) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockSnapshotRepository) Save(ctx context.Context, game *domain.GameState, kind string, now int64) error {
+	m.Saved = append(m.Saved, kind)
+	return nil
+}
+
+func (m *MockSnapshotRepository) List(ctx context.Context, userID, gameID string) ([]snapshot.Snapshot, error) {
+	return nil, nil
+}
+
+func (m *MockSnapshotRepository) Load(ctx context.Context, id int64, userID, gameID string) (*snapshot.Snapshot, *domain.GameState, error) {
+	return &snapshot.Snapshot{ID: id, Kind: snapshot.KindHourly}, m.Snapshot.Clone(), nil
+}
+
+type MockGameStore struct {
+	Current  *domain.GameState
+	Restored *domain.GameState
+}
+
+func (m *MockGameStore) SlotState(ctx context.Context, userID, gameID string) (*domain.GameState, error) {
+	return m.Current.Clone(), nil
+}
+
+func (m *MockGameStore) Restore(ctx context.Context, game *domain.GameState) error {
+	m.Restored = game
+	return nil
+}
+
+func TestRollbackKeepsCurrentState(t *testing.T) {
+	current := domain.NewGameState("testUserID", "testGameID", time.Now().Unix())
+	current.Balance = 10
+	snap := domain.NewGameState("testUserID", "testGameID", time.Now().Unix())
+	snap.Balance = 5000
+
+	repo := &MockSnapshotRepository{Snapshot: snap}
+	games := &MockGameStore{Current: current}
+	snapshotService := snapshot.NewService(snapshot.ServiceDeps{Repo: repo, Games: games})
+
+	if err := snapshotService.Rollback(context.Background(), 1, "testUserID", "testGameID", "adminID"); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if len(repo.Saved) != 1 || repo.Saved[0] != snapshot.KindManual {
+		t.Fatalf("expected manual snapshot before rollback, got %v", repo.Saved)
+	}
+	if games.Restored == nil || games.Restored.Balance != 5000 {
+		t.Fatalf("expected game restored from snapshot, got %+v", games.Restored)
+	}
+}
+
+func TestDiffMarksChanges(t *testing.T) {
+	current := domain.NewGameState("testUserID", "testGameID", time.Now().Unix())
+	current.Balance = 10
+	current.AddMiner("small", time.Now().Unix())
+	snap := current.Clone()
+	snap.Balance = 20
+
+	changed := map[string]bool{}
+	for _, row := range snapshot.Diff(current, snap) {
+		changed[row.Field] = row.Changed
+	}
+	if !changed["Баланс"] {
+		t.Fatalf("expected balance to differ")
+	}
+	if changed["Шахтёры: Шахтер"] || changed["Инструменты"] {
+		t.Fatalf("expected miners and equipment to match")
+	}
+}
@@ -1,6 +1,11 @@
 package user
 
+import "context"
+
 type IUserRepository interface {
-	SaveUser(user *User) error
-	FindByEmail(email string) (*User, error)
+	SaveUser(ctx context.Context, user *User) error
+	FindByEmail(ctx context.Context, email string) (*User, error)
+	FindByUsername(ctx context.Context, username string) (*User, error)
+	FindByReferralCode(ctx context.Context, referralCode string) (*User, error)
+	SetProfileHidden(ctx context.Context, userID string, hidden bool) error
 }
@@ -1,19 +1,30 @@
 package user
 
-import "github.com/google/uuid"
+import (
+	"miners_game/pkg/code"
+
+	"github.com/google/uuid"
+)
 
 type User struct {
 	ID       string
 	Email    string
 	Password string
 	UserName string
+
+	ProfileHidden bool
+	ReferralCode  string
+	RegisterIP    string
 }
 
-func NewUser(email, password, userName string) *User {
+func NewUser(email, password, userName, registerIP string) *User {
 	return &User{
 		ID:       uuid.NewString(),
 		Email:    email,
 		Password: password,
 		UserName: userName,
+
+		ReferralCode: code.GenerateReferral(),
+		RegisterIP:   registerIP,
 	}
 }
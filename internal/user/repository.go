@@ -2,43 +2,59 @@ package user
 
 import (
 	"context"
+	"errors"
+	"miners_game/pkg/database"
 	"miners_game/pkg/errs"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog"
 )
 
 type Repository struct {
-	dbPool *pgxpool.Pool
-	logger zerolog.Logger
+	dbPool  *pgxpool.Pool
+	timeout time.Duration
+	logger  zerolog.Logger
 }
 
 type RepositoryDeps struct {
-	DbPool *pgxpool.Pool
-	Logger zerolog.Logger
+	DbPool  *pgxpool.Pool
+	Timeout time.Duration
+	Logger  zerolog.Logger
 }
 
 func NewRepository(deps RepositoryDeps) *Repository {
 	return &Repository{
-		dbPool: deps.DbPool,
-		logger: deps.Logger,
+		dbPool:  deps.DbPool,
+		timeout: deps.Timeout,
+		logger:  deps.Logger,
 	}
 }
 
-func (r *Repository) SaveUser(user *User) error {
+func (r *Repository) SaveUser(ctx context.Context, user *User) error {
 	query := `
-		INSERT INTO users (user_id, email, password, username)
-		VALUES (@user_id, @email, @password, @username)
+		INSERT INTO users (user_id, email, password, username, referral_code, register_ip)
+		VALUES (@user_id, @email, @password, @username, @referral_code, @register_ip)
 	`
 	args := pgx.NamedArgs{
-		"user_id":  user.ID,
-		"email":    user.Email,
-		"password": user.Password,
-		"username": user.UserName,
+		"user_id":       user.ID,
+		"email":         user.Email,
+		"password":      user.Password,
+		"username":      user.UserName,
+		"referral_code": user.ReferralCode,
+		"register_ip":   user.RegisterIP,
 	}
 
-	if _, err := r.dbPool.Exec(context.Background(), query, args); err != nil {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.dbPool.Exec(ctx, query, args); err != nil {
+		// 23505 - unique_violation, ник занят параллельной регистрацией
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "users_username_key" {
+			return errs.ErrUsernameTaken
+		}
 		r.logger.Error().Err(err).Str("user_id", user.ID).Msg("failed to save user")
 		return err
 	}
@@ -46,32 +62,78 @@ func (r *Repository) SaveUser(user *User) error {
 	return nil
 }
 
-func (r *Repository) FindByEmail(email string) (*User, error) {
+func (r *Repository) FindByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT user_id, username, password
+		SELECT user_id, email, username, password, profile_hidden, referral_code, register_ip
 		FROM users
 		WHERE email = @email
 	`
-	rows := r.dbPool.QueryRow(context.Background(), query, pgx.NamedArgs{
+	return r.findOne(ctx, query, pgx.NamedArgs{
 		"email": email,
 	})
+}
 
-	var userID string
-	var userName string
-	var password string
+func (r *Repository) FindByUsername(ctx context.Context, username string) (*User, error) {
+	query := `
+		SELECT user_id, email, username, password, profile_hidden, referral_code, register_ip
+		FROM users
+		WHERE username = @username
+		LIMIT 1
+	`
+	return r.findOne(ctx, query, pgx.NamedArgs{
+		"username": username,
+	})
+}
 
-	if err := rows.Scan(&userID, &userName, &password); err != nil {
+func (r *Repository) FindByReferralCode(ctx context.Context, referralCode string) (*User, error) {
+	query := `
+		SELECT user_id, email, username, password, profile_hidden, referral_code, register_ip
+		FROM users
+		WHERE referral_code = @referral_code
+	`
+	return r.findOne(ctx, query, pgx.NamedArgs{
+		"referral_code": referralCode,
+	})
+}
+
+func (r *Repository) SetProfileHidden(ctx context.Context, userID string, hidden bool) error {
+	query := `
+		UPDATE users SET profile_hidden = @profile_hidden
+		WHERE user_id = @user_id
+	`
+	args := pgx.NamedArgs{
+		"user_id":        userID,
+		"profile_hidden": hidden,
+	}
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.dbPool.Exec(ctx, query, args); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to set profile privacy")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *Repository) findOne(ctx context.Context, query string, args pgx.NamedArgs) (*User, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	rows := r.dbPool.QueryRow(ctx, query, args)
+
+	var user User
+	if err := rows.Scan(
+		&user.ID,
+		&user.Email,
+		&user.UserName,
+		&user.Password,
+		&user.ProfileHidden,
+		&user.ReferralCode,
+		&user.RegisterIP,
+	); err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, errs.ErrUserNotFound
 		}
-		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to find user")
+		r.logger.Error().Err(err).Msg("failed to find user")
 		return nil, errs.ErrServer
 	}
-	return &User{
-		ID:       userID,
-		Email:    email,
-		Password: password,
-		UserName: userName,
-	}, nil
-
+	return &user, nil
 }
@@ -0,0 +1,67 @@
+package user
+
+import (
+	"context"
+	"miners_game/pkg/errs"
+	"sync"
+)
+
+// MemoryRepository - пользователи в памяти процесса для локального запуска и тестов
+type MemoryRepository struct {
+	users map[string]User
+	mu    sync.RWMutex
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		users: make(map[string]User),
+	}
+}
+
+func (r *MemoryRepository) SaveUser(ctx context.Context, user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.UserName == user.UserName {
+			return errs.ErrUsernameTaken
+		}
+		if u.ReferralCode == user.ReferralCode {
+			return errs.ErrEmailAlreadyExist
+		}
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *MemoryRepository) FindByEmail(ctx context.Context, email string) (*User, error) {
+	return r.findOne(func(u User) bool { return u.Email == email })
+}
+
+func (r *MemoryRepository) FindByUsername(ctx context.Context, username string) (*User, error) {
+	return r.findOne(func(u User) bool { return u.UserName == username })
+}
+
+func (r *MemoryRepository) FindByReferralCode(ctx context.Context, referralCode string) (*User, error) {
+	return r.findOne(func(u User) bool { return u.ReferralCode == referralCode })
+}
+
+func (r *MemoryRepository) SetProfileHidden(ctx context.Context, userID string, hidden bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[userID]; ok {
+		u.ProfileHidden = hidden
+		r.users[userID] = u
+	}
+	return nil
+}
+
+func (r *MemoryRepository) findOne(match func(u User) bool) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, u := range r.users {
+		if match(u) {
+			return &u, nil
+		}
+	}
+	return nil, errs.ErrUserNotFound
+}
@@ -0,0 +1,101 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"miners_game/pkg/database"
+	"miners_game/pkg/errs"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/rs/zerolog"
+)
+
+// SQLiteRepository - пользователи в файле SQLite для локального запуска
+type SQLiteRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+	logger  zerolog.Logger
+}
+
+type SQLiteRepositoryDeps struct {
+	DB      *sql.DB
+	Timeout time.Duration
+	Logger  zerolog.Logger
+}
+
+func NewSQLiteRepository(deps SQLiteRepositoryDeps) *SQLiteRepository {
+	return &SQLiteRepository{
+		db:      deps.DB,
+		timeout: deps.Timeout,
+		logger:  deps.Logger,
+	}
+}
+
+func (r *SQLiteRepository) SaveUser(ctx context.Context, user *User) error {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO users (user_id, email, password, username, referral_code, register_ip)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		user.ID, user.Email, user.Password, user.UserName, user.ReferralCode, user.RegisterIP)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique && strings.Contains(sqliteErr.Error(), "users.username") {
+		return errs.ErrUsernameTaken
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Str("user_id", user.ID).Msg("failed to save user")
+		return err
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) FindByEmail(ctx context.Context, email string) (*User, error) {
+	return r.findOne(ctx, `WHERE email = ? LIMIT 1`, email)
+}
+
+func (r *SQLiteRepository) FindByUsername(ctx context.Context, username string) (*User, error) {
+	return r.findOne(ctx, `WHERE username = ? LIMIT 1`, username)
+}
+
+func (r *SQLiteRepository) FindByReferralCode(ctx context.Context, referralCode string) (*User, error) {
+	return r.findOne(ctx, `WHERE referral_code = ?`, referralCode)
+}
+
+func (r *SQLiteRepository) SetProfileHidden(ctx context.Context, userID string, hidden bool) error {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.db.ExecContext(ctx, `UPDATE users SET profile_hidden = ? WHERE user_id = ?`, hidden, userID); err != nil {
+		r.logger.Error().Err(err).Str("user_id", userID).Msg("failed to set profile privacy")
+		return errs.ErrServer
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) findOne(ctx context.Context, where string, arg string) (*User, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	row := r.db.QueryRowContext(ctx, `
+		SELECT user_id, email, username, password, profile_hidden, referral_code, register_ip
+		FROM users `+where, arg)
+
+	var user User
+	if err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.UserName,
+		&user.Password,
+		&user.ProfileHidden,
+		&user.ReferralCode,
+		&user.RegisterIP,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrUserNotFound
+		}
+		r.logger.Error().Err(err).Msg("failed to find user")
+		return nil, errs.ErrServer
+	}
+	return &user, nil
+}
@@ -0,0 +1,84 @@
+// Package usertest - общий контракт хранилищ пользователей для Postgres, SQLite и памяти
+package usertest
+
+import (
+	"context"
+	"errors"
+	"miners_game/internal/user"
+	"miners_game/pkg/errs"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// RunRepositoryContract - пользователи создаются со случайными email и никами
+func RunRepositoryContract(t *testing.T, newRepo func(t *testing.T) user.IUserRepository) {
+	ctx := context.Background()
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.FindByEmail(ctx, uuid.NewString()+"@test.local"); !errors.Is(err, errs.ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound, got %v:", err)
+		}
+		if _, err := repo.FindByUsername(ctx, uuid.NewString()); !errors.Is(err, errs.ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound, got %v:", err)
+		}
+		if _, err := repo.FindByReferralCode(ctx, uuid.NewString()); !errors.Is(err, errs.ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound, got %v:", err)
+		}
+	})
+
+	t.Run("SaveFind", func(t *testing.T) {
+		repo := newRepo(t)
+		u := newUser()
+		if err := repo.SaveUser(ctx, u); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		for name, find := range map[string]func() (*user.User, error){
+			"email":    func() (*user.User, error) { return repo.FindByEmail(ctx, u.Email) },
+			"username": func() (*user.User, error) { return repo.FindByUsername(ctx, u.UserName) },
+			"referral": func() (*user.User, error) { return repo.FindByReferralCode(ctx, u.ReferralCode) },
+		} {
+			found, err := find()
+			if err != nil {
+				t.Fatalf("expected user by %s, got %v:", name, err)
+			}
+			if *found != *u {
+				t.Fatalf("expected %+v by %s, got %+v", u, name, found)
+			}
+		}
+	})
+
+	t.Run("UsernameTaken", func(t *testing.T) {
+		repo := newRepo(t)
+		u := newUser()
+		if err := repo.SaveUser(ctx, u); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		other := newUser()
+		other.UserName = u.UserName
+		if err := repo.SaveUser(ctx, other); !errors.Is(err, errs.ErrUsernameTaken) {
+			t.Fatalf("expected ErrUsernameTaken, got %v:", err)
+		}
+	})
+
+	t.Run("SetProfileHidden", func(t *testing.T) {
+		repo := newRepo(t)
+		u := newUser()
+		if err := repo.SaveUser(ctx, u); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		if err := repo.SetProfileHidden(ctx, u.ID, true); err != nil {
+			t.Fatalf("expected success, got %v:", err)
+		}
+		found, err := repo.FindByEmail(ctx, u.Email)
+		if err != nil || !found.ProfileHidden {
+			t.Fatalf("expected hidden profile, got %+v %v", found, err)
+		}
+	})
+}
+
+func newUser() *user.User {
+	id := uuid.NewString()
+	return user.NewUser(id+"@test.local", "hash", "user-"+id[:8], "127.0.0.1")
+}
@@ -1,4 +1,4 @@
-CREATE TABLE test_table (
+CREATE TABLE IF NOT EXISTS test_table (
     id SERIAL PRIMARY KEY,
     email VARCHAR(255) NOT NULL,
     role VARCHAR(255),
@@ -1,4 +1,4 @@
-CREATE TABLE game_saves (
+CREATE TABLE IF NOT EXISTS game_saves (
     user_id TEXT NOT NULL,
     save_id TEXT NOT NULL,
     balance BIGINT NOT NULL,
@@ -1,4 +1,4 @@
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     user_id TEXT NOT NULL,
     email TEXT NOT NULL,
     password TEXT NOT NULL,
@@ -1,4 +1,4 @@
-CREATE TABLE games (
+CREATE TABLE IF NOT EXISTS games (
     user_id TEXT NOT NULL,
     game_id TEXT NOT NULL,
     balance BIGINT NOT NULL,
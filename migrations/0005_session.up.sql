@@ -0,0 +1,7 @@
+-- Таблица хранилища сессий fiber (gofiber/storage/postgres), схема совпадает с его initQuery
+CREATE TABLE IF NOT EXISTS session (
+    k VARCHAR(64) PRIMARY KEY NOT NULL DEFAULT '',
+    v BYTEA NOT NULL,
+    e BIGINT NOT NULL DEFAULT '0'
+);
+CREATE INDEX IF NOT EXISTS e ON session (e);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS chat_sanctions;
+DROP TABLE IF EXISTS chat_messages;
+DROP TABLE IF EXISTS guild_members;
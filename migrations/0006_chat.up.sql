@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS guild_members (
+    user_id TEXT NOT NULL,
+    guild_id TEXT NOT NULL,
+    PRIMARY KEY (user_id)
+);
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id BIGSERIAL NOT NULL,
+    channel TEXT NOT NULL,
+    guild_id TEXT NOT NULL DEFAULT '',
+    user_id TEXT NOT NULL,
+    username TEXT NOT NULL,
+    text TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS chat_messages_channel_idx ON chat_messages (channel, guild_id, id DESC);
+CREATE TABLE IF NOT EXISTS chat_sanctions (
+    user_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL,
+    expires_at BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, kind)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS profile_hidden BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS users_username_idx ON users (username);
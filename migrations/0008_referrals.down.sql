@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS referral_rewards;
+DROP TABLE IF EXISTS referrals;
+DROP INDEX IF EXISTS users_referral_code_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS register_ip;
+ALTER TABLE users DROP COLUMN IF EXISTS referral_code;
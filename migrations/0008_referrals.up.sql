@@ -0,0 +1,22 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS referral_code TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS register_ip TEXT NOT NULL DEFAULT '';
+UPDATE users SET referral_code = upper(substr(md5(user_id), 1, 8)) WHERE referral_code IS NULL;
+ALTER TABLE users ALTER COLUMN referral_code SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS users_referral_code_idx ON users (referral_code);
+CREATE TABLE IF NOT EXISTS referrals (
+    referee_id TEXT NOT NULL,
+    referrer_id TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    rewarded_at BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (referee_id)
+);
+CREATE INDEX IF NOT EXISTS referrals_ip_idx ON referrals (ip, created_at);
+CREATE TABLE IF NOT EXISTS referral_rewards (
+    id BIGSERIAL NOT NULL,
+    user_id TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS referral_rewards_user_idx ON referral_rewards (user_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS games_user_created_idx;
+ALTER TABLE games DROP COLUMN IF EXISTS created_at;
+ALTER TABLE games DROP COLUMN IF EXISTS name;
@@ -0,0 +1,4 @@
+ALTER TABLE games ADD COLUMN IF NOT EXISTS name TEXT NOT NULL DEFAULT '';
+ALTER TABLE games ADD COLUMN IF NOT EXISTS created_at BIGINT NOT NULL DEFAULT 0;
+UPDATE games SET created_at = last_update_at WHERE created_at = 0;
+CREATE INDEX IF NOT EXISTS games_user_created_idx ON games (user_id, created_at);
@@ -0,0 +1,7 @@
+-- Названия не откатываются: 0009 down удаляет колонку целиком.
+-- Колонки перечислены явно: 0014 down возвращает JSONB-колонки в конец таблицы
+INSERT INTO games (user_id, game_id, name, created_at, balance, income, last_update_at, miners, equipments, upgrades)
+SELECT user_id, game_id, name, created_at, balance, income, last_update_at, miners, equipments, upgrades
+FROM games_orphaned
+ON CONFLICT (user_id, game_id) DO NOTHING;
+DROP TABLE IF EXISTS games_orphaned;
@@ -0,0 +1,25 @@
+-- Пустые игры, созданные GameMiddleware при потере cookie, уходят в архив,
+-- чтобы последней сыгранной снова стала игра с прогрессом
+CREATE TABLE IF NOT EXISTS games_orphaned (LIKE games INCLUDING ALL);
+INSERT INTO games_orphaned
+SELECT g.* FROM games g
+WHERE (g.miners IS NULL OR g.miners = 'null'::jsonb OR g.miners = '{}'::jsonb)
+  AND NOT COALESCE(g.equipments @> '[{"Own": true}]'::jsonb, false)
+  AND NOT COALESCE(g.upgrades @> '[{"Own": true}]'::jsonb, false)
+  AND EXISTS (
+      SELECT 1 FROM games o
+      WHERE o.user_id = g.user_id
+        AND o.game_id <> g.game_id
+        AND (o.balance > g.balance
+             OR COALESCE(o.equipments @> '[{"Own": true}]'::jsonb, false)
+             OR COALESCE(o.upgrades @> '[{"Own": true}]'::jsonb, false))
+  );
+DELETE FROM games g USING games_orphaned o
+WHERE g.user_id = o.user_id AND g.game_id = o.game_id;
+-- Оставшимся безымянным играм - номер по порядку создания
+UPDATE games g SET name = 'Сохранение ' || n.rn
+FROM (
+    SELECT user_id, game_id, row_number() OVER (PARTITION BY user_id ORDER BY created_at, game_id) AS rn
+    FROM games
+) n
+WHERE g.user_id = n.user_id AND g.game_id = n.game_id AND g.name = '';
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS game_snapshots (
+    id BIGSERIAL NOT NULL,
+    user_id TEXT NOT NULL,
+    game_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    taken_at BIGINT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    balance BIGINT NOT NULL,
+    income BIGINT NOT NULL,
+    last_update_at BIGINT NOT NULL,
+    miners JSONB,
+    equipments JSONB,
+    upgrades JSONB,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS game_snapshots_game_idx ON game_snapshots (user_id, game_id, kind, taken_at DESC);
+CREATE INDEX IF NOT EXISTS game_snapshots_prune_idx ON game_snapshots (kind, taken_at);
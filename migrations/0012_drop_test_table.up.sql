@@ -0,0 +1,2 @@
+-- test_table остался от первой пробной миграции и нигде не используется
+DROP TABLE IF EXISTS test_table;
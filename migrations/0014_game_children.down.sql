@@ -0,0 +1,23 @@
+ALTER TABLE games ADD COLUMN IF NOT EXISTS miners JSONB;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS equipments JSONB;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS upgrades JSONB;
+
+UPDATE games g SET
+    miners = COALESCE((
+        SELECT jsonb_object_agg(m.miner_key, jsonb_build_object('ID', m.miner_id, 'Class', m.class, 'StartAt', m.start_at, 'EndAt', m.end_at))
+        FROM game_miners m
+        WHERE m.user_id = g.user_id AND m.game_id = g.game_id
+    ), '{}'::jsonb),
+    equipments = (
+        SELECT jsonb_agg(jsonb_build_object('Name', i.name, 'Own', i.own) ORDER BY i.position)
+        FROM game_items i
+        WHERE i.user_id = g.user_id AND i.game_id = g.game_id AND i.kind = 'equipment'
+    ),
+    upgrades = (
+        SELECT jsonb_agg(jsonb_build_object('Name', i.name, 'Own', i.own) ORDER BY i.position)
+        FROM game_items i
+        WHERE i.user_id = g.user_id AND i.game_id = g.game_id AND i.kind = 'upgrade'
+    );
+
+DROP TABLE IF EXISTS game_items;
+DROP TABLE IF EXISTS game_miners;
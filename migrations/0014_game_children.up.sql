@@ -0,0 +1,58 @@
+CREATE TABLE IF NOT EXISTS game_miners (
+    user_id TEXT NOT NULL,
+    game_id TEXT NOT NULL,
+    miner_key TEXT NOT NULL,
+    miner_id TEXT NOT NULL,
+    class TEXT NOT NULL,
+    start_at BIGINT NOT NULL,
+    end_at BIGINT NOT NULL,
+    PRIMARY KEY (user_id, game_id, miner_key),
+    FOREIGN KEY (user_id, game_id) REFERENCES games (user_id, game_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS game_miners_class_idx ON game_miners (class, end_at);
+
+CREATE TABLE IF NOT EXISTS game_items (
+    user_id TEXT NOT NULL,
+    game_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    own BOOLEAN NOT NULL DEFAULT FALSE,
+    position INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, game_id, kind, name),
+    FOREIGN KEY (user_id, game_id) REFERENCES games (user_id, game_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS game_items_owned_idx ON game_items (kind, name) WHERE own;
+
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'games' AND column_name = 'miners'
+    ) THEN
+        INSERT INTO game_miners (user_id, game_id, miner_key, miner_id, class, start_at, end_at)
+        SELECT g.user_id, g.game_id, m.key,
+               COALESCE(m.value->>'ID', m.key),
+               m.value->>'Class',
+               (m.value->>'StartAt')::BIGINT,
+               (m.value->>'EndAt')::BIGINT
+        FROM games g, jsonb_each(g.miners) m
+        WHERE jsonb_typeof(g.miners) = 'object'
+        ON CONFLICT DO NOTHING;
+
+        INSERT INTO game_items (user_id, game_id, kind, name, own, position)
+        SELECT g.user_id, g.game_id, 'equipment', e.value->>'Name', (e.value->>'Own')::BOOLEAN, e.ordinality - 1
+        FROM games g, jsonb_array_elements(g.equipments) WITH ORDINALITY e
+        WHERE jsonb_typeof(g.equipments) = 'array'
+        ON CONFLICT DO NOTHING;
+
+        INSERT INTO game_items (user_id, game_id, kind, name, own, position)
+        SELECT g.user_id, g.game_id, 'upgrade', u.value->>'Name', (u.value->>'Own')::BOOLEAN, u.ordinality - 1
+        FROM games g, jsonb_array_elements(g.upgrades) WITH ORDINALITY u
+        WHERE jsonb_typeof(g.upgrades) = 'array'
+        ON CONFLICT DO NOTHING;
+    END IF;
+END $$;
+
+ALTER TABLE games DROP COLUMN IF EXISTS miners;
+ALTER TABLE games DROP COLUMN IF EXISTS equipments;
+ALTER TABLE games DROP COLUMN IF EXISTS upgrades;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS game_ledger;
+DROP FUNCTION IF EXISTS game_ledger_append_only();
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS game_ledger (
+    id BIGSERIAL NOT NULL,
+    user_id TEXT NOT NULL,
+    game_id TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    item TEXT NOT NULL DEFAULT '',
+    balance_after BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS game_ledger_game_idx ON game_ledger (user_id, game_id, id);
+
+-- Журнал только дополняется: записи переживают удаление игры и не правятся
+CREATE OR REPLACE FUNCTION game_ledger_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'game_ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS game_ledger_append_only ON game_ledger;
+CREATE TRIGGER game_ledger_append_only
+    BEFORE UPDATE OR DELETE ON game_ledger
+    FOR EACH ROW EXECUTE FUNCTION game_ledger_append_only();
+
+-- Начальная запись на текущий баланс существующих игр
+INSERT INTO game_ledger (user_id, game_id, amount, reason, balance_after, created_at)
+SELECT g.user_id, g.game_id, g.balance, 'opening', g.balance, g.last_update_at
+FROM games g
+WHERE g.balance <> 0 AND NOT EXISTS (
+    SELECT 1 FROM game_ledger l WHERE l.user_id = g.user_id AND l.game_id = g.game_id
+);
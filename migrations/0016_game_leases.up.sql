@@ -0,0 +1,11 @@
+-- Аренда игры инстансом: пока она не истекла, игру тикает и сохраняет только owner.
+-- handoff просит владельца сохранить игру и отпустить её
+CREATE TABLE IF NOT EXISTS game_leases (
+    user_id TEXT NOT NULL,
+    game_id TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    expires_at BIGINT NOT NULL,
+    handoff BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (user_id, game_id)
+);
+CREATE INDEX IF NOT EXISTS game_leases_owner_idx ON game_leases (owner);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS games_last_update_idx;
+DROP TABLE IF EXISTS games_archive;
@@ -0,0 +1,12 @@
+-- Архив игр неактивных аккаунтов: состояние целиком в сжатом JSON.
+-- Строки games и дочерних таблиц удаляются, EnterGame возвращает их из архива
+CREATE TABLE IF NOT EXISTS games_archive (
+    user_id TEXT NOT NULL,
+    game_id TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    last_update_at BIGINT NOT NULL,
+    archived_at BIGINT NOT NULL,
+    data BYTEA NOT NULL,
+    PRIMARY KEY (user_id, game_id)
+);
+CREATE INDEX IF NOT EXISTS games_last_update_idx ON games (user_id, last_update_at);
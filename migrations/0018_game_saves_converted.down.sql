@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS game_saves DROP COLUMN IF EXISTS conflict;
+ALTER TABLE IF EXISTS game_saves DROP COLUMN IF EXISTS converted_at;
@@ -0,0 +1,4 @@
+-- Отметки переноса старых сохранений в games (команда legacy-saves).
+-- Таблицы может уже не быть: после переноса её удаляет legacy-saves drop
+ALTER TABLE IF EXISTS game_saves ADD COLUMN IF NOT EXISTS converted_at BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE IF EXISTS game_saves ADD COLUMN IF NOT EXISTS conflict TEXT NOT NULL DEFAULT '';
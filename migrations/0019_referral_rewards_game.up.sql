@@ -0,0 +1,3 @@
+-- Награда закрепляется за игрой, в которую её начисляют, и удаляется только
+-- после того, как запись журнала с ней сохранилась вместе с балансом
+ALTER TABLE referral_rewards ADD COLUMN IF NOT EXISTS game_id TEXT NOT NULL DEFAULT '';
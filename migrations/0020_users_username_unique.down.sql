@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_username_key;
+CREATE INDEX IF NOT EXISTS users_username_idx ON users (username);
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS users_username_idx;
+-- Повторяющиеся имена, оставшиеся с неуникального индекса, получают суффикс
+-- из user_id: исходное имя остаётся у самого раннего по user_id пользователя
+UPDATE users SET username = username || '_' || substr(user_id, 1, 8)
+WHERE user_id IN (
+    SELECT user_id FROM (
+        SELECT user_id, row_number() OVER (PARTITION BY username ORDER BY user_id) AS n
+        FROM users
+    ) AS duplicates
+    WHERE n > 1
+);
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON users (username);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS registrations (
+    session_id TEXT NOT NULL PRIMARY KEY,
+    started_at BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS registrations_started_idx ON registrations (started_at);
@@ -0,0 +1,8 @@
+// Package migrations - SQL-миграции, встроенные в бинарник.
+// Файлы называются NNNN_name.up.sql / NNNN_name.down.sql и применяются по номеру
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
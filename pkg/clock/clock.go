@@ -0,0 +1,52 @@
+// Package clock - источник времени сервисов. В проде системные часы,
+// в тестах Fake: время двигается вручную и тикеры срабатывают без ожидания
+package clock
+
+import "time"
+
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker - как time.Ticker: в канале не больше одного тика, медленный
+// получатель пропускает промежуточные
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// New - системные часы
+func New() Clock {
+	return realClock{}
+}
+
+// OrReal - часы из Deps, без них системные
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return New()
+	}
+	return c
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t realTicker) Stop() {
+	t.ticker.Stop()
+}
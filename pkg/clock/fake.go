@@ -0,0 +1,108 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake - часы для тестов. Время стоит, пока его не сдвинут Advance или Set,
+// тикеры срабатывают по ходу сдвига в порядке своих сроков
+type Fake struct {
+	now     time.Time
+	tickers []*fakeTicker
+	mu      sync.Mutex
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ticker := &fakeTicker{
+		clock:    f,
+		c:        make(chan time.Time, 1),
+		interval: d,
+		next:     f.now.Add(d),
+	}
+	f.tickers = append(f.tickers, ticker)
+	return ticker
+}
+
+// Advance - сдвиг времени на d. Тикер, чей срок попал в отрезок, срабатывает
+// столько раз, сколько сроков прошло, но в канале остаётся не больше одного тика
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set - перевод часов на t, назад время не идёт
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for {
+		ticker := f.nextTicker(t)
+		if ticker == nil {
+			break
+		}
+		f.now = ticker.next
+		ticker.next = ticker.next.Add(ticker.interval)
+		select {
+		case ticker.c <- f.now:
+		default:
+		}
+	}
+	if t.After(f.now) {
+		f.now = t
+	}
+}
+
+// Tickers - число работающих тикеров. Тест ждёт по нему, что воркер запустился
+func (f *Fake) Tickers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.tickers)
+}
+
+// nextTicker - вызывается под блокировкой: тикер с ближайшим сроком не позже until
+func (f *Fake) nextTicker(until time.Time) *fakeTicker {
+	sort.SliceStable(f.tickers, func(i, j int) bool {
+		return f.tickers[i].next.Before(f.tickers[j].next)
+	})
+	if len(f.tickers) == 0 || f.tickers[0].next.After(until) {
+		return nil
+	}
+	return f.tickers[0]
+}
+
+type fakeTicker struct {
+	clock    *Fake
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, ticker := range t.clock.tickers {
+		if ticker == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package clock_test
+
+import (
+	"miners_game/pkg/clock"
+	"testing"
+	"time"
+)
+
+func TestFakeTickerFiresOnAdvance(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clk := clock.NewFake(start)
+	ticker := clk.NewTicker(time.Second)
+
+	clk.Advance(500 * time.Millisecond)
+	select {
+	case <-ticker.C():
+		t.Fatalf("expected no tick before interval")
+	default:
+	}
+
+	clk.Advance(500 * time.Millisecond)
+	select {
+	case at := <-ticker.C():
+		if !at.Equal(start.Add(time.Second)) {
+			t.Fatalf("expected tick at %v, got %v", start.Add(time.Second), at)
+		}
+	default:
+		t.Fatalf("expected tick after interval")
+	}
+
+	// как у time.Ticker: пропущенные тики не копятся
+	clk.Advance(3 * time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Fatalf("expected single buffered tick")
+	default:
+	}
+	if !clk.Now().Equal(start.Add(4 * time.Second)) {
+		t.Fatalf("expected now %v, got %v", start.Add(4*time.Second), clk.Now())
+	}
+}
+
+func TestFakeTickerStop(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	ticker := clk.NewTicker(time.Second)
+	ticker.Stop()
+	if clk.Tickers() != 0 {
+		t.Fatalf("expected no tickers, got %d", clk.Tickers())
+	}
+	clk.Advance(time.Minute)
+	select {
+	case <-ticker.C():
+		t.Fatalf("expected stopped ticker not to fire")
+	default:
+	}
+}
@@ -9,4 +9,15 @@ import (
 func Generate() string {
 	code, _ := rand.Int(rand.Reader, big.NewInt(10000))
 	return fmt.Sprintf("%04d", code.Int64())
-}
\ No newline at end of file
+}
+
+const referralAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func GenerateReferral() string {
+	b := make([]byte, 8)
+	for i := range b {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(referralAlphabet))))
+		b[i] = referralAlphabet[n.Int64()]
+	}
+	return string(b)
+}
@@ -0,0 +1,15 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeout - дедлайн одной операции с базой. Нулевой timeout оставляет
+// только дедлайн вызывающего
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
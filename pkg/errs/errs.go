@@ -14,4 +14,28 @@ var (
 	ErrExpireSession      = errors.New("Сессия истекла")
 	ErrEmptyRegisterCode  = errors.New("Введите код")
 	ErrRegisterCode       = errors.New("Неверный код")
+	ErrUsernameTaken      = errors.New("Никнейм уже занят")
+	ErrProfileHidden      = errors.New("Профиль скрыт")
+	ErrReferralNotFound   = errors.New("Реферальный код не найден")
+	ErrReferralAbuse      = errors.New("Реферальный код недоступен")
+	ErrChatEmptyMessage   = errors.New("Пустое сообщение")
+	ErrChatMessageTooLong = errors.New("Слишком длинное сообщение")
+	ErrChatRateLimit      = errors.New("Слишком много сообщений, подождите")
+	ErrChatMuted          = errors.New("Вы не можете писать в чат")
+	ErrChatBanned         = errors.New("Вы заблокированы в чате")
+	ErrChatNoGuild        = errors.New("Вы не состоите в гильдии")
+	ErrChatGuildName      = errors.New("Название гильдии - от 1 до 32 символов")
+	ErrChatUnknownChannel = errors.New("Неизвестный канал")
+	ErrUnknownItem        = errors.New("Неизвестный товар")
+	ErrUnauthorized       = errors.New("Требуется авторизация")
+	ErrBadRequest         = errors.New("Некорректный запрос")
+	ErrSlotLimit          = errors.New("Достигнут лимит сохранений")
+	ErrSlotName           = errors.New("Название от 1 до 32 символов")
+	ErrSaveSignature      = errors.New("Файл сохранения повреждён или изменён")
+	ErrSaveVersion        = errors.New("Неподдерживаемая версия сохранения")
+	ErrSaveOwner          = errors.New("Сохранение принадлежит другому игроку")
+	ErrSnapshotNotFound   = errors.New("Снимок не найден")
+	ErrSaveConflict       = errors.New("Игра сохранена с другого сервера")
+	ErrGameLeased         = errors.New("Игра открыта на другом сервере, попробуйте позже")
+	ErrLegacySavesPending = errors.New("Не все старые сохранения перенесены")
 )
@@ -0,0 +1,115 @@
+// Package lifecycle - фоновые задачи процесса и упорядоченная остановка
+package lifecycle
+
+import (
+	"context"
+	"miners_game/pkg/clock"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type step struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Manager - запускает фоновые задачи в общем контексте и останавливает процесс
+// шагами в порядке регистрации. Каждый шаг получает свой дедлайн StepTimeout
+type Manager struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	jobs        sync.WaitGroup
+	steps       []step
+	stepTimeout time.Duration
+	clock       clock.Clock
+	logger      zerolog.Logger
+}
+
+type ManagerDeps struct {
+	StepTimeout time.Duration
+	Clock       clock.Clock
+	Logger      zerolog.Logger
+}
+
+func NewManager(deps ManagerDeps) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		ctx:         ctx,
+		cancel:      cancel,
+		stepTimeout: deps.StepTimeout,
+		clock:       clock.OrReal(deps.Clock),
+		logger:      deps.Logger,
+	}
+}
+
+// Go - фоновая задача, fn должна вернуться после отмены ctx
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	m.jobs.Add(1)
+	go func() {
+		defer m.jobs.Done()
+		fn(m.ctx)
+		m.logger.Debug().Str("job", name).Msg("job stopped")
+	}()
+}
+
+// Every - задача по таймеру часов Manager. Тикер создаётся до возврата, поэтому
+// сдвиг тестовых часов сразу после Every уже её запустит. Запуск, начавшийся
+// до остановки, доработает с уже отменённым ctx
+func (m *Manager) Every(name string, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := m.clock.NewTicker(interval)
+	m.Go(name, func(ctx context.Context) {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				fn(ctx)
+			}
+		}
+	})
+}
+
+// OnShutdown - шаг остановки, шаги выполняются в порядке регистрации
+func (m *Manager) OnShutdown(name string, fn func(ctx context.Context) error) {
+	m.steps = append(m.steps, step{name: name, fn: fn})
+}
+
+// StopJobs - шаг остановки: отменяет контекст задач и ждёт их завершения
+func (m *Manager) StopJobs(ctx context.Context) error {
+	m.cancel()
+	done := make(chan struct{})
+	go func() {
+		m.jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown - выполняет все шаги. Ошибка или таймаут шага не останавливают
+// следующие: пул закрывается, даже если сохранение не успело
+func (m *Manager) Shutdown() {
+	start := time.Now()
+	for _, s := range m.steps {
+		ctx, cancel := context.WithTimeout(context.Background(), m.stepTimeout)
+		stepStart := time.Now()
+		err := s.fn(ctx)
+		cancel()
+
+		event := m.logger.Info()
+		if err != nil {
+			event = m.logger.Error().Err(err)
+		}
+		event.Str("step", s.name).Dur("duration", time.Since(stepStart)).Msg("shutdown step finished")
+	}
+	m.cancel()
+	m.logger.Info().Dur("duration", time.Since(start)).Msg("shutdown complete")
+}
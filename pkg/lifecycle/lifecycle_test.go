@@ -0,0 +1,76 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"miners_game/pkg/clock"
+	"miners_game/pkg/lifecycle"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestShutdownRunsStepsInOrder(t *testing.T) {
+	manager := lifecycle.NewManager(lifecycle.ManagerDeps{
+		StepTimeout: 100 * time.Millisecond,
+		Logger:      zerolog.Nop(),
+	})
+	jobStopped := false
+	manager.Go("job", func(ctx context.Context) {
+		<-ctx.Done()
+		jobStopped = true
+	})
+
+	var order []string
+	manager.OnShutdown("http", func(ctx context.Context) error {
+		order = append(order, "http")
+		if jobStopped {
+			t.Fatalf("expected jobs to run until their step")
+		}
+		return nil
+	})
+	manager.OnShutdown("jobs", manager.StopJobs)
+	manager.OnShutdown("games", func(ctx context.Context) error {
+		order = append(order, "games")
+		if !jobStopped {
+			t.Fatalf("expected jobs to be stopped before games flush")
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	manager.OnShutdown("storage", func(ctx context.Context) error {
+		order = append(order, "storage")
+		return errors.New("already closed")
+	})
+
+	manager.Shutdown()
+	if len(order) != 3 || order[0] != "http" || order[1] != "games" || order[2] != "storage" {
+		t.Fatalf("expected steps in order after a timed out step, got %v", order)
+	}
+}
+
+func TestEveryRunsOnClockTicks(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	manager := lifecycle.NewManager(lifecycle.ManagerDeps{
+		StepTimeout: 100 * time.Millisecond,
+		Clock:       clk,
+		Logger:      zerolog.Nop(),
+	})
+	runs := make(chan int64)
+	manager.Every("job", time.Minute, func(ctx context.Context) {
+		runs <- clk.Now().Unix()
+	})
+
+	for i := int64(1); i <= 3; i++ {
+		clk.Advance(time.Minute)
+		if at := <-runs; at != 1000+i*60 {
+			t.Fatalf("expected run at %d, got %d", 1000+i*60, at)
+		}
+	}
+	manager.OnShutdown("jobs", manager.StopJobs)
+	manager.Shutdown()
+	if clk.Tickers() != 0 {
+		t.Fatalf("expected ticker to be stopped, got %d", clk.Tickers())
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+func AdminMiddleware(adminIDs []string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		logger := c.Locals("logger").(zerolog.Logger)
+		userID, _ := c.Locals("user_id").(string)
+		if userID == "" {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		if !slices.Contains(adminIDs, userID) {
+			logger.Warn().Msg("admin access denied")
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+		return c.Next()
+	}
+}
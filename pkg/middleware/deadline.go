@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestDeadlineMiddleware - c.UserContext() с дедлайном обработчика. Контекст
+// отменяется только по дедлайну, после ответа или при остановке сервера.
+// Отмены по обрыву соединения нет: fasthttp не сообщает о закрытии соединения
+// до возврата обработчика, поэтому брошенный запрос дорабатывает до дедлайна
+func RequestDeadlineMiddleware(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
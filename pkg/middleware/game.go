@@ -1,13 +1,19 @@
 package middleware
 
 import (
+	"context"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
-	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 )
 
-func GameMiddleware(store *session.Store) fiber.Handler {
+// GameResolver - выбор игры по аккаунту, когда в сессии ещё нет game_id
+type GameResolver interface {
+	ResolveGameID(ctx context.Context, userID string) (string, error)
+}
+
+func GameMiddleware(store *session.Store, resolver GameResolver) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		logger := c.Locals("logger").(zerolog.Logger)
 		sess, err := store.Get(c)
@@ -23,8 +29,12 @@ func GameMiddleware(store *session.Store) fiber.Handler {
 		}
 
 		gameID, ok := sess.Get("game_id").(string)
-		if !ok {
-			gameID = uuid.NewString()
+		if !ok || gameID == "" {
+			gameID, err = resolver.ResolveGameID(c.UserContext(), userID)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to resolve game")
+				return c.SendStatus(fiber.StatusInternalServerError)
+			}
 			sess.Set("game_id", gameID)
 			if err := sess.Save(); err != nil {
 				logger.Error().Err(err).Msg("failed to save session")
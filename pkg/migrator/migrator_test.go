@@ -0,0 +1,52 @@
+package migrator_test
+
+import (
+	"miners_game/migrations"
+	"miners_game/pkg/migrator"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadOrdersAndPairs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_b.up.sql":   {Data: []byte("CREATE TABLE b ()")},
+		"0001_a.up.sql":   {Data: []byte("CREATE TABLE a ()")},
+		"0001_a.down.sql": {Data: []byte("DROP TABLE a")},
+	}
+	list, err := migrator.Load(fsys)
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if len(list) != 2 || list[0].Version != 1 || list[1].Version != 2 {
+		t.Fatalf("expected migrations ordered by version, got %+v", list)
+	}
+	if list[0].Down == "" || list[1].Down != "" {
+		t.Fatalf("expected down script only for 0001, got %+v", list)
+	}
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	for _, name := range []string{"2025-12-23-test.sql", "0001_a.sql", "x_a.up.sql"} {
+		if _, err := migrator.Load(fstest.MapFS{name: {Data: []byte("SELECT 1")}}); err == nil {
+			t.Fatalf("expected error for %s", name)
+		}
+	}
+	if _, err := migrator.Load(fstest.MapFS{"0001_a.down.sql": {Data: []byte("SELECT 1")}}); err == nil {
+		t.Fatalf("expected error for missing up script")
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	list, err := migrator.Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("expected embedded migrations to parse, got %v:", err)
+	}
+	for i, m := range list {
+		if m.Version != int64(i+1) {
+			t.Fatalf("expected contiguous versions, got %d at %d", m.Version, i)
+		}
+		if m.Down == "" {
+			t.Fatalf("expected down script for %d_%s", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+CREATE TABLE IF NOT EXISTS users (
+    user_id TEXT NOT NULL PRIMARY KEY,
+    email TEXT NOT NULL,
+    password TEXT NOT NULL,
+    username TEXT NOT NULL,
+    profile_hidden INTEGER NOT NULL DEFAULT 0,
+    referral_code TEXT NOT NULL,
+    register_ip TEXT NOT NULL DEFAULT '',
+    UNIQUE (email, username)
+);
+CREATE INDEX IF NOT EXISTS users_email_idx ON users (email);
+DROP INDEX IF EXISTS users_username_idx;
+-- Повторяющиеся имена, оставшиеся с неуникального индекса, получают суффикс
+-- из user_id: исходное имя остаётся у самого раннего по user_id пользователя
+UPDATE users SET username = username || '_' || substr(user_id, 1, 8)
+WHERE user_id IN (
+    SELECT user_id FROM (
+        SELECT user_id, row_number() OVER (PARTITION BY username ORDER BY user_id) AS n
+        FROM users
+    ) AS duplicates
+    WHERE n > 1
+);
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS users_referral_code_idx ON users (referral_code);
+
+CREATE TABLE IF NOT EXISTS games (
+    user_id TEXT NOT NULL,
+    game_id TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL DEFAULT 0,
+    balance INTEGER NOT NULL,
+    income INTEGER NOT NULL,
+    last_update_at INTEGER NOT NULL,
+    version INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, game_id)
+);
+CREATE INDEX IF NOT EXISTS games_user_created_idx ON games (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS game_miners (
+    user_id TEXT NOT NULL,
+    game_id TEXT NOT NULL,
+    miner_key TEXT NOT NULL,
+    miner_id TEXT NOT NULL,
+    class TEXT NOT NULL,
+    start_at INTEGER NOT NULL,
+    end_at INTEGER NOT NULL,
+    PRIMARY KEY (user_id, game_id, miner_key),
+    FOREIGN KEY (user_id, game_id) REFERENCES games (user_id, game_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS game_items (
+    user_id TEXT NOT NULL,
+    game_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    own INTEGER NOT NULL DEFAULT 0,
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, game_id, kind, name),
+    FOREIGN KEY (user_id, game_id) REFERENCES games (user_id, game_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS game_ledger (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    game_id TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    item TEXT NOT NULL DEFAULT '',
+    balance_after INTEGER NOT NULL,
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS game_ledger_game_idx ON game_ledger (user_id, game_id, id);
+
+CREATE TABLE IF NOT EXISTS session (
+    k TEXT NOT NULL PRIMARY KEY,
+    v BLOB NOT NULL,
+    e INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS session_e_idx ON session (e);
@@ -0,0 +1,35 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "embed"
+	"net/url"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// schema - те же таблицы, что создают миграции Postgres, в диалекте SQLite.
+// Миграций нет: база для локального запуска создаётся сразу в последней версии
+//
+//go:embed schema.sql
+var schema string
+
+// Open - файл базы с включёнными внешними ключами, WAL и ожиданием блокировок
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_journal_mode", "WAL")
+	params.Set("_busy_timeout", "5000")
+	params.Set("_txlock", "immediate")
+	db, err := sql.Open("sqlite3", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	// SQLite допускает одного писателя, одно соединение убирает SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Storage - хранилище сессий fiber в таблице session, как у gofiber/storage/postgres
+type Storage struct {
+	db   *sql.DB
+	done chan struct{}
+}
+
+// NewStorage - gcInterval задаёт период удаления истёкших сессий
+func NewStorage(db *sql.DB, gcInterval time.Duration) *Storage {
+	s := &Storage{
+		db:   db,
+		done: make(chan struct{}),
+	}
+	go s.gc(gcInterval)
+	return s
+}
+
+func (s *Storage) Get(key string) ([]byte, error) {
+	if key == "" {
+		return nil, nil
+	}
+	var value []byte
+	var expires int64
+	err := s.db.QueryRow(`SELECT v, e FROM session WHERE k = ?`, key).Scan(&value, &expires)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if expires != 0 && expires <= time.Now().Unix() {
+		return nil, nil
+	}
+	return value, nil
+}
+
+func (s *Storage) Set(key string, value []byte, exp time.Duration) error {
+	if key == "" || len(value) == 0 {
+		return nil
+	}
+	var expires int64
+	if exp != 0 {
+		expires = time.Now().Add(exp).Unix()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO session (k, v, e) VALUES (?, ?, ?)
+		ON CONFLICT (k) DO UPDATE SET v = excluded.v, e = excluded.e`, key, value, expires)
+	return err
+}
+
+func (s *Storage) Delete(key string) error {
+	if key == "" {
+		return nil
+	}
+	_, err := s.db.Exec(`DELETE FROM session WHERE k = ?`, key)
+	return err
+}
+
+func (s *Storage) Reset() error {
+	_, err := s.db.Exec(`DELETE FROM session`)
+	return err
+}
+
+// Close - останавливает сборку мусора, база закрывается владельцем
+func (s *Storage) Close() error {
+	close(s.done)
+	return nil
+}
+
+func (s *Storage) gc(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.db.Exec(`DELETE FROM session WHERE e <> 0 AND e <= ?`, now.Unix())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package sqlite_test
+
+import (
+	"miners_game/pkg/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStorageSetGetDelete(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	defer db.Close()
+	storage := sqlite.NewStorage(db, time.Minute)
+	defer storage.Close()
+
+	if err := storage.Set("key", []byte("value"), 0); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if err := storage.Set("expired", []byte("value"), -time.Second); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	value, err := storage.Get("key")
+	if err != nil || string(value) != "value" {
+		t.Fatalf("expected stored value, got %q %v", value, err)
+	}
+	if value, _ := storage.Get("expired"); value != nil {
+		t.Fatalf("expected expired value to be hidden, got %q", value)
+	}
+	if err := storage.Delete("key"); err != nil {
+		t.Fatalf("expected success, got %v:", err)
+	}
+	if value, _ := storage.Get("key"); value != nil {
+		t.Fatalf("expected deleted value, got %q", value)
+	}
+}
@@ -53,7 +53,7 @@ func Game() templ.Component {
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<div id=\"hud-container\" hx-get=\"/game/hud\" hx-trigger=\"every 500msС\" hx-swap=\"innerHTML\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<div id=\"hud-container\" data-stream=\"/game/hud/stream\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -61,7 +61,15 @@ func Game() templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</div><section class=\"game-scene-wrapper\" hx-get=\"/game/upgrade\" hx-trigger=\"load, refresh-upgrade from:body\" hx-swap=\"innerHTML\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = widgets.HUDScript().Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, " <section class=\"game-scene-wrapper\" hx-get=\"/game/upgrade\" hx-trigger=\"load, refresh-upgrade from:body\" hx-swap=\"innerHTML\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -69,7 +77,7 @@ func Game() templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</section><section class=\"game-bottom-panel\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</section><section class=\"game-bottom-panel\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -77,7 +85,11 @@ func Game() templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</section>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</section>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = widgets.Chat().Render(ctx, templ_7745c5c3_Buffer)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -90,7 +102,7 @@ func Game() templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</main>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</main>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -119,7 +131,7 @@ func GameStyle() templ.Component {
 			templ_7745c5c3_Var3 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<style>\r\n    :root {\r\n        --bg-dark: #0b0b0b;\r\n        --bg-mid: #141414;\r\n        --accent: #f5c16c;\r\n        --text-main: #ffffff;\r\n    }\r\n\r\n    body {\r\n        margin: 0;\r\n        background: black;\r\n        min-height: 100vh;\r\n        color: var(--text-main);\r\n        font-family: Inter, system-ui, sans-serif;\r\n        overflow-x: hidden;\r\n    }\r\n    body::before{\r\n        content: \"\";\r\n        position: fixed;\r\n        inset: 0;\r\n        background-image: url(/public/backgrounds/stars4.jpg);\r\n        background-size: cover;\r\n        background-position: center;\r\n        background-repeat: no-repeat;\r\n        z-index: -3;\r\n        transform: scale(1.05);\r\n    }\r\n    body::after{\r\n        content: \"\";\r\n        position: fixed;\r\n        inset: 0;\r\n        background:\r\n            radial-gradient(\r\n                circle at top,\r\n                rgba(225,255,255,0.06),\r\n                rgba(0,0,0,0.85) 70%,\r\n            ),\r\n            radial-gradient(\r\n                circle at center,\r\n                transparent 55%,\r\n                rgba(0,0,0,0.85)\r\n            );\r\n        z-index: -2;\r\n    }\r\n\r\n    .game-page {\r\n        min-height: 100vh;\r\n        display: flex;\r\n        flex-direction: column;\r\n    }\r\n    .game-bottom-panel{\r\n        flex: 0 0 auto;\r\n    }\r\n    .game-scene-wrapper{\r\n        flex: 1;\r\n        display: flex;\r\n        align-items: center;\r\n        justify-content: center;\r\n        position: relative;\r\n        overflow: visible;\r\n    }\r\n    .game-scene-wrapper::before {\r\n    content: \"\";\r\n    position: absolute;\r\n    inset: -20%;\r\n    background: radial-gradient(\r\n        circle at center,\r\n        transparent 38%,\r\n        rgba(0, 0, 0, 0.65) 70%\r\n    );\r\n    pointer-events: none;\r\n    z-index: 0;\r\n    }\r\n\r\n    .hud {\r\n        height: 64px;\r\n        padding: 0 24px;\r\n        background: rgba(0, 0, 0, 0.4);\r\n        backdrop-filter: blur(8px);\r\n        display: flex;\r\n        justify-content: space-between;\r\n        align-items: center;\r\n        font-weight: 600;\r\n        flex: 0 0 64px;\r\n        position: relative;\r\n        z-index: 100;\r\n    }\r\n\r\n    .hud-balance {\r\n        transition: transform 0.15s ease;\r\n    }\r\n\r\n    .hud-balance.updated {\r\n        transform: scale(1.05);\r\n    }\r\n\r\n</style>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "<style>\r\n    :root {\r\n        --bg-dark: #0b0b0b;\r\n        --bg-mid: #141414;\r\n        --accent: #f5c16c;\r\n        --text-main: #ffffff;\r\n    }\r\n\r\n    body {\r\n        margin: 0;\r\n        background: black;\r\n        min-height: 100vh;\r\n        color: var(--text-main);\r\n        font-family: Inter, system-ui, sans-serif;\r\n        overflow-x: hidden;\r\n    }\r\n    body::before{\r\n        content: \"\";\r\n        position: fixed;\r\n        inset: 0;\r\n        background-image: url(/public/backgrounds/stars4.jpg);\r\n        background-size: cover;\r\n        background-position: center;\r\n        background-repeat: no-repeat;\r\n        z-index: -3;\r\n        transform: scale(1.05);\r\n    }\r\n    body::after{\r\n        content: \"\";\r\n        position: fixed;\r\n        inset: 0;\r\n        background:\r\n            radial-gradient(\r\n                circle at top,\r\n                rgba(225,255,255,0.06),\r\n                rgba(0,0,0,0.85) 70%,\r\n            ),\r\n            radial-gradient(\r\n                circle at center,\r\n                transparent 55%,\r\n                rgba(0,0,0,0.85)\r\n            );\r\n        z-index: -2;\r\n    }\r\n\r\n    .game-page {\r\n        min-height: 100vh;\r\n        display: flex;\r\n        flex-direction: column;\r\n    }\r\n    .game-bottom-panel{\r\n        flex: 0 0 auto;\r\n    }\r\n    .game-scene-wrapper{\r\n        flex: 1;\r\n        display: flex;\r\n        align-items: center;\r\n        justify-content: center;\r\n        position: relative;\r\n        overflow: visible;\r\n    }\r\n    .game-scene-wrapper::before {\r\n    content: \"\";\r\n    position: absolute;\r\n    inset: -20%;\r\n    background: radial-gradient(\r\n        circle at center,\r\n        transparent 38%,\r\n        rgba(0, 0, 0, 0.65) 70%\r\n    );\r\n    pointer-events: none;\r\n    z-index: 0;\r\n    }\r\n\r\n    .hud {\r\n        height: 64px;\r\n        padding: 0 24px;\r\n        background: rgba(0, 0, 0, 0.4);\r\n        backdrop-filter: blur(8px);\r\n        display: flex;\r\n        justify-content: space-between;\r\n        align-items: center;\r\n        font-weight: 600;\r\n        flex: 0 0 64px;\r\n        position: relative;\r\n        z-index: 100;\r\n    }\r\n\r\n    .hud-balance {\r\n        transition: transform 0.15s ease;\r\n    }\r\n\r\n    .hud-balance.updated {\r\n        transform: scale(1.05);\r\n    }\r\n\r\n</style>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -11,7 +11,7 @@ import templruntime "github.com/a-h/templ/runtime"
 import "miners_game/views/layout"
 import "miners_game/views/widgets"
 
-func Register() templ.Component {
+func Register(referralCode string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -52,7 +52,7 @@ func Register() templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = widgets.RegisterForm().Render(ctx, templ_7745c5c3_Buffer)
+			templ_7745c5c3_Err = widgets.RegisterForm(referralCode).Render(ctx, templ_7745c5c3_Buffer)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -29,33 +29,63 @@ func HUD(Balance, Income string) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"hud\" id=\"hud\"><div class=\"hud-balance\">💰 ")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"hud\" id=\"hud\"><div class=\"hud-balance\">💰 <span class=\"hud-balance-value\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var2 string
 		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(Balance)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `views/widgets/hud.templ`, Line: 6, Col: 21}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `views/widgets/hud.templ`, Line: 6, Col: 53}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, " угля</div><div class=\"hud-income\">⛏ +")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</span> угля</div><div class=\"hud-income\">⛏ +<span class=\"hud-income-value\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var3 string
 		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(Income)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `views/widgets/hud.templ`, Line: 10, Col: 20}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `views/widgets/hud.templ`, Line: 10, Col: 51}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "/сек</div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</span>/сек</div></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+// HUDScript - локальная анимация баланса по снимкам из /game/hud/stream
+func HUDScript() templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var4 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var4 == nil {
+			templ_7745c5c3_Var4 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<script>\r\n    (function () {\r\n        const root = document.getElementById(\"hud-container\");\r\n        const balanceEl = root.querySelector(\".hud-balance-value\");\r\n        const incomeEl = root.querySelector(\".hud-income-value\");\r\n        let snapshot = null;\r\n        let offset = 0;\r\n\r\n        const source = new EventSource(root.dataset.stream);\r\n        source.addEventListener(\"snapshot\", (e) => {\r\n            snapshot = JSON.parse(e.data);\r\n            offset = snapshot.server_time - Date.now();\r\n            incomeEl.textContent = snapshot.rate;\r\n        });\r\n\r\n        function frame() {\r\n            if (snapshot) {\r\n                let now = (Date.now() + offset) / 1000;\r\n                // после истечения майнера доход падает: ждём новый снимок, а не обгоняем сервер\r\n                const expiry = snapshot.expiries.find((t) => t > snapshot.at);\r\n                if (expiry !== undefined && now > expiry) {\r\n                    now = expiry;\r\n                }\r\n                const value = snapshot.balance + Math.max(0, now - snapshot.at) * snapshot.rate;\r\n                balanceEl.textContent = Math.floor(value);\r\n            }\r\n            if (document.body.contains(root)) {\r\n                requestAnimationFrame(frame);\r\n            } else {\r\n                source.close();\r\n            }\r\n        }\r\n        requestAnimationFrame(frame);\r\n    })();\r\n</script>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -10,7 +10,7 @@ import templruntime "github.com/a-h/templ/runtime"
 
 import "miners_game/views/components"
 
-func RegisterForm() templ.Component {
+func RegisterForm(referralCode string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -77,6 +77,14 @@ func RegisterForm() templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
+		templ_7745c5c3_Err = components.Input(components.InputProps{
+			Name:        "referralCode",
+			Placeholder: "Реферальный код (необязательно)",
+			Value:       referralCode,
+		}).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
 		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<div class=\"register-form__auth-switch\"><span>Уже есть аккаунт?</span> <a href=\"/login\">Войти</a></div><div class=\"register-form__button\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err